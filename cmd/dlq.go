@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/spf13/pflag"
+)
+
+const dlqUsage = `usage: city-picks dlq <command> [flags] [ids...]
+
+commands:
+  list       list dead letters, use --cursor/--count to page
+  replay     re-enqueue dead letters into stream:orders with retry count reset
+  discard    delete dead letters without compensation
+  reconcile  return Redis stock for dead orders that were never created
+
+flags:
+`
+
+// runDLQ 处理 dlq 子命令, 返回进程退出码
+func runDLQ() int {
+	// 参数由 config.NewOptions 中的 pflag.Parse 统一解析, 这里只需提前注册
+	all := pflag.Bool("all", false, "apply to all dead letters")
+	cursor := pflag.String("cursor", "", "list entries after this id")
+	count := pflag.Int64("count", 0, "max entries to list")
+	pflag.Usage = func() {
+		fmt.Fprint(os.Stderr, dlqUsage)
+		pflag.PrintDefaults()
+	}
+
+	svc, cleanup, err := InitDeadLetterService()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer cleanup()
+
+	// pflag.Args() 形如 [dlq <command> ids...]
+	args := pflag.Args()
+	if len(args) < 2 {
+		pflag.Usage()
+		return 2
+	}
+	ctx := context.Background()
+	req := &service.DeadLetterRequest{IDs: args[2:], All: *all}
+
+	var result any
+	switch args[1] {
+	case "list":
+		page, err := svc.List(ctx, *cursor, *count)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printDeadLetters(page)
+		return 0
+	case "replay":
+		var n int
+		n, err = svc.Replay(ctx, req)
+		result = map[string]int{"replayed": n}
+	case "discard":
+		var n int
+		n, err = svc.Discard(ctx, req)
+		result = map[string]int{"discarded": n}
+	case "reconcile":
+		result, err = svc.Reconcile(ctx, req)
+	default:
+		pflag.Usage()
+		return 2
+	}
+
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func printDeadLetters(page *service.DeadLetterPage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tORIGINAL_ID\tORDER_ID\tUSER_ID\tVOUCHER_ID\tFAILED_AT\tERROR")
+	for _, e := range page.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.OriginalID, e.OrderID, e.UserID, e.VoucherID, e.FailedAt, e.Error)
+	}
+	w.Flush()
+	if page.NextCursor != "" {
+		fmt.Printf("\nmore entries: --cursor %s\n", page.NextCursor)
+	}
+}
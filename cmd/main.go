@@ -3,25 +3,52 @@ package main
 import (
 	"context"
 	"errors"
-
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/hmmm42/city-picks/internal/config"
 )
 
+// drainTimeout 退出时等待消费者和后台任务结束的最长时间
+const drainTimeout = 10 * time.Second
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		os.Exit(runDLQ())
+	}
+
 	app, cleanup, err := InitApp()
 	if err != nil {
 		panic(err)
 	}
 	defer cleanup()
 
-	go app.OrderConsumer.Start(context.Background())
+	// 后台任务在服务退出时取消, 全部结束后才关闭数据库和 Redis 连接
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	var jobs sync.WaitGroup
+	runJob := func(start func(ctx context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			start(bgCtx)
+		}()
+	}
+
+	app.OrderConsumers.Start(bgCtx)
+	runJob(app.OrderForwarder.Start)
+	runJob(app.OutboxRelay.Start)
+	runJob(app.UnpaidCanceller.Start)
+	runJob(app.VoucherExpireJob.Start)
+	runJob(app.LotteryDrawJob.Start)
+	runJob(app.StockReconcileJob.Start)
 
 	server := &http.Server{
 		Addr:    ":" + config.ServerOptions.Port,
@@ -29,7 +56,7 @@ func main() {
 	}
 	slog.Info("Listening on " + server.Addr)
 	go func() {
-		err = server.ListenAndServe()
+		err := server.ListenAndServe()
 		//err = app.Engine.Run(config.ServerOptions.Port)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("Server failed to start", "error", err)
@@ -43,7 +70,36 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	// 关闭超时时仍有请求未处理完, 继续停止后台任务, 不能跳过等待直接关闭连接
 	if err = server.Shutdown(ctx); err != nil {
-		panic(err)
+		slog.Error("Failed to shut down server gracefully", "err", err)
+	}
+
+	// 不再接收新请求后停止后台任务, 等待消费者处理完已读取的消息、其他后台任务退出后再关闭数据库连接
+	stopBackground()
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+	if err = app.OrderConsumers.Wait(drainCtx); err != nil {
+		slog.Error("Failed to drain order consumers", "err", err)
+	}
+	if err = waitJobs(drainCtx, &jobs); err != nil {
+		slog.Error("Failed to stop background jobs", "err", err)
+	}
+}
+
+// waitJobs 等待后台任务退出, ctx 超时则放弃等待
+func waitJobs(ctx context.Context, jobs *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("All background jobs stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs did not stop in time: %w", ctx.Err())
 	}
 }
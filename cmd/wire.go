@@ -10,24 +10,38 @@ import (
 	"github.com/hmmm42/city-picks/internal/adapter/persistent"
 	"github.com/hmmm42/city-picks/internal/config"
 	"github.com/hmmm42/city-picks/internal/handler"
+	"github.com/hmmm42/city-picks/internal/job"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/moderation"
 	"github.com/hmmm42/city-picks/internal/mq"
+	"github.com/hmmm42/city-picks/internal/payment"
 	"github.com/hmmm42/city-picks/internal/repository"
 	"github.com/hmmm42/city-picks/internal/router"
 	"github.com/hmmm42/city-picks/internal/service"
 	"github.com/hmmm42/city-picks/pkg/logger"
+	sf "github.com/hmmm42/city-picks/pkg/sonyflake"
 )
 
 type App struct {
-	Engine        *gin.Engine
-	OrderConsumer *mq.OrderConsumer
+	Engine            *gin.Engine
+	OrderConsumers    *mq.OrderConsumerPool
+	OrderForwarder    *mq.OrderForwarder
+	OutboxRelay       *mq.OutboxRelay
+	UnpaidCanceller   *mq.UnpaidOrderCanceller
+	VoucherExpireJob  *job.VoucherExpireJob
+	LotteryDrawJob    *job.LotteryDrawJob
+	StockReconcileJob *job.StockReconcileJob
 }
 
 var configSet = wire.NewSet(config.NewOptions,
 	wire.FieldsOf(new(*config.Options),
 		// 从 *Options 中提取出子结构体，供其他Provider使用
-		"MySQL", "Redis", "Log", "JWT", "Server"))
+		"MySQL", "Redis", "Log", "JWT", "Server", "Idempotency", "MQ"))
 var dbSet = wire.NewSet(persistent.NewMySQL, cache.NewRedisClient)
 var loggerSet = wire.NewSet(logger.NewLogger)
+var idGenSet = wire.NewSet(sf.NewSonyflake)
+var paymentSet = wire.NewSet(payment.NewLocalGateway)
+var moderationSet = wire.NewSet(moderation.NewLocalModerator)
 
 var repositorySet = wire.NewSet(
 	repository.NewUserRepo,
@@ -35,35 +49,81 @@ var repositorySet = wire.NewSet(
 	repository.NewVoucherRepo,
 	repository.NewVoucherOrderRepo,
 	repository.NewMessageQueue,
+	repository.NewRateLimiter,
+	repository.NewLotteryRepo,
+	repository.NewOutboxRepo,
+	repository.NewBlogRepo,
+	repository.NewCommentRepo,
+	repository.NewFollowRepo,
 )
 
 var serviceSet = wire.NewSet(
 	service.NewUserService,
 	service.NewShopService,
 	service.NewVoucherService,
+	service.NewLotteryService,
+	service.NewOrderService,
+	service.NewDeadLetterService,
+	service.NewStockReconcileService,
+	service.NewBlogService,
+	service.NewCommentService,
+	service.NewModerationService,
+	service.NewFollowService,
 )
 
 var handlerSet = wire.NewSet(
 	handler.NewLoginHandler,
 	handler.NewShopService,
 	handler.NewVoucherHandler,
+	handler.NewLotteryHandler,
+	handler.NewOrderHandler,
+	handler.NewDeadLetterHandler,
+	handler.NewStockReconcileHandler,
+	handler.NewBlogHandler,
+	handler.NewCommentHandler,
+	handler.NewModerationHandler,
+	handler.NewFollowHandler,
 )
 
+var middlewareSet = wire.NewSet(middleware.NewIdempotency)
+
 var routerSet = wire.NewSet(router.NewRouter)
 
-var mqSet = wire.NewSet(mq.NewOrderConsumer)
+var mqSet = wire.NewSet(mq.NewOrderConsumerPool, mq.NewOrderForwarder, mq.NewOutboxRelay, mq.NewUnpaidOrderCanceller)
+
+var jobSet = wire.NewSet(job.NewVoucherExpireJob, job.NewLotteryDrawJob, job.NewStockReconcileJob)
 
 func InitApp() (*App, func(), error) {
 	wire.Build(
 		configSet,
 		dbSet,
 		loggerSet,
+		idGenSet,
+		paymentSet,
+		moderationSet,
 		repositorySet,
 		serviceSet,
 		handlerSet,
+		middlewareSet,
 		routerSet,
 		mqSet,
+		jobSet,
 		wire.Struct(new(App), "*"),
 	)
 	return nil, nil, nil
 }
+
+// InitDeadLetterService 供 dlq 子命令使用, 不启动 HTTP 服务和消费者
+func InitDeadLetterService() (service.DeadLetterService, func(), error) {
+	wire.Build(
+		config.NewOptions,
+		wire.FieldsOf(new(*config.Options), "MySQL", "Redis", "Log", "MQ"),
+		dbSet,
+		loggerSet,
+		repository.NewVoucherRepo,
+		repository.NewVoucherOrderRepo,
+		repository.NewMessageQueue,
+		service.NewDeadLetterService,
+	)
+	return nil, nil, nil
+}
@@ -13,11 +13,16 @@ import (
 	"github.com/hmmm42/city-picks/internal/adapter/persistent"
 	"github.com/hmmm42/city-picks/internal/config"
 	"github.com/hmmm42/city-picks/internal/handler"
+	"github.com/hmmm42/city-picks/internal/job"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/moderation"
 	"github.com/hmmm42/city-picks/internal/mq"
+	"github.com/hmmm42/city-picks/internal/payment"
 	"github.com/hmmm42/city-picks/internal/repository"
 	"github.com/hmmm42/city-picks/internal/router"
 	"github.com/hmmm42/city-picks/internal/service"
 	"github.com/hmmm42/city-picks/pkg/logger"
+	"github.com/hmmm42/city-picks/pkg/sonyflake"
 )
 
 // Injectors from wire.go:
@@ -52,17 +57,110 @@ func InitApp() (*App, func(), error) {
 	shopService := service.NewShopService(shopRepo)
 	handlerShopService := handler.NewShopService(shopService)
 	voucherRepo := repository.NewVoucherRepo(db, client, slogLogger)
-	voucherOrderRepo := repository.NewVoucherOrderRepo(db, slogLogger)
-	voucherService := service.NewVoucherService(voucherRepo, voucherOrderRepo, slogLogger)
+	voucherOrderRepo := repository.NewVoucherOrderRepo(db, client, slogLogger)
+	rateLimiter := repository.NewRateLimiter(client)
+	sonyflake, err := sf.NewSonyflake()
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	voucherService := service.NewVoucherService(voucherRepo, voucherOrderRepo, rateLimiter, sonyflake, slogLogger)
 	voucherHandler := handler.NewVoucherHandler(voucherService)
-	engine := router.NewRouter(loginHandler, handlerShopService, voucherHandler)
-	messageQueue := repository.NewMessageQueue(client)
-	orderConsumer := mq.NewOrderConsumer(messageQueue, voucherService)
+	lotteryRepo := repository.NewLotteryRepo(db, client, slogLogger)
+	lotteryService := service.NewLotteryService(lotteryRepo, voucherRepo, sonyflake, slogLogger)
+	lotteryHandler := handler.NewLotteryHandler(lotteryService)
+	gateway := payment.NewLocalGateway(slogLogger)
+	orderService := service.NewOrderService(voucherOrderRepo, voucherRepo, shopRepo, gateway, slogLogger)
+	orderHandler := handler.NewOrderHandler(orderService)
+	mqSetting := options.MQ
+	messageQueue, cleanup3, err := repository.NewMessageQueue(mqSetting, client)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	deadLetterService := service.NewDeadLetterService(messageQueue, voucherRepo, voucherOrderRepo, slogLogger)
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterService)
+	stockReconcileService := service.NewStockReconcileService(messageQueue, client, voucherRepo, voucherOrderRepo, slogLogger)
+	stockReconcileHandler := handler.NewStockReconcileHandler(stockReconcileService)
+	blogRepo := repository.NewBlogRepo(db, client)
+	commentRepo := repository.NewCommentRepo(db, client)
+	moderator := moderation.NewLocalModerator(slogLogger)
+	blogService := service.NewBlogService(blogRepo, commentRepo, shopRepo, userRepo, moderator, slogLogger)
+	blogHandler := handler.NewBlogHandler(blogService)
+	commentService := service.NewCommentService(commentRepo, blogRepo, userRepo, moderator, slogLogger)
+	commentHandler := handler.NewCommentHandler(commentService)
+	moderationService := service.NewModerationService(blogRepo, commentRepo, userRepo, slogLogger)
+	moderationHandler := handler.NewModerationHandler(moderationService)
+	followRepo := repository.NewFollowRepo(db, client)
+	followService := service.NewFollowService(followRepo, userRepo, slogLogger)
+	followHandler := handler.NewFollowHandler(followService)
+	idempotencySetting := options.Idempotency
+	idempotency := middleware.NewIdempotency(client, idempotencySetting)
+	engine := router.NewRouter(loginHandler, handlerShopService, voucherHandler, lotteryHandler, orderHandler, deadLetterHandler, stockReconcileHandler, blogHandler, commentHandler, moderationHandler, followHandler, idempotency)
+	orderConsumerPool := mq.NewOrderConsumerPool(messageQueue, voucherService)
+	orderForwarder := mq.NewOrderForwarder(client, messageQueue)
+	outboxRepo := repository.NewOutboxRepo(db)
+	outboxRelay := mq.NewOutboxRelay(outboxRepo, messageQueue)
+	unpaidOrderCanceller := mq.NewUnpaidOrderCanceller(voucherOrderRepo, orderService)
+	voucherExpireJob := job.NewVoucherExpireJob(voucherService)
+	lotteryDrawJob := job.NewLotteryDrawJob(lotteryService)
+	stockReconcileJob := job.NewStockReconcileJob(stockReconcileService)
 	app := &App{
-		Engine:        engine,
-		OrderConsumer: orderConsumer,
+		Engine:            engine,
+		OrderConsumers:    orderConsumerPool,
+		OrderForwarder:    orderForwarder,
+		OutboxRelay:       outboxRelay,
+		UnpaidCanceller:   unpaidOrderCanceller,
+		VoucherExpireJob:  voucherExpireJob,
+		LotteryDrawJob:    lotteryDrawJob,
+		StockReconcileJob: stockReconcileJob,
 	}
 	return app, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+// InitDeadLetterService 供 dlq 子命令使用, 不启动 HTTP 服务和消费者
+func InitDeadLetterService() (service.DeadLetterService, func(), error) {
+	options, err := config.NewOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	mqSetting := options.MQ
+	redisSetting := options.Redis
+	client, cleanup, err := cache.NewRedisClient(redisSetting)
+	if err != nil {
+		return nil, nil, err
+	}
+	messageQueue, cleanup2, err := repository.NewMessageQueue(mqSetting, client)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	mySQLSetting := options.MySQL
+	db, cleanup3, err := persistent.NewMySQL(mySQLSetting)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	logSettings := options.Log
+	slogLogger, err := logger.NewLogger(logSettings)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	voucherRepo := repository.NewVoucherRepo(db, client, slogLogger)
+	voucherOrderRepo := repository.NewVoucherOrderRepo(db, client, slogLogger)
+	deadLetterService := service.NewDeadLetterService(messageQueue, voucherRepo, voucherOrderRepo, slogLogger)
+	return deadLetterService, func() {
+		cleanup3()
 		cleanup2()
 		cleanup()
 	}, nil
@@ -71,24 +169,40 @@ func InitApp() (*App, func(), error) {
 // wire.go:
 
 type App struct {
-	Engine        *gin.Engine
-	OrderConsumer *mq.OrderConsumer
+	Engine            *gin.Engine
+	OrderConsumers    *mq.OrderConsumerPool
+	OrderForwarder    *mq.OrderForwarder
+	OutboxRelay       *mq.OutboxRelay
+	UnpaidCanceller   *mq.UnpaidOrderCanceller
+	VoucherExpireJob  *job.VoucherExpireJob
+	LotteryDrawJob    *job.LotteryDrawJob
+	StockReconcileJob *job.StockReconcileJob
 }
 
 var configSet = wire.NewSet(config.NewOptions, wire.FieldsOf(new(*config.Options),
 
-	"MySQL", "Redis", "Log", "JWT", "Server"))
+	"MySQL", "Redis", "Log", "JWT", "Server", "Idempotency", "MQ"))
 
 var dbSet = wire.NewSet(persistent.NewMySQL, cache.NewRedisClient)
 
 var loggerSet = wire.NewSet(logger.NewLogger)
 
-var repositorySet = wire.NewSet(repository.NewUserRepo, repository.NewShopRepo, repository.NewVoucherRepo, repository.NewVoucherOrderRepo, repository.NewMessageQueue)
+var idGenSet = wire.NewSet(sf.NewSonyflake)
 
-var serviceSet = wire.NewSet(service.NewUserService, service.NewShopService, service.NewVoucherService)
+var paymentSet = wire.NewSet(payment.NewLocalGateway)
 
-var handlerSet = wire.NewSet(handler.NewLoginHandler, handler.NewShopService, handler.NewVoucherHandler)
+var moderationSet = wire.NewSet(moderation.NewLocalModerator)
+
+var repositorySet = wire.NewSet(repository.NewUserRepo, repository.NewShopRepo, repository.NewVoucherRepo, repository.NewVoucherOrderRepo, repository.NewMessageQueue, repository.NewRateLimiter, repository.NewLotteryRepo, repository.NewOutboxRepo, repository.NewBlogRepo, repository.NewCommentRepo, repository.NewFollowRepo)
+
+var serviceSet = wire.NewSet(service.NewUserService, service.NewShopService, service.NewVoucherService, service.NewLotteryService, service.NewOrderService, service.NewDeadLetterService, service.NewStockReconcileService, service.NewBlogService, service.NewCommentService, service.NewModerationService, service.NewFollowService)
+
+var handlerSet = wire.NewSet(handler.NewLoginHandler, handler.NewShopService, handler.NewVoucherHandler, handler.NewLotteryHandler, handler.NewOrderHandler, handler.NewDeadLetterHandler, handler.NewStockReconcileHandler, handler.NewBlogHandler, handler.NewCommentHandler, handler.NewModerationHandler, handler.NewFollowHandler)
+
+var middlewareSet = wire.NewSet(middleware.NewIdempotency)
 
 var routerSet = wire.NewSet(router.NewRouter)
 
-var mqSet = wire.NewSet(mq.NewOrderConsumer)
+var mqSet = wire.NewSet(mq.NewOrderConsumerPool, mq.NewOrderForwarder, mq.NewOutboxRelay, mq.NewUnpaidOrderCanceller)
+
+var jobSet = wire.NewSet(job.NewVoucherExpireJob, job.NewLotteryDrawJob, job.NewStockReconcileJob)
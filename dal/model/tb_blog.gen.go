@@ -20,6 +20,7 @@ type TbBlog struct {
 	Content    string    `gorm:"column:content;type:varchar(2048);not null;comment:探店的文字描述" json:"content"`                            // 探店的文字描述
 	Liked      uint64    `gorm:"column:liked;type:int unsigned;comment:点赞数量" json:"liked"`                                             // 点赞数量
 	Comments   uint64    `gorm:"column:comments;type:int unsigned;comment:评论数量" json:"comments"`                                       // 评论数量
+	Status     uint8     `gorm:"column:status;type:tinyint unsigned;not null;comment:状态，0：正常，1：待审核，2：禁止查看" json:"status"`              // 状态，0：正常，1：待审核，2：禁止查看
 	CreateTime time.Time `gorm:"column:create_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"create_time"` // 创建时间
 	UpdateTime time.Time `gorm:"column:update_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:更新时间" json:"update_time"` // 更新时间
 }
@@ -20,6 +20,7 @@ type TbBlogComment struct {
 	Content    string    `gorm:"column:content;type:varchar(255);not null;comment:回复的内容" json:"content"`                               // 回复的内容
 	Liked      uint64    `gorm:"column:liked;type:int unsigned;comment:点赞数" json:"liked"`                                              // 点赞数
 	Status     uint8     `gorm:"column:status;type:tinyint unsigned;comment:状态，0：正常，1：被举报，2：禁止查看" json:"status"`                       // 状态，0：正常，1：被举报，2：禁止查看
+	Reviewed   uint8     `gorm:"column:reviewed;type:tinyint unsigned;not null;comment:是否已被管理员审核通过，通过后再被举报不再进入审核队列" json:"reviewed"`   // 是否已被管理员审核通过，通过后再被举报不再进入审核队列
 	CreateTime time.Time `gorm:"column:create_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"create_time"` // 创建时间
 	UpdateTime time.Time `gorm:"column:update_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:更新时间" json:"update_time"` // 更新时间
 }
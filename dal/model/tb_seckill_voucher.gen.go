@@ -17,6 +17,7 @@ type TbSeckillVoucher struct {
 	CreateTime time.Time `gorm:"column:create_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"create_time"` // 创建时间
 	BeginTime  time.Time `gorm:"column:begin_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:生效时间" json:"begin_time"`   // 生效时间
 	EndTime    time.Time `gorm:"column:end_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:失效时间" json:"end_time"`       // 失效时间
+	Mode       uint8     `gorm:"column:mode;type:tinyint unsigned;not null;comment:秒杀模式，0：先到先得；1：预约抽签" json:"mode"`                    // 秒杀模式，0：先到先得；1：预约抽签
 	UpdateTime time.Time `gorm:"column:update_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:更新时间" json:"update_time"` // 更新时间
 }
 
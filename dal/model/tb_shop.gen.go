@@ -25,6 +25,7 @@ type TbShop struct {
 	Comments   uint64    `gorm:"column:comments;type:int(10) unsigned zerofill;not null;comment:评论数量" json:"comments"`        // 评论数量
 	Score      uint64    `gorm:"column:score;type:int(2) unsigned zerofill;not null;comment:评分，1~5分，乘10保存，避免小数" json:"score"` // 评分，1~5分，乘10保存，避免小数
 	OpenHours  string    `gorm:"column:open_hours;type:varchar(32);comment:营业时间，例如 10:00-22:00" json:"open_hours"`            // 营业时间，例如 10:00-22:00
+	OwnerID    uint64    `gorm:"column:owner_id;type:bigint unsigned;comment:店主的用户id, 可核销本店的优惠券订单" json:"owner_id"`           // 店主的用户id, 可核销本店的优惠券订单
 	CreateTime time.Time `gorm:"column:create_time;type:timestamp;default:CURRENT_TIMESTAMP;comment:创建时间" json:"create_time"` // 创建时间
 	UpdateTime time.Time `gorm:"column:update_time;type:timestamp;default:CURRENT_TIMESTAMP;comment:更新时间" json:"update_time"` // 更新时间
 }
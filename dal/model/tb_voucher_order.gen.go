@@ -12,16 +12,20 @@ const TableNameTbVoucherOrder = "tb_voucher_order"
 
 // TbVoucherOrder mapped from table <tb_voucher_order>
 type TbVoucherOrder struct {
-	ID         int64     `gorm:"column:id;type:bigint;primaryKey;comment:主键" json:"id"`                                                                 // 主键
-	UserID     uint64    `gorm:"column:user_id;type:bigint unsigned;not null;comment:下单的用户id" json:"user_id"`                                           // 下单的用户id
-	VoucherID  uint64    `gorm:"column:voucher_id;type:bigint unsigned;not null;comment:购买的代金券id" json:"voucher_id"`                                    // 购买的代金券id
-	PayType    uint8     `gorm:"column:pay_type;type:tinyint unsigned;not null;default:1;comment:支付方式 1：余额支付；2：支付宝；3：微信" json:"pay_type"`               // 支付方式 1：余额支付；2：支付宝；3：微信
-	Status     uint8     `gorm:"column:status;type:tinyint unsigned;not null;default:1;comment:订单状态，1：未支付；2：已支付；3：已核销；4：已取消；5：退款中；6：已退款" json:"status"` // 订单状态，1：未支付；2：已支付；3：已核销；4：已取消；5：退款中；6：已退款
-	CreateTime time.Time `gorm:"column:create_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:下单时间" json:"create_time"`                  // 下单时间
-	PayTime    time.Time `gorm:"column:pay_time;type:timestamp;comment:支付时间" json:"pay_time"`                                                           // 支付时间
-	UseTime    time.Time `gorm:"column:use_time;type:timestamp;comment:核销时间" json:"use_time"`                                                           // 核销时间
-	RefundTime time.Time `gorm:"column:refund_time;type:timestamp;comment:退款时间" json:"refund_time"`                                                     // 退款时间
-	UpdateTime time.Time `gorm:"column:update_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:更新时间" json:"update_time"`                  // 更新时间
+	ID          int64     `gorm:"column:id;type:bigint;primaryKey;comment:主键" json:"id"`                                                                 // 主键
+	UserID      uint64    `gorm:"column:user_id;type:bigint unsigned;not null;comment:下单的用户id" json:"user_id"`                                           // 下单的用户id
+	VoucherID   uint64    `gorm:"column:voucher_id;type:bigint unsigned;not null;comment:购买的代金券id" json:"voucher_id"`                                    // 购买的代金券id
+	ShopID      uint64    `gorm:"column:shop_id;type:bigint unsigned;not null;default:0;comment:下单时代金券所属的商铺id" json:"shop_id"`                           // 下单时代金券所属的商铺id
+	Title       string    `gorm:"column:title;type:varchar(255);not null;default:'';comment:下单时的代金券标题" json:"title"`                                     // 下单时的代金券标题
+	PayValue    uint64    `gorm:"column:pay_value;type:bigint unsigned;not null;default:0;comment:下单时的支付金额，单位是分" json:"pay_value"`                       // 下单时的支付金额，单位是分
+	ActualValue int64     `gorm:"column:actual_value;type:bigint;not null;default:0;comment:下单时的抵扣金额，单位是分" json:"actual_value"`                          // 下单时的抵扣金额，单位是分
+	PayType     uint8     `gorm:"column:pay_type;type:tinyint unsigned;not null;default:1;comment:支付方式 1：余额支付；2：支付宝；3：微信" json:"pay_type"`               // 支付方式 1：余额支付；2：支付宝；3：微信
+	Status      uint8     `gorm:"column:status;type:tinyint unsigned;not null;default:1;comment:订单状态，1：未支付；2：已支付；3：已核销；4：已取消；5：退款中；6：已退款" json:"status"` // 订单状态，1：未支付；2：已支付；3：已核销；4：已取消；5：退款中；6：已退款
+	CreateTime  time.Time `gorm:"column:create_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:下单时间" json:"create_time"`                  // 下单时间
+	PayTime     time.Time `gorm:"column:pay_time;type:timestamp;comment:支付时间" json:"pay_time"`                                                           // 支付时间
+	UseTime     time.Time `gorm:"column:use_time;type:timestamp;comment:核销时间" json:"use_time"`                                                           // 核销时间
+	RefundTime  time.Time `gorm:"column:refund_time;type:timestamp;comment:退款时间" json:"refund_time"`                                                     // 退款时间
+	UpdateTime  time.Time `gorm:"column:update_time;type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:更新时间" json:"update_time"`                  // 更新时间
 }
 
 // TableName TbVoucherOrder's table name
@@ -16,25 +16,35 @@ import (
 )
 
 var (
-	Q                = new(Query)
-	TbBlog           *tbBlog
-	TbBlogComment    *tbBlogComment
-	TbFollow         *tbFollow
-	TbSeckillVoucher *tbSeckillVoucher
-	TbShop           *tbShop
-	TbShopType       *tbShopType
-	TbSign           *tbSign
-	TbUser           *tbUser
-	TbUserInfo       *tbUserInfo
-	TbVoucher        *tbVoucher
-	TbVoucherOrder   *tbVoucherOrder
+	Q                           = new(Query)
+	TbBlog                      *tbBlog
+	TbBlogComment               *tbBlogComment
+	TbBlogCommentReport         *tbBlogCommentReport
+	TbFollow                    *tbFollow
+	TbOutbox                    *tbOutbox
+	TbSeckillLottery            *tbSeckillLottery
+	TbSeckillLotteryParticipant *tbSeckillLotteryParticipant
+	TbSeckillStockLog           *tbSeckillStockLog
+	TbSeckillVoucher            *tbSeckillVoucher
+	TbShop                      *tbShop
+	TbShopType                  *tbShopType
+	TbSign                      *tbSign
+	TbUser                      *tbUser
+	TbUserInfo                  *tbUserInfo
+	TbVoucher                   *tbVoucher
+	TbVoucherOrder              *tbVoucherOrder
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
 	TbBlog = &Q.TbBlog
 	TbBlogComment = &Q.TbBlogComment
+	TbBlogCommentReport = &Q.TbBlogCommentReport
 	TbFollow = &Q.TbFollow
+	TbOutbox = &Q.TbOutbox
+	TbSeckillLottery = &Q.TbSeckillLottery
+	TbSeckillLotteryParticipant = &Q.TbSeckillLotteryParticipant
+	TbSeckillStockLog = &Q.TbSeckillStockLog
 	TbSeckillVoucher = &Q.TbSeckillVoucher
 	TbShop = &Q.TbShop
 	TbShopType = &Q.TbShopType
@@ -47,53 +57,68 @@ func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:               db,
-		TbBlog:           newTbBlog(db, opts...),
-		TbBlogComment:    newTbBlogComment(db, opts...),
-		TbFollow:         newTbFollow(db, opts...),
-		TbSeckillVoucher: newTbSeckillVoucher(db, opts...),
-		TbShop:           newTbShop(db, opts...),
-		TbShopType:       newTbShopType(db, opts...),
-		TbSign:           newTbSign(db, opts...),
-		TbUser:           newTbUser(db, opts...),
-		TbUserInfo:       newTbUserInfo(db, opts...),
-		TbVoucher:        newTbVoucher(db, opts...),
-		TbVoucherOrder:   newTbVoucherOrder(db, opts...),
+		db:                          db,
+		TbBlog:                      newTbBlog(db, opts...),
+		TbBlogComment:               newTbBlogComment(db, opts...),
+		TbBlogCommentReport:         newTbBlogCommentReport(db, opts...),
+		TbFollow:                    newTbFollow(db, opts...),
+		TbOutbox:                    newTbOutbox(db, opts...),
+		TbSeckillLottery:            newTbSeckillLottery(db, opts...),
+		TbSeckillLotteryParticipant: newTbSeckillLotteryParticipant(db, opts...),
+		TbSeckillStockLog:           newTbSeckillStockLog(db, opts...),
+		TbSeckillVoucher:            newTbSeckillVoucher(db, opts...),
+		TbShop:                      newTbShop(db, opts...),
+		TbShopType:                  newTbShopType(db, opts...),
+		TbSign:                      newTbSign(db, opts...),
+		TbUser:                      newTbUser(db, opts...),
+		TbUserInfo:                  newTbUserInfo(db, opts...),
+		TbVoucher:                   newTbVoucher(db, opts...),
+		TbVoucherOrder:              newTbVoucherOrder(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	TbBlog           tbBlog
-	TbBlogComment    tbBlogComment
-	TbFollow         tbFollow
-	TbSeckillVoucher tbSeckillVoucher
-	TbShop           tbShop
-	TbShopType       tbShopType
-	TbSign           tbSign
-	TbUser           tbUser
-	TbUserInfo       tbUserInfo
-	TbVoucher        tbVoucher
-	TbVoucherOrder   tbVoucherOrder
+	TbBlog                      tbBlog
+	TbBlogComment               tbBlogComment
+	TbBlogCommentReport         tbBlogCommentReport
+	TbFollow                    tbFollow
+	TbOutbox                    tbOutbox
+	TbSeckillLottery            tbSeckillLottery
+	TbSeckillLotteryParticipant tbSeckillLotteryParticipant
+	TbSeckillStockLog           tbSeckillStockLog
+	TbSeckillVoucher            tbSeckillVoucher
+	TbShop                      tbShop
+	TbShopType                  tbShopType
+	TbSign                      tbSign
+	TbUser                      tbUser
+	TbUserInfo                  tbUserInfo
+	TbVoucher                   tbVoucher
+	TbVoucherOrder              tbVoucherOrder
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:               db,
-		TbBlog:           q.TbBlog.clone(db),
-		TbBlogComment:    q.TbBlogComment.clone(db),
-		TbFollow:         q.TbFollow.clone(db),
-		TbSeckillVoucher: q.TbSeckillVoucher.clone(db),
-		TbShop:           q.TbShop.clone(db),
-		TbShopType:       q.TbShopType.clone(db),
-		TbSign:           q.TbSign.clone(db),
-		TbUser:           q.TbUser.clone(db),
-		TbUserInfo:       q.TbUserInfo.clone(db),
-		TbVoucher:        q.TbVoucher.clone(db),
-		TbVoucherOrder:   q.TbVoucherOrder.clone(db),
+		db:                          db,
+		TbBlog:                      q.TbBlog.clone(db),
+		TbBlogComment:               q.TbBlogComment.clone(db),
+		TbBlogCommentReport:         q.TbBlogCommentReport.clone(db),
+		TbFollow:                    q.TbFollow.clone(db),
+		TbOutbox:                    q.TbOutbox.clone(db),
+		TbSeckillLottery:            q.TbSeckillLottery.clone(db),
+		TbSeckillLotteryParticipant: q.TbSeckillLotteryParticipant.clone(db),
+		TbSeckillStockLog:           q.TbSeckillStockLog.clone(db),
+		TbSeckillVoucher:            q.TbSeckillVoucher.clone(db),
+		TbShop:                      q.TbShop.clone(db),
+		TbShopType:                  q.TbShopType.clone(db),
+		TbSign:                      q.TbSign.clone(db),
+		TbUser:                      q.TbUser.clone(db),
+		TbUserInfo:                  q.TbUserInfo.clone(db),
+		TbVoucher:                   q.TbVoucher.clone(db),
+		TbVoucherOrder:              q.TbVoucherOrder.clone(db),
 	}
 }
 
@@ -107,48 +132,63 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:               db,
-		TbBlog:           q.TbBlog.replaceDB(db),
-		TbBlogComment:    q.TbBlogComment.replaceDB(db),
-		TbFollow:         q.TbFollow.replaceDB(db),
-		TbSeckillVoucher: q.TbSeckillVoucher.replaceDB(db),
-		TbShop:           q.TbShop.replaceDB(db),
-		TbShopType:       q.TbShopType.replaceDB(db),
-		TbSign:           q.TbSign.replaceDB(db),
-		TbUser:           q.TbUser.replaceDB(db),
-		TbUserInfo:       q.TbUserInfo.replaceDB(db),
-		TbVoucher:        q.TbVoucher.replaceDB(db),
-		TbVoucherOrder:   q.TbVoucherOrder.replaceDB(db),
+		db:                          db,
+		TbBlog:                      q.TbBlog.replaceDB(db),
+		TbBlogComment:               q.TbBlogComment.replaceDB(db),
+		TbBlogCommentReport:         q.TbBlogCommentReport.replaceDB(db),
+		TbFollow:                    q.TbFollow.replaceDB(db),
+		TbOutbox:                    q.TbOutbox.replaceDB(db),
+		TbSeckillLottery:            q.TbSeckillLottery.replaceDB(db),
+		TbSeckillLotteryParticipant: q.TbSeckillLotteryParticipant.replaceDB(db),
+		TbSeckillStockLog:           q.TbSeckillStockLog.replaceDB(db),
+		TbSeckillVoucher:            q.TbSeckillVoucher.replaceDB(db),
+		TbShop:                      q.TbShop.replaceDB(db),
+		TbShopType:                  q.TbShopType.replaceDB(db),
+		TbSign:                      q.TbSign.replaceDB(db),
+		TbUser:                      q.TbUser.replaceDB(db),
+		TbUserInfo:                  q.TbUserInfo.replaceDB(db),
+		TbVoucher:                   q.TbVoucher.replaceDB(db),
+		TbVoucherOrder:              q.TbVoucherOrder.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	TbBlog           ITbBlogDo
-	TbBlogComment    ITbBlogCommentDo
-	TbFollow         ITbFollowDo
-	TbSeckillVoucher ITbSeckillVoucherDo
-	TbShop           ITbShopDo
-	TbShopType       ITbShopTypeDo
-	TbSign           ITbSignDo
-	TbUser           ITbUserDo
-	TbUserInfo       ITbUserInfoDo
-	TbVoucher        ITbVoucherDo
-	TbVoucherOrder   ITbVoucherOrderDo
+	TbBlog                      ITbBlogDo
+	TbBlogComment               ITbBlogCommentDo
+	TbBlogCommentReport         ITbBlogCommentReportDo
+	TbFollow                    ITbFollowDo
+	TbOutbox                    ITbOutboxDo
+	TbSeckillLottery            ITbSeckillLotteryDo
+	TbSeckillLotteryParticipant ITbSeckillLotteryParticipantDo
+	TbSeckillStockLog           ITbSeckillStockLogDo
+	TbSeckillVoucher            ITbSeckillVoucherDo
+	TbShop                      ITbShopDo
+	TbShopType                  ITbShopTypeDo
+	TbSign                      ITbSignDo
+	TbUser                      ITbUserDo
+	TbUserInfo                  ITbUserInfoDo
+	TbVoucher                   ITbVoucherDo
+	TbVoucherOrder              ITbVoucherOrderDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		TbBlog:           q.TbBlog.WithContext(ctx),
-		TbBlogComment:    q.TbBlogComment.WithContext(ctx),
-		TbFollow:         q.TbFollow.WithContext(ctx),
-		TbSeckillVoucher: q.TbSeckillVoucher.WithContext(ctx),
-		TbShop:           q.TbShop.WithContext(ctx),
-		TbShopType:       q.TbShopType.WithContext(ctx),
-		TbSign:           q.TbSign.WithContext(ctx),
-		TbUser:           q.TbUser.WithContext(ctx),
-		TbUserInfo:       q.TbUserInfo.WithContext(ctx),
-		TbVoucher:        q.TbVoucher.WithContext(ctx),
-		TbVoucherOrder:   q.TbVoucherOrder.WithContext(ctx),
+		TbBlog:                      q.TbBlog.WithContext(ctx),
+		TbBlogComment:               q.TbBlogComment.WithContext(ctx),
+		TbBlogCommentReport:         q.TbBlogCommentReport.WithContext(ctx),
+		TbFollow:                    q.TbFollow.WithContext(ctx),
+		TbOutbox:                    q.TbOutbox.WithContext(ctx),
+		TbSeckillLottery:            q.TbSeckillLottery.WithContext(ctx),
+		TbSeckillLotteryParticipant: q.TbSeckillLotteryParticipant.WithContext(ctx),
+		TbSeckillStockLog:           q.TbSeckillStockLog.WithContext(ctx),
+		TbSeckillVoucher:            q.TbSeckillVoucher.WithContext(ctx),
+		TbShop:                      q.TbShop.WithContext(ctx),
+		TbShopType:                  q.TbShopType.WithContext(ctx),
+		TbSign:                      q.TbSign.WithContext(ctx),
+		TbUser:                      q.TbUser.WithContext(ctx),
+		TbUserInfo:                  q.TbUserInfo.WithContext(ctx),
+		TbVoucher:                   q.TbVoucher.WithContext(ctx),
+		TbVoucherOrder:              q.TbVoucherOrder.WithContext(ctx),
 	}
 }
 
@@ -36,6 +36,7 @@ func newTbBlog(db *gorm.DB, opts ...gen.DOOption) tbBlog {
 	_tbBlog.Content = field.NewString(tableName, "content")
 	_tbBlog.Liked = field.NewUint64(tableName, "liked")
 	_tbBlog.Comments = field.NewUint64(tableName, "comments")
+	_tbBlog.Status = field.NewUint8(tableName, "status")
 	_tbBlog.CreateTime = field.NewTime(tableName, "create_time")
 	_tbBlog.UpdateTime = field.NewTime(tableName, "update_time")
 
@@ -56,6 +57,7 @@ type tbBlog struct {
 	Content    field.String // 探店的文字描述
 	Liked      field.Uint64 // 点赞数量
 	Comments   field.Uint64 // 评论数量
+	Status     field.Uint8  // 状态，0：正常，1：待审核，2：禁止查看
 	CreateTime field.Time   // 创建时间
 	UpdateTime field.Time   // 更新时间
 
@@ -82,6 +84,7 @@ func (t *tbBlog) updateTableName(table string) *tbBlog {
 	t.Content = field.NewString(table, "content")
 	t.Liked = field.NewUint64(table, "liked")
 	t.Comments = field.NewUint64(table, "comments")
+	t.Status = field.NewUint8(table, "status")
 	t.CreateTime = field.NewTime(table, "create_time")
 	t.UpdateTime = field.NewTime(table, "update_time")
 
@@ -100,7 +103,7 @@ func (t *tbBlog) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (t *tbBlog) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 10)
+	t.fieldMap = make(map[string]field.Expr, 11)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["shop_id"] = t.ShopID
 	t.fieldMap["user_id"] = t.UserID
@@ -109,6 +112,7 @@ func (t *tbBlog) fillFieldMap() {
 	t.fieldMap["content"] = t.Content
 	t.fieldMap["liked"] = t.Liked
 	t.fieldMap["comments"] = t.Comments
+	t.fieldMap["status"] = t.Status
 	t.fieldMap["create_time"] = t.CreateTime
 	t.fieldMap["update_time"] = t.UpdateTime
 }
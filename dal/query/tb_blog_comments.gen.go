@@ -36,6 +36,7 @@ func newTbBlogComment(db *gorm.DB, opts ...gen.DOOption) tbBlogComment {
 	_tbBlogComment.Content = field.NewString(tableName, "content")
 	_tbBlogComment.Liked = field.NewUint64(tableName, "liked")
 	_tbBlogComment.Status = field.NewUint8(tableName, "status")
+	_tbBlogComment.Reviewed = field.NewUint8(tableName, "reviewed")
 	_tbBlogComment.CreateTime = field.NewTime(tableName, "create_time")
 	_tbBlogComment.UpdateTime = field.NewTime(tableName, "update_time")
 
@@ -56,6 +57,7 @@ type tbBlogComment struct {
 	Content    field.String // 回复的内容
 	Liked      field.Uint64 // 点赞数
 	Status     field.Uint8  // 状态，0：正常，1：被举报，2：禁止查看
+	Reviewed   field.Uint8  // 是否已被管理员审核通过，通过后再被举报不再进入审核队列
 	CreateTime field.Time   // 创建时间
 	UpdateTime field.Time   // 更新时间
 
@@ -82,6 +84,7 @@ func (t *tbBlogComment) updateTableName(table string) *tbBlogComment {
 	t.Content = field.NewString(table, "content")
 	t.Liked = field.NewUint64(table, "liked")
 	t.Status = field.NewUint8(table, "status")
+	t.Reviewed = field.NewUint8(table, "reviewed")
 	t.CreateTime = field.NewTime(table, "create_time")
 	t.UpdateTime = field.NewTime(table, "update_time")
 
@@ -100,7 +103,7 @@ func (t *tbBlogComment) GetFieldByName(fieldName string) (field.OrderExpr, bool)
 }
 
 func (t *tbBlogComment) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 10)
+	t.fieldMap = make(map[string]field.Expr, 11)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["user_id"] = t.UserID
 	t.fieldMap["blog_id"] = t.BlogID
@@ -109,6 +112,7 @@ func (t *tbBlogComment) fillFieldMap() {
 	t.fieldMap["content"] = t.Content
 	t.fieldMap["liked"] = t.Liked
 	t.fieldMap["status"] = t.Status
+	t.fieldMap["reviewed"] = t.Reviewed
 	t.fieldMap["create_time"] = t.CreateTime
 	t.fieldMap["update_time"] = t.UpdateTime
 }
@@ -33,6 +33,7 @@ func newTbSeckillVoucher(db *gorm.DB, opts ...gen.DOOption) tbSeckillVoucher {
 	_tbSeckillVoucher.CreateTime = field.NewTime(tableName, "create_time")
 	_tbSeckillVoucher.BeginTime = field.NewTime(tableName, "begin_time")
 	_tbSeckillVoucher.EndTime = field.NewTime(tableName, "end_time")
+	_tbSeckillVoucher.Mode = field.NewUint8(tableName, "mode")
 	_tbSeckillVoucher.UpdateTime = field.NewTime(tableName, "update_time")
 
 	_tbSeckillVoucher.fillFieldMap()
@@ -50,6 +51,7 @@ type tbSeckillVoucher struct {
 	CreateTime field.Time   // 创建时间
 	BeginTime  field.Time   // 生效时间
 	EndTime    field.Time   // 失效时间
+	Mode       field.Uint8  // 秒杀模式，0：先到先得；1：预约抽签
 	UpdateTime field.Time   // 更新时间
 
 	fieldMap map[string]field.Expr
@@ -72,6 +74,7 @@ func (t *tbSeckillVoucher) updateTableName(table string) *tbSeckillVoucher {
 	t.CreateTime = field.NewTime(table, "create_time")
 	t.BeginTime = field.NewTime(table, "begin_time")
 	t.EndTime = field.NewTime(table, "end_time")
+	t.Mode = field.NewUint8(table, "mode")
 	t.UpdateTime = field.NewTime(table, "update_time")
 
 	t.fillFieldMap()
@@ -89,12 +92,13 @@ func (t *tbSeckillVoucher) GetFieldByName(fieldName string) (field.OrderExpr, bo
 }
 
 func (t *tbSeckillVoucher) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 6)
+	t.fieldMap = make(map[string]field.Expr, 7)
 	t.fieldMap["voucher_id"] = t.VoucherID
 	t.fieldMap["stock"] = t.Stock
 	t.fieldMap["create_time"] = t.CreateTime
 	t.fieldMap["begin_time"] = t.BeginTime
 	t.fieldMap["end_time"] = t.EndTime
+	t.fieldMap["mode"] = t.Mode
 	t.fieldMap["update_time"] = t.UpdateTime
 }
 
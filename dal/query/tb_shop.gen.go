@@ -41,6 +41,7 @@ func newTbShop(db *gorm.DB, opts ...gen.DOOption) tbShop {
 	_tbShop.Comments = field.NewUint64(tableName, "comments")
 	_tbShop.Score = field.NewUint64(tableName, "score")
 	_tbShop.OpenHours = field.NewString(tableName, "open_hours")
+	_tbShop.OwnerID = field.NewUint64(tableName, "owner_id")
 	_tbShop.CreateTime = field.NewTime(tableName, "create_time")
 	_tbShop.UpdateTime = field.NewTime(tableName, "update_time")
 
@@ -66,6 +67,7 @@ type tbShop struct {
 	Comments   field.Uint64  // 评论数量
 	Score      field.Uint64  // 评分，1~5分，乘10保存，避免小数
 	OpenHours  field.String  // 营业时间，例如 10:00-22:00
+	OwnerID    field.Uint64  // 店主的用户id, 可核销本店的优惠券订单
 	CreateTime field.Time    // 创建时间
 	UpdateTime field.Time    // 更新时间
 
@@ -97,6 +99,7 @@ func (t *tbShop) updateTableName(table string) *tbShop {
 	t.Comments = field.NewUint64(table, "comments")
 	t.Score = field.NewUint64(table, "score")
 	t.OpenHours = field.NewString(table, "open_hours")
+	t.OwnerID = field.NewUint64(table, "owner_id")
 	t.CreateTime = field.NewTime(table, "create_time")
 	t.UpdateTime = field.NewTime(table, "update_time")
 
@@ -115,7 +118,7 @@ func (t *tbShop) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (t *tbShop) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 15)
+	t.fieldMap = make(map[string]field.Expr, 16)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["name"] = t.Name
 	t.fieldMap["type_id"] = t.TypeID
@@ -129,6 +132,7 @@ func (t *tbShop) fillFieldMap() {
 	t.fieldMap["comments"] = t.Comments
 	t.fieldMap["score"] = t.Score
 	t.fieldMap["open_hours"] = t.OpenHours
+	t.fieldMap["owner_id"] = t.OwnerID
 	t.fieldMap["create_time"] = t.CreateTime
 	t.fieldMap["update_time"] = t.UpdateTime
 }
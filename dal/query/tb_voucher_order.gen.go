@@ -31,6 +31,10 @@ func newTbVoucherOrder(db *gorm.DB, opts ...gen.DOOption) tbVoucherOrder {
 	_tbVoucherOrder.ID = field.NewInt64(tableName, "id")
 	_tbVoucherOrder.UserID = field.NewUint64(tableName, "user_id")
 	_tbVoucherOrder.VoucherID = field.NewUint64(tableName, "voucher_id")
+	_tbVoucherOrder.ShopID = field.NewUint64(tableName, "shop_id")
+	_tbVoucherOrder.Title = field.NewString(tableName, "title")
+	_tbVoucherOrder.PayValue = field.NewUint64(tableName, "pay_value")
+	_tbVoucherOrder.ActualValue = field.NewInt64(tableName, "actual_value")
 	_tbVoucherOrder.PayType = field.NewUint8(tableName, "pay_type")
 	_tbVoucherOrder.Status = field.NewUint8(tableName, "status")
 	_tbVoucherOrder.CreateTime = field.NewTime(tableName, "create_time")
@@ -47,17 +51,21 @@ func newTbVoucherOrder(db *gorm.DB, opts ...gen.DOOption) tbVoucherOrder {
 type tbVoucherOrder struct {
 	tbVoucherOrderDo
 
-	ALL        field.Asterisk
-	ID         field.Int64  // 主键
-	UserID     field.Uint64 // 下单的用户id
-	VoucherID  field.Uint64 // 购买的代金券id
-	PayType    field.Uint8  // 支付方式 1：余额支付；2：支付宝；3：微信
-	Status     field.Uint8  // 订单状态，1：未支付；2：已支付；3：已核销；4：已取消；5：退款中；6：已退款
-	CreateTime field.Time   // 下单时间
-	PayTime    field.Time   // 支付时间
-	UseTime    field.Time   // 核销时间
-	RefundTime field.Time   // 退款时间
-	UpdateTime field.Time   // 更新时间
+	ALL         field.Asterisk
+	ID          field.Int64  // 主键
+	UserID      field.Uint64 // 下单的用户id
+	VoucherID   field.Uint64 // 购买的代金券id
+	ShopID      field.Uint64 // 下单时代金券所属的商铺id
+	Title       field.String // 下单时的代金券标题
+	PayValue    field.Uint64 // 下单时的支付金额，单位是分
+	ActualValue field.Int64  // 下单时的抵扣金额，单位是分
+	PayType     field.Uint8  // 支付方式 1：余额支付；2：支付宝；3：微信
+	Status      field.Uint8  // 订单状态，1：未支付；2：已支付；3：已核销；4：已取消；5：退款中；6：已退款
+	CreateTime  field.Time   // 下单时间
+	PayTime     field.Time   // 支付时间
+	UseTime     field.Time   // 核销时间
+	RefundTime  field.Time   // 退款时间
+	UpdateTime  field.Time   // 更新时间
 
 	fieldMap map[string]field.Expr
 }
@@ -77,6 +85,10 @@ func (t *tbVoucherOrder) updateTableName(table string) *tbVoucherOrder {
 	t.ID = field.NewInt64(table, "id")
 	t.UserID = field.NewUint64(table, "user_id")
 	t.VoucherID = field.NewUint64(table, "voucher_id")
+	t.ShopID = field.NewUint64(table, "shop_id")
+	t.Title = field.NewString(table, "title")
+	t.PayValue = field.NewUint64(table, "pay_value")
+	t.ActualValue = field.NewInt64(table, "actual_value")
 	t.PayType = field.NewUint8(table, "pay_type")
 	t.Status = field.NewUint8(table, "status")
 	t.CreateTime = field.NewTime(table, "create_time")
@@ -100,10 +112,14 @@ func (t *tbVoucherOrder) GetFieldByName(fieldName string) (field.OrderExpr, bool
 }
 
 func (t *tbVoucherOrder) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 10)
+	t.fieldMap = make(map[string]field.Expr, 14)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["user_id"] = t.UserID
 	t.fieldMap["voucher_id"] = t.VoucherID
+	t.fieldMap["shop_id"] = t.ShopID
+	t.fieldMap["title"] = t.Title
+	t.fieldMap["pay_value"] = t.PayValue
+	t.fieldMap["actual_value"] = t.ActualValue
 	t.fieldMap["pay_type"] = t.PayType
 	t.fieldMap["status"] = t.Status
 	t.fieldMap["create_time"] = t.CreateTime
@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -14,19 +16,38 @@ import (
 )
 
 var (
-	ServerOptions *ServerSetting
-	MySQLOptions  *MySQLSetting
-	RedisOptions  *RedisSetting
-	LogOptions    *logger.LogSettings
-	JWTOptions    *JWTSetting
+	ServerOptions      *ServerSetting
+	MySQLOptions       *MySQLSetting
+	RedisOptions       *RedisSetting
+	LogOptions         *logger.LogSettings
+	JWTOptions         *JWTSetting
+	AdminOptions       *AdminSetting
+	IdempotencyOptions *IdempotencySetting
+	RateLimitOptions   *RateLimitSetting
+	OrderOptions       *OrderSetting
+	MQOptions          *MQSetting
+	ReconcileOptions   *ReconcileSetting
+	ModerationOptions  *ModerationSetting
+)
+
+var (
+	changeHooksMu sync.Mutex
+	changeHooks   []func()
 )
 
 type Options struct {
-	Server *ServerSetting
-	MySQL  *MySQLSetting
-	Redis  *RedisSetting
-	Log    *logger.LogSettings
-	JWT    *JWTSetting
+	Server      *ServerSetting
+	MySQL       *MySQLSetting
+	Redis       *RedisSetting
+	Log         *logger.LogSettings
+	JWT         *JWTSetting
+	Admin       *AdminSetting
+	Idempotency *IdempotencySetting
+	RateLimit   *RateLimitSetting
+	Order       *OrderSetting
+	MQ          *MQSetting
+	Reconcile   *ReconcileSetting
+	Moderation  *ModerationSetting
 }
 
 type ServerSetting struct {
@@ -58,6 +79,74 @@ type JWTSetting struct {
 	Expire time.Duration
 }
 
+// IdempotencySetting 幂等键的配置, TTL 为首次响应的保存时间
+type IdempotencySetting struct {
+	TTL time.Duration
+}
+
+// RateLimitSetting 秒杀接口的令牌桶限流配置, Rate 为每秒生成的令牌数, Burst 为桶容量
+type RateLimitSetting struct {
+	UserRate     float64
+	UserBurst    int64
+	IPRate       float64
+	IPBurst      int64
+	VoucherRate  float64
+	VoucherBurst int64
+}
+
+// OrderSetting 订单配置, UnpaidTimeout 为未支付订单自动取消的超时时间, RedeemTokenTTL 为核销码的有效期,
+// RedeemSecret 为核销码的签名密钥, 与 JWT 密钥分开配置
+type OrderSetting struct {
+	UnpaidTimeout  time.Duration
+	RedeemTokenTTL time.Duration
+	RedeemSecret   string
+}
+
+// MQSetting 订单消息队列配置
+// Broker 为 redis(默认)、memory 或 nats, 使用 nats 时连接 NATSURL, 秒杀脚本写入 Redis 的消息会被转发过去;
+// 每个进程运行 Consumers 个消费者, 名称为 <ConsumerName>-<序号>, ConsumerName 为空时使用 <主机名>-<进程号>,
+// 配置 ConsumerName 时需保证每个进程不同;
+// 闲置超过 DeadConsumerIdle 且没有待确认消息的其他消费者会被移出消费者组;
+// 每次阻塞读取最多 BatchSize 条消息, 最长等待 BlockTime, 由 Workers 个协程并发处理;
+// 闲置超过 ClaimMinIdle 的待确认消息(消费者崩溃后遗留)会被认领重新处理, 每 ClaimInterval 扫描一次, 每次最多认领 ClaimCount 条;
+// 可重试的失败最多重试 MaxRetries 次, 第 n 次重试延迟 RetryBaseDelay*2^(n-1) 并加随机抖动, 不超过 RetryMaxDelay,
+// 每 RetryPollInterval 将到期的重试消息写回订单流
+type MQSetting struct {
+	Broker            string
+	NATSURL           string
+	ConsumerName      string
+	Consumers         int
+	DeadConsumerIdle  time.Duration
+	BlockTime         time.Duration
+	BatchSize         int64
+	Workers           int
+	ClaimMinIdle      time.Duration
+	ClaimInterval     time.Duration
+	ClaimCount        int64
+	MaxRetries        int
+	RetryBaseDelay    time.Duration
+	RetryMaxDelay     time.Duration
+	RetryPollInterval time.Duration
+}
+
+// ReconcileSetting 库存核对任务配置, 每 Interval 核对一次 Redis 与 MySQL 的秒杀库存;
+// AutoRepair 为 true 时以 MySQL 为准修复 Redis 库存, 否则只记录将要执行的修复
+type ReconcileSetting struct {
+	Interval   time.Duration
+	AutoRepair bool
+}
+
+// ModerationSetting 内容审核配置, WordsFile 为本地过滤的词表文件, 相对路径相对于配置文件所在目录;
+// 修改配置文件后重新加载词表
+type ModerationSetting struct {
+	WordsFile string
+}
+
+// AdminSetting 管理员配置, UserIDs 中的用户可以访问 /admin 下的接口
+type AdminSetting struct {
+	UserIDs []uint64
+}
+
 func NewOptions() (*Options, error) {
 	// 使用 pflag 读取命令行参数中的配置文件路径
 	configPath := pflag.StringP("config", "c", GetDefaultConfigPath(), "path to config file")
@@ -68,6 +157,7 @@ func NewOptions() (*Options, error) {
 
 	// 绑定环境变量，特别是JWT Secret
 	_ = vp.BindEnv("jwt.secret", "JWT_SECRET")
+	_ = vp.BindEnv("order.redeemsecret", "REDEEM_SECRET")
 	vp.AutomaticEnv()
 
 	if err := vp.ReadInConfig(); err != nil {
@@ -78,6 +168,7 @@ func NewOptions() (*Options, error) {
 	if err := vp.Unmarshal(&opts); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	resolvePaths(&opts, *configPath)
 
 	// 将读取到的配置赋值给全局变量
 	ServerOptions = opts.Server
@@ -85,6 +176,13 @@ func NewOptions() (*Options, error) {
 	RedisOptions = opts.Redis
 	LogOptions = opts.Log
 	JWTOptions = opts.JWT
+	AdminOptions = opts.Admin
+	IdempotencyOptions = opts.Idempotency
+	RateLimitOptions = opts.RateLimit
+	OrderOptions = opts.Order
+	MQOptions = opts.MQ
+	ReconcileOptions = opts.Reconcile
+	ModerationOptions = opts.Moderation
 
 	// 配置热更新逻辑
 	vp.WatchConfig()
@@ -96,21 +194,53 @@ func NewOptions() (*Options, error) {
 			slog.Error("Failed to re-unmarshal config on change:", "err", err)
 			return
 		}
+		resolvePaths(&updatedOpts, *configPath)
 		ServerOptions = updatedOpts.Server
 		MySQLOptions = updatedOpts.MySQL
 		RedisOptions = updatedOpts.Redis
 		LogOptions = updatedOpts.Log
 		JWTOptions = updatedOpts.JWT
+		AdminOptions = updatedOpts.Admin
+		IdempotencyOptions = updatedOpts.Idempotency
+		RateLimitOptions = updatedOpts.RateLimit
+		OrderOptions = updatedOpts.Order
+		MQOptions = updatedOpts.MQ
+		ReconcileOptions = updatedOpts.Reconcile
+		ModerationOptions = updatedOpts.Moderation
 
 		// 特别处理日志级别热更新
 		if newLevel := vp.GetString("log.level"); newLevel != "" {
 			logger.LogLevel.Set(logger.GetLogLevel(newLevel))
 		}
+		runChangeHooks()
 	})
 
 	return &opts, nil
 }
 
+// OnChange 注册配置热更新后执行的回调, 回调执行时全局配置已更新
+func OnChange(fn func()) {
+	changeHooksMu.Lock()
+	defer changeHooksMu.Unlock()
+	changeHooks = append(changeHooks, fn)
+}
+
+func runChangeHooks() {
+	changeHooksMu.Lock()
+	hooks := slices.Clone(changeHooks)
+	changeHooksMu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// resolvePaths 将配置中的相对路径转换为相对于配置文件所在目录的路径
+func resolvePaths(opts *Options, configPath string) {
+	if m := opts.Moderation; m != nil && m.WordsFile != "" && !filepath.IsAbs(m.WordsFile) {
+		m.WordsFile = filepath.Join(filepath.Dir(configPath), m.WordsFile)
+	}
+}
+
 //var once sync.Once
 //
 //func InitConfig(path string) {
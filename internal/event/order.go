@@ -0,0 +1,102 @@
+// Package event 定义订单流中消息的格式与编解码
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// OrderVersion 当前的订单事件版本, 秒杀与抽签的 Lua 脚本按此版本写入
+// 版本 2 增加下单时的优惠券快照
+const OrderVersion = 2
+
+// FieldOrder 编码后的订单事件所在的字段, 重试次数、死信原因等元数据仍为独立字段
+const FieldOrder = "event"
+
+// 旧版本(版本 0)的消息直接以独立字段保存订单信息
+const (
+	legacyFieldUserID    = "userID"
+	legacyFieldVoucherID = "voucherID"
+	legacyFieldOrderID   = "orderID"
+)
+
+var (
+	ErrMalformed          = errors.New("malformed order event")
+	ErrUnsupportedVersion = errors.New("unsupported order event version")
+)
+
+// Order 秒杀或抽签成功后写入订单流的事件, ID 以字符串编码, 避免 Lua cjson 将大整数转为浮点数丢失精度
+type Order struct {
+	Version   int    `json:"v"`
+	OrderID   int64  `json:"order_id,string"`
+	UserID    uint64 `json:"user_id,string"`
+	VoucherID uint64 `json:"voucher_id,string"`
+	// Snapshot 下单时的优惠券信息, 版本 1 的消息或秒杀状态缓存中没有快照时为 nil
+	Snapshot *OrderSnapshot `json:"snapshot,omitempty"`
+}
+
+// OrderSnapshot 下单时的优惠券快照, 商家之后修改优惠券不影响已下的订单
+type OrderSnapshot struct {
+	ShopID      uint64 `json:"shop_id,string"`
+	Title       string `json:"title"`
+	PayValue    uint64 `json:"pay_value,string"`
+	ActualValue int64  `json:"actual_value,string"`
+}
+
+func (o *Order) Validate() error {
+	if o.OrderID <= 0 || o.UserID == 0 || o.VoucherID == 0 {
+		return fmt.Errorf("%w: order_id, user_id and voucher_id are required", ErrMalformed)
+	}
+	return nil
+}
+
+// EncodeOrder 编码为当前版本的消息字段
+func EncodeOrder(o *Order) (map[string]any, error) {
+	e := *o
+	e.Version = OrderVersion
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(&e)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{FieldOrder: string(data)}, nil
+}
+
+// DecodeOrder 解析订单消息, 兼容没有 event 字段的旧版本消息; 格式错误时返回 ErrMalformed 或 ErrUnsupportedVersion
+func DecodeOrder(values map[string]string) (*Order, error) {
+	data, ok := values[FieldOrder]
+	if !ok {
+		return decodeLegacyOrder(values)
+	}
+
+	var o Order
+	if err := json.Unmarshal([]byte(data), &o); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if o.Version < 1 || o.Version > OrderVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, o.Version)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func decodeLegacyOrder(values map[string]string) (*Order, error) {
+	userID, err1 := strconv.ParseUint(values[legacyFieldUserID], 10, 64)
+	voucherID, err2 := strconv.ParseUint(values[legacyFieldVoucherID], 10, 64)
+	orderID, err3 := strconv.ParseInt(values[legacyFieldOrderID], 10, 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	o := &Order{OrderID: orderID, UserID: userID, VoucherID: voucherID}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
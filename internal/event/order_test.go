@@ -0,0 +1,54 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeOrder(t *testing.T) {
+	// 超过 2^53 的订单 ID 编码为字符串, 不会丢失精度
+	want := &Order{OrderID: 1<<62 + 1, UserID: 7, VoucherID: 2,
+		Snapshot: &OrderSnapshot{ShopID: 1, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}}
+	values, err := EncodeOrder(want)
+	require.NoError(t, err)
+	assert.Equal(t, `{"v":2,"order_id":"4611686018427387905","user_id":"7","voucher_id":"2",`+
+		`"snapshot":{"shop_id":"1","title":"50元代金券","pay_value":"4750","actual_value":"5000"}}`, values[FieldOrder])
+
+	got, err := DecodeOrder(map[string]string{FieldOrder: values[FieldOrder].(string), "retry_count": "1"})
+	require.NoError(t, err)
+	want.Version = OrderVersion
+	assert.Equal(t, want, got)
+}
+
+func TestDecodeOrderWithoutSnapshot(t *testing.T) {
+	got, err := DecodeOrder(map[string]string{FieldOrder: `{"v":1,"order_id":"100","user_id":"7","voucher_id":"2"}`})
+	require.NoError(t, err)
+	assert.Equal(t, &Order{Version: 1, OrderID: 100, UserID: 7, VoucherID: 2}, got)
+}
+
+func TestDecodeLegacyOrder(t *testing.T) {
+	got, err := DecodeOrder(map[string]string{"userID": "7", "voucherID": "2", "orderID": "100"})
+	require.NoError(t, err)
+	assert.Equal(t, &Order{OrderID: 100, UserID: 7, VoucherID: 2}, got)
+}
+
+func TestDecodeMalformedOrder(t *testing.T) {
+	for name, values := range map[string]map[string]string{
+		"empty":            {},
+		"legacy invalid":   {"userID": "7", "voucherID": "x", "orderID": "100"},
+		"invalid json":     {FieldOrder: "{"},
+		"invalid id":       {FieldOrder: `{"v":1,"order_id":"abc","user_id":"7","voucher_id":"2"}`},
+		"missing id":       {FieldOrder: `{"v":1,"order_id":"100","user_id":"7"}`},
+		"invalid snapshot": {FieldOrder: `{"v":2,"order_id":"100","user_id":"7","voucher_id":"2","snapshot":{"pay_value":"x"}}`},
+	} {
+		_, err := DecodeOrder(values)
+		assert.ErrorIs(t, err, ErrMalformed, name)
+	}
+
+	for _, version := range []string{"0", "3"} {
+		_, err := DecodeOrder(map[string]string{FieldOrder: `{"v":` + version + `,"order_id":"100","user_id":"7","voucher_id":"2"}`})
+		assert.ErrorIs(t, err, ErrUnsupportedVersion, version)
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+	"gorm.io/gorm"
+)
+
+type BlogHandler struct {
+	blogService service.BlogService
+}
+
+func NewBlogHandler(svc service.BlogService) *BlogHandler {
+	return &BlogHandler{
+		blogService: svc,
+	}
+}
+
+func (h *BlogHandler) CreateBlog(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	var req service.BlogCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		code.WriteResponse(c, code.ErrBind, err.Error())
+		return
+	}
+
+	blog, err := h.blogService.CreateBlog(c.Request.Context(), userID, &req)
+	writeBlogResponse(c, blog, err)
+}
+
+// GetBlog 查询笔记详情, 待审核和禁止查看的笔记只有作者和管理员可以查看
+func (h *BlogHandler) GetBlog(c *gin.Context) {
+	id, ok := parseBlogID(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	blog, err := h.blogService.GetBlog(c.Request.Context(), id, userID, !middleware.IsAdmin(userID))
+	writeBlogResponse(c, blog, err)
+}
+
+// DeleteBlog 删除自己发布的笔记
+func (h *BlogHandler) DeleteBlog(c *gin.Context) {
+	id, ok := parseBlogID(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	err := h.blogService.DeleteBlog(c.Request.Context(), id, userID)
+	writeBlogResponse(c, nil, err)
+}
+
+// ListHotBlogs 按点赞数分页查询热门笔记, page 从 1 开始
+func (h *BlogHandler) ListHotBlogs(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	blogs, err := h.blogService.ListHotBlogs(c.Request.Context(), userID, page, size)
+	if err != nil {
+		slog.Error("failed to list hot blogs", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, blogs)
+}
+
+// LikeBlog 点赞, 已点赞时取消点赞
+func (h *BlogHandler) LikeBlog(c *gin.Context) {
+	id, ok := parseBlogID(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	like, err := h.blogService.LikeBlog(c.Request.Context(), id, userID)
+	writeBlogResponse(c, like, err)
+}
+
+// ListBlogLikers 最早点赞的 5 位用户
+func (h *BlogHandler) ListBlogLikers(c *gin.Context) {
+	id, ok := parseBlogID(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	likers, err := h.blogService.ListBlogLikers(c.Request.Context(), id, userID, !middleware.IsAdmin(userID))
+	writeBlogResponse(c, likers, err)
+}
+
+func parseBlogID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Blog ID format")
+		return 0, false
+	}
+	return id, true
+}
+
+// parsePage 解析分页参数 page 与 size, page 从 1 开始, size 为 0 时使用默认值; 失败时已写入响应
+func parsePage(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid page")
+		return 0, 0, false
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid size")
+		return 0, 0, false
+	}
+	return page, size, true
+}
+
+func writeBlogResponse(c *gin.Context, data any, err error) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, data)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code.WriteResponse(c, code.ErrDatabase, "Blog not found")
+	case errors.Is(err, service.ErrInvalidBlog), errors.Is(err, service.ErrContentRejected):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	case errors.Is(err, service.ErrNotBlogAuthor):
+		code.WriteResponse(c, code.ErrPermissionDenied, err.Error())
+	default:
+		slog.Error("failed to handle blog request", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+	"gorm.io/gorm"
+)
+
+type CommentHandler struct {
+	commentService service.CommentService
+}
+
+func NewCommentHandler(svc service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		commentService: svc,
+	}
+}
+
+// CreateComment 评论笔记, answer_id 不为 0 时回复该评论
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	blogID, ok := parseBlogID(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	var req service.CommentCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		code.WriteResponse(c, code.ErrBind, err.Error())
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(c.Request.Context(), blogID, userID, &req)
+	writeCommentResponse(c, comment, err)
+}
+
+// ListComments 分页查询笔记的一级评论, 管理员可以看到被举报和禁止查看的评论
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	blogID, ok := parseBlogID(c)
+	if !ok {
+		return
+	}
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	comments, err := h.commentService.ListComments(c.Request.Context(), blogID, userID, !middleware.IsAdmin(userID), page, size)
+	writeCommentResponse(c, comments, err)
+}
+
+// ListReplies 分页查询一级评论下的回复
+func (h *CommentHandler) ListReplies(c *gin.Context) {
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	replies, err := h.commentService.ListReplies(c.Request.Context(), commentID, userID, !middleware.IsAdmin(userID), page, size)
+	writeCommentResponse(c, replies, err)
+}
+
+// DeleteComment 评论作者或笔记作者删除评论
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	err := h.commentService.DeleteComment(c.Request.Context(), commentID, userID)
+	writeCommentResponse(c, nil, err)
+}
+
+// LikeComment 点赞评论, 已点赞时取消点赞
+func (h *CommentHandler) LikeComment(c *gin.Context) {
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	like, err := h.commentService.LikeComment(c.Request.Context(), commentID, userID)
+	writeCommentResponse(c, like, err)
+}
+
+func parseCommentID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Comment ID format")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeCommentResponse(c *gin.Context, data any, err error) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, data)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code.WriteResponse(c, code.ErrDatabase, "Blog or comment not found")
+	case errors.Is(err, service.ErrInvalidComment), errors.Is(err, service.ErrContentRejected):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	case errors.Is(err, service.ErrNotCommentAuthor):
+		code.WriteResponse(c, code.ErrPermissionDenied, err.Error())
+	default:
+		slog.Error("failed to handle comment request", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
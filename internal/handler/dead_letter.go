@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+)
+
+type DeadLetterHandler struct {
+	deadLetterService service.DeadLetterService
+}
+
+func NewDeadLetterHandler(svc service.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterService: svc,
+	}
+}
+
+// List 分页查看死信队列, cursor 为上一页返回的 next_cursor
+func (h *DeadLetterHandler) List(c *gin.Context) {
+	count, err := strconv.ParseInt(c.DefaultQuery("count", "0"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid count")
+		return
+	}
+	page, err := h.deadLetterService.List(c.Request.Context(), c.Query("cursor"), count)
+	if err != nil {
+		code.WriteResponse(c, code.ErrDatabase, err.Error())
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, page)
+}
+
+// Replay 将死信重新投递到订单流
+func (h *DeadLetterHandler) Replay(c *gin.Context) {
+	req, ok := bindDeadLetterRequest(c)
+	if !ok {
+		return
+	}
+	n, err := h.deadLetterService.Replay(c.Request.Context(), req)
+	writeDeadLetterResponse(c, gin.H{"replayed": n}, err)
+}
+
+func (h *DeadLetterHandler) Discard(c *gin.Context) {
+	req, ok := bindDeadLetterRequest(c)
+	if !ok {
+		return
+	}
+	n, err := h.deadLetterService.Discard(c.Request.Context(), req)
+	writeDeadLetterResponse(c, gin.H{"discarded": n}, err)
+}
+
+// Reconcile 核对死信对应的订单, 未落库的归还 Redis 库存
+func (h *DeadLetterHandler) Reconcile(c *gin.Context) {
+	req, ok := bindDeadLetterRequest(c)
+	if !ok {
+		return
+	}
+	result, err := h.deadLetterService.Reconcile(c.Request.Context(), req)
+	writeDeadLetterResponse(c, result, err)
+}
+
+func bindDeadLetterRequest(c *gin.Context) (*service.DeadLetterRequest, bool) {
+	var req service.DeadLetterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		code.WriteResponse(c, code.ErrBind, err.Error())
+		return nil, false
+	}
+	return &req, true
+}
+
+func writeDeadLetterResponse(c *gin.Context, data any, err error) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, data)
+	case errors.Is(err, service.ErrNoDeadLetterSelected):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	default:
+		code.WriteResponse(c, code.ErrDatabase, err.Error())
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+	"gorm.io/gorm"
+)
+
+type FollowHandler struct {
+	followService service.FollowService
+}
+
+func NewFollowHandler(svc service.FollowService) *FollowHandler {
+	return &FollowHandler{
+		followService: svc,
+	}
+}
+
+// Follow 关注用户, 已关注时直接返回成功
+func (h *FollowHandler) Follow(c *gin.Context) {
+	userID, followUserID, ok := parseFollowIDs(c)
+	if !ok {
+		return
+	}
+	err := h.followService.Follow(c.Request.Context(), userID, followUserID)
+	writeFollowResponse(c, nil, err)
+}
+
+// Unfollow 取消关注, 未关注时直接返回成功
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	userID, followUserID, ok := parseFollowIDs(c)
+	if !ok {
+		return
+	}
+	err := h.followService.Unfollow(c.Request.Context(), userID, followUserID)
+	writeFollowResponse(c, nil, err)
+}
+
+// IsFollowing 当前用户是否关注了该用户
+func (h *FollowHandler) IsFollowing(c *gin.Context) {
+	userID, followUserID, ok := parseFollowIDs(c)
+	if !ok {
+		return
+	}
+	status, err := h.followService.IsFollowing(c.Request.Context(), userID, followUserID)
+	writeFollowResponse(c, status, err)
+}
+
+// ListFollowers 分页查询用户的粉丝
+func (h *FollowHandler) ListFollowers(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	users, err := h.followService.ListFollowers(c.Request.Context(), id, page, size)
+	writeFollowResponse(c, users, err)
+}
+
+// ListFollowees 分页查询用户关注的人
+func (h *FollowHandler) ListFollowees(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	users, err := h.followService.ListFollowees(c.Request.Context(), id, page, size)
+	writeFollowResponse(c, users, err)
+}
+
+// ListCommonFollows 当前用户与该用户的共同关注
+func (h *FollowHandler) ListCommonFollows(c *gin.Context) {
+	userID, otherID, ok := parseFollowIDs(c)
+	if !ok {
+		return
+	}
+	users, err := h.followService.ListCommonFollows(c.Request.Context(), userID, otherID)
+	writeFollowResponse(c, users, err)
+}
+
+func parseUserID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid User ID format")
+		return 0, false
+	}
+	return id, true
+}
+
+// parseFollowIDs 返回当前用户与路径中的用户 ID; 失败时已写入响应
+func parseFollowIDs(c *gin.Context) (uint64, uint64, bool) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func writeFollowResponse(c *gin.Context, data any, err error) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, data)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code.WriteResponse(c, code.ErrDatabase, "User not found")
+	case errors.Is(err, service.ErrInvalidFollow):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	default:
+		slog.Error("failed to handle follow request", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+)
+
+type LotteryHandler struct {
+	lotteryService service.LotteryService
+}
+
+func NewLotteryHandler(svc service.LotteryService) *LotteryHandler {
+	return &LotteryHandler{
+		lotteryService: svc,
+	}
+}
+
+func (h *LotteryHandler) Register(c *gin.Context) {
+	voucherID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Voucher ID format")
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+
+	err = h.lotteryService.Register(c.Request.Context(), voucherID, userID)
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, nil)
+	case errors.Is(err, service.ErrLotteryNotAvailable), errors.Is(err, service.ErrLotteryRegistered):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	default:
+		slog.Error("failed to register lottery", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
+
+func (h *LotteryHandler) GetResult(c *gin.Context) {
+	voucherID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Voucher ID format")
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+
+	result, err := h.lotteryService.GetResult(c.Request.Context(), voucherID, userID)
+	if err != nil {
+		slog.Error("failed to get lottery result", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, result)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+	"gorm.io/gorm"
+)
+
+type ModerationHandler struct {
+	moderationService service.ModerationService
+}
+
+func NewModerationHandler(svc service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: svc,
+	}
+}
+
+// ReportComment 举报评论, 被举报的评论进入审核队列并对其他用户隐藏
+func (h *ModerationHandler) ReportComment(c *gin.Context) {
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	err := h.moderationService.ReportComment(c.Request.Context(), commentID, userID)
+	writeModerationResponse(c, nil, err)
+}
+
+// ListQueue 分页查看审核队列, kind 为 blog 或 comment
+func (h *ModerationHandler) ListQueue(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	queue, err := h.moderationService.ListQueue(c.Request.Context(), c.DefaultQuery("kind", service.ModerationKindComment), page, size)
+	writeModerationResponse(c, queue, err)
+}
+
+// Review 通过或禁止查看审核队列中的内容
+func (h *ModerationHandler) Review(c *gin.Context) {
+	var req service.ModerationReviewDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		code.WriteResponse(c, code.ErrBind, err.Error())
+		return
+	}
+	err := h.moderationService.Review(c.Request.Context(), &req)
+	writeModerationResponse(c, nil, err)
+}
+
+func writeModerationResponse(c *gin.Context, data any, err error) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, data)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code.WriteResponse(c, code.ErrDatabase, "Blog or comment not found")
+	case errors.Is(err, service.ErrInvalidReview), errors.Is(err, service.ErrNotPendingReview):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	default:
+		slog.Error("failed to handle moderation request", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
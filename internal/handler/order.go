@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/payment"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+	"gorm.io/gorm"
+)
+
+type OrderHandler struct {
+	orderService service.OrderService
+}
+
+func NewOrderHandler(svc service.OrderService) *OrderHandler {
+	return &OrderHandler{
+		orderService: svc,
+	}
+}
+
+func (h *OrderHandler) Pay(c *gin.Context) {
+	orderID, userID, ok := parseOrderRequest(c)
+	if !ok {
+		return
+	}
+	var req service.PayOrderDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		code.WriteResponse(c, code.ErrBind, err.Error())
+		return
+	}
+
+	order, err := h.orderService.Pay(c.Request.Context(), orderID, userID, &req)
+	writeOrderResponse(c, order, err)
+}
+
+// IssueRedeemToken 用户获取核销码, 前端据此生成二维码
+func (h *OrderHandler) IssueRedeemToken(c *gin.Context) {
+	orderID, userID, ok := parseOrderRequest(c)
+	if !ok {
+		return
+	}
+	token, err := h.orderService.IssueRedeemToken(c.Request.Context(), orderID, userID)
+	if err != nil {
+		writeOrderResponse(c, nil, err)
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, token)
+}
+
+// Redeem 商户扫码核销
+func (h *OrderHandler) Redeem(c *gin.Context) {
+	merchantID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+	var req service.RedeemDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		code.WriteResponse(c, code.ErrBind, err.Error())
+		return
+	}
+
+	order, err := h.orderService.Redeem(c.Request.Context(), &req, merchantID)
+	writeOrderResponse(c, order, err)
+}
+
+func (h *OrderHandler) Cancel(c *gin.Context) {
+	orderID, userID, ok := parseOrderRequest(c)
+	if !ok {
+		return
+	}
+	order, err := h.orderService.Cancel(c.Request.Context(), orderID, userID)
+	writeOrderResponse(c, order, err)
+}
+
+func (h *OrderHandler) Refund(c *gin.Context) {
+	orderID, userID, ok := parseOrderRequest(c)
+	if !ok {
+		return
+	}
+	order, err := h.orderService.Refund(c.Request.Context(), orderID, userID)
+	writeOrderResponse(c, order, err)
+}
+
+// parseOrderRequest 解析路径中的订单 ID 和当前登录用户, 失败时已写入响应
+func parseOrderRequest(c *gin.Context) (int64, uint64, bool) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Order ID format")
+		return 0, 0, false
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return 0, 0, false
+	}
+	return orderID, userID, true
+}
+
+func writeOrderResponse(c *gin.Context, order *model.TbVoucherOrder, err error) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, order)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code.WriteResponse(c, code.ErrDatabase, "Order not found")
+	case errors.Is(err, payment.ErrUnsupportedPayType),
+		errors.Is(err, service.ErrInvalidRedeemToken):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	case errors.Is(err, service.ErrNotShopOwner):
+		code.WriteResponse(c, code.ErrPermissionDenied, err.Error())
+	case errors.Is(err, service.ErrInvalidOrderTransition),
+		errors.Is(err, service.ErrOrderPaying),
+		errors.Is(err, service.ErrOrderRefunding),
+		errors.Is(err, repository.ErrOrderStatusChanged):
+		code.WriteResponse(c, code.ErrOrderStatusConflict, err.Error())
+	case errors.Is(err, service.ErrPaymentFailed), errors.Is(err, service.ErrRefundFailed):
+		slog.Error("payment gateway failed", "err", err)
+		code.WriteResponse(c, code.ErrPaymentFailed, nil)
+	default:
+		slog.Error("failed to update order", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
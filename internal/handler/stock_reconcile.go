@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/hmmm42/city-picks/pkg/code"
+)
+
+type StockReconcileHandler struct {
+	reconcileService service.StockReconcileService
+}
+
+func NewStockReconcileHandler(svc service.StockReconcileService) *StockReconcileHandler {
+	return &StockReconcileHandler{
+		reconcileService: svc,
+	}
+}
+
+// Reconcile 立即核对秒杀库存, 默认只报告不一致, repair=true 时以 MySQL 为准修复 Redis 库存
+func (h *StockReconcileHandler) Reconcile(c *gin.Context) {
+	repair, err := strconv.ParseBool(c.DefaultQuery("repair", "false"))
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid repair")
+		return
+	}
+	report, err := h.reconcileService.Reconcile(c.Request.Context(), repair)
+	if err != nil {
+		code.WriteResponse(c, code.ErrDatabase, err.Error())
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, report)
+}
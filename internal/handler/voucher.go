@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
+	"math"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
 	"github.com/hmmm42/city-picks/internal/service"
 	"github.com/hmmm42/city-picks/pkg/code"
+	"github.com/hmmm42/city-picks/pkg/json_time"
+	"gorm.io/gorm"
 )
 
 type VoucherHandler struct {
@@ -29,6 +35,10 @@ func (h *VoucherHandler) CreateVoucher(c *gin.Context) {
 	}
 
 	err = h.voucherService.CreateVoucher(c.Request.Context(), &req)
+	if errors.Is(err, service.ErrInvalidSeckillMode) {
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+		return
+	}
 	if err != nil {
 		slog.Error("failed to create voucher", "err", err)
 		code.WriteResponse(c, code.ErrDatabase, nil)
@@ -37,9 +47,124 @@ func (h *VoucherHandler) CreateVoucher(c *gin.Context) {
 	code.WriteResponse(c, code.ErrSuccess, nil)
 }
 
+func (h *VoucherHandler) UpdateVoucher(c *gin.Context) {
+	var req service.VoucherUpdateDTO
+	if err := c.BindJSON(&req); err != nil {
+		slog.Error("failed to bind voucher update data", "err", err)
+		code.WriteResponse(c, code.ErrBind, nil)
+		return
+	}
+
+	err := h.voucherService.UpdateVoucher(c.Request.Context(), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.WriteResponse(c, code.ErrDatabase, "Voucher not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to update voucher", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, nil)
+}
+
+func (h *VoucherHandler) TakeDownVoucher(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Voucher ID format")
+		return
+	}
+
+	err = h.voucherService.TakeDownVoucher(c.Request.Context(), id)
+	h.writeLifecycleResponse(c, err, "failed to take down voucher")
+}
+
+type SeckillWindowRequest struct {
+	BeginTime json_time.CustomTime `json:"begin_time"`
+	EndTime   json_time.CustomTime `json:"end_time"`
+}
+
+func (h *VoucherHandler) UpdateSeckillWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Voucher ID format")
+		return
+	}
+
+	var req SeckillWindowRequest
+	if err = c.BindJSON(&req); err != nil {
+		slog.Error("failed to bind seckill window data", "err", err)
+		code.WriteResponse(c, code.ErrBind, nil)
+		return
+	}
+
+	err = h.voucherService.UpdateSeckillWindow(c.Request.Context(), id,
+		time.Time(req.BeginTime), time.Time(req.EndTime))
+	h.writeLifecycleResponse(c, err, "failed to update seckill window")
+}
+
+func (h *VoucherHandler) AdjustSeckillStock(c *gin.Context) {
+	var req service.StockAdjustDTO
+	if err := c.BindJSON(&req); err != nil {
+		slog.Error("failed to bind stock adjust data", "err", err)
+		code.WriteResponse(c, code.ErrBind, nil)
+		return
+	}
+	operatorID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+
+	stockLog, err := h.voucherService.AdjustSeckillStock(c.Request.Context(), &req, operatorID)
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, stockLog)
+	case errors.Is(err, service.ErrStockCacheMissing), errors.Is(err, service.ErrStockBelowIssued):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	case errors.Is(err, service.ErrStockCacheInconsistent):
+		slog.Error("failed to adjust seckill stock", "err", err, "voucher_id", req.VoucherID)
+		code.WriteResponse(c, code.ErrDatabase, service.ErrStockCacheInconsistent.Error())
+	default:
+		h.writeLifecycleResponse(c, err, "failed to adjust seckill stock")
+	}
+}
+
+func (h *VoucherHandler) ListSeckillStockLogs(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Voucher ID format")
+		return
+	}
+
+	logs, err := h.voucherService.ListSeckillStockLogs(c.Request.Context(), id)
+	if err != nil {
+		slog.Error("failed to list seckill stock logs", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, logs)
+}
+
+func (h *VoucherHandler) writeLifecycleResponse(c *gin.Context, err error, msg string) {
+	switch {
+	case err == nil:
+		code.WriteResponse(c, code.ErrSuccess, nil)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code.WriteResponse(c, code.ErrDatabase, "Voucher not found")
+	case errors.Is(err, service.ErrNotSeckillVoucher),
+		errors.Is(err, service.ErrVoucherExpired),
+		errors.Is(err, service.ErrInvalidTimeWindow):
+		code.WriteResponse(c, code.ErrValidation, err.Error())
+	default:
+		slog.Error(msg, "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+	}
+}
+
+// SeckillVoucherRequest 下单用户取自 JWT, 不能在请求体中指定
 type SeckillVoucherRequest struct {
 	VoucherID string `json:"voucher_id"`
-	UserID    string `json:"user_id"`
 }
 
 func (h *VoucherHandler) SeckillVoucher(c *gin.Context) {
@@ -51,28 +176,26 @@ func (h *VoucherHandler) SeckillVoucher(c *gin.Context) {
 		return
 	}
 
-	// 从 JWT token 中获取用户 ID
-	//userID, exists := c.Get("userID")
-	//if !exists {
-	//	slog.Error("userID not found in context")
-	//	code.WriteResponse(c, code.ErrValidation, nil)
-	//	return
-	//}
-
+	uid, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
 	vid, err := strconv.ParseUint(req.VoucherID, 10, 64)
 	if err != nil {
 		slog.Error("voucherID in context is not of type uint64", "err", err)
 		code.WriteResponse(c, code.ErrValidation, nil)
 		return
 	}
-	uid, err := strconv.ParseUint(req.UserID, 10, 64)
-	if err != nil {
-		slog.Error("userID in context is not of type uint64", "err", err)
-		code.WriteResponse(c, code.ErrValidation, nil)
+
+	orderID, err := h.voucherService.SeckillVoucher(c.Request.Context(), vid, uid, c.ClientIP())
+	var rateLimitErr *service.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		code.WriteResponse(c, code.ErrTooManyRequests, nil)
 		return
 	}
-
-	orderID, err := h.voucherService.SeckillVoucher(c.Request.Context(), vid, uid)
 	if err != nil {
 		slog.Error("failed to seckill voucher", "err", err)
 		code.WriteResponse(c, code.ErrDatabase, err.Error()) // 返回具体的错误信息
@@ -82,3 +205,29 @@ func (h *VoucherHandler) SeckillVoucher(c *gin.Context) {
 		"order_id": orderID,
 	})
 }
+
+// GetSeckillOrder 轮询异步创建的秒杀订单状态, 与下单一样按 JWT 用户查询, 只能查询自己的订单
+func (h *VoucherHandler) GetSeckillOrder(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		code.WriteResponse(c, code.ErrValidation, "Invalid Order ID format")
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		code.WriteResponse(c, code.ErrTokenInvalid, nil)
+		return
+	}
+
+	order, err := h.voucherService.GetSeckillOrder(c.Request.Context(), orderID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.WriteResponse(c, code.ErrDatabase, "Order not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get seckill order", "err", err)
+		code.WriteResponse(c, code.ErrDatabase, nil)
+		return
+	}
+	code.WriteResponse(c, code.ErrSuccess, order)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/middleware"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testUserHeader = "X-Test-User"
+
+// fakeSeckillService 记录每次下单的用户, 订单只能由下单用户查询
+type fakeSeckillService struct {
+	service.VoucherService
+	buyers []uint64
+}
+
+func (s *fakeSeckillService) SeckillVoucher(ctx context.Context, voucherID, userID uint64, clientIP string) (int64, error) {
+	s.buyers = append(s.buyers, userID)
+	return int64(len(s.buyers)), nil
+}
+
+func (s *fakeSeckillService) GetSeckillOrder(ctx context.Context, orderID int64, userID uint64) (*service.SeckillOrderDTO, error) {
+	if orderID < 1 || orderID > int64(len(s.buyers)) || s.buyers[orderID-1] != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &service.SeckillOrderDTO{OrderID: orderID}, nil
+}
+
+// newSeckillRouter 与 router 中的顺序一致: 先写入 JWT 用户, 再经过幂等中间件; 用 X-Test-User 请求头模拟 JWT 中间件
+func newSeckillRouter(t *testing.T, svc service.VoucherService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	jwt := func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader(testUserHeader), 10, 64); err == nil {
+			c.Set(middleware.ContextUserIDKey, id)
+		}
+	}
+	h := NewVoucherHandler(svc)
+	r := gin.New()
+	r.POST("/voucher/seckill", jwt, middleware.NewIdempotency(rdb, nil).Handle(), h.SeckillVoucher)
+	r.GET("/voucher/order/:id", jwt, h.GetSeckillOrder)
+	return r
+}
+
+func doSeckill(r *gin.Engine, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/voucher/seckill", strings.NewReader(body))
+	req.Header.Set(testUserHeader, user)
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func seckillOrderID(t *testing.T, w *httptest.ResponseRecorder) int64 {
+	var resp struct {
+		Data struct {
+			OrderID int64 `json:"order_id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Data.OrderID
+}
+
+// 订单属于 JWT 用户, 幂等键也按该用户隔离; 请求体中的 user_id 不影响下单用户
+func TestSeckillVoucherIdempotentForJWTUser(t *testing.T) {
+	svc := &fakeSeckillService{}
+	r := newSeckillRouter(t, svc)
+
+	first := doSeckill(r, "1", "k1", `{"voucher_id":"2"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	replayed := doSeckill(r, "1", "k1", `{"voucher_id":"2"}`)
+	require.Equal(t, http.StatusOK, replayed.Code)
+	assert.Equal(t, seckillOrderID(t, first), seckillOrderID(t, replayed))
+
+	reused := doSeckill(r, "1", "k1", `{"voucher_id":"2","user_id":"9"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+
+	require.Equal(t, http.StatusOK, doSeckill(r, "1", "", `{"voucher_id":"2","user_id":"9"}`).Code)
+	assert.Equal(t, []uint64{1, 1}, svc.buyers)
+}
+
+// 下单和查询订单使用同一个 JWT 用户, 下单后可以立即查询, 其他用户查询不到
+func TestGetSeckillOrderOfJWTUser(t *testing.T) {
+	r := newSeckillRouter(t, &fakeSeckillService{})
+	orderID := seckillOrderID(t, doSeckill(r, "1", "", `{"voucher_id":"2"}`))
+
+	get := func(user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/voucher/order/"+strconv.FormatInt(orderID, 10), nil)
+		req.Header.Set(testUserHeader, user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, get("1"))
+	assert.NotEqual(t, http.StatusOK, get("2"))
+}
@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/service"
+)
+
+const lotteryDrawInterval = time.Second
+
+// LotteryDrawJob 在抽签券到达 BeginTime 时开奖
+type LotteryDrawJob struct {
+	lotteryService service.LotteryService
+}
+
+func NewLotteryDrawJob(svc service.LotteryService) *LotteryDrawJob {
+	return &LotteryDrawJob{
+		lotteryService: svc,
+	}
+}
+
+func (j *LotteryDrawJob) Start(ctx context.Context) {
+	slog.Info("Lottery draw job started", "interval", lotteryDrawInterval)
+
+	ticker := time.NewTicker(lotteryDrawInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Lottery draw job stopped")
+			return
+		case <-ticker.C:
+			n, err := j.lotteryService.DrawPending(ctx)
+			if err != nil {
+				slog.Error("failed to draw lotteries", "err", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("Drew seckill lotteries", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/service"
+)
+
+const defaultStockReconcileInterval = 5 * time.Minute
+
+// StockReconcileJob 定期核对 Redis 与 MySQL 的秒杀库存, 按配置决定是否自动修复, 配置修改后下一轮生效
+type StockReconcileJob struct {
+	reconcileService service.StockReconcileService
+}
+
+func NewStockReconcileJob(svc service.StockReconcileService) *StockReconcileJob {
+	return &StockReconcileJob{
+		reconcileService: svc,
+	}
+}
+
+func (j *StockReconcileJob) Start(ctx context.Context) {
+	interval := stockReconcileInterval()
+	slog.Info("Stock reconcile job started", "interval", interval)
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stock reconcile job stopped")
+			return
+		case <-timer.C:
+			repair := config.ReconcileOptions != nil && config.ReconcileOptions.AutoRepair
+			if _, err := j.reconcileService.Reconcile(ctx, repair); err != nil && ctx.Err() == nil {
+				slog.Error("failed to reconcile seckill stock", "err", err)
+			}
+			timer.Reset(stockReconcileInterval())
+		}
+	}
+}
+
+func stockReconcileInterval() time.Duration {
+	if opts := config.ReconcileOptions; opts != nil && opts.Interval > 0 {
+		return opts.Interval
+	}
+	return defaultStockReconcileInterval
+}
@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/service"
+)
+
+const voucherExpireInterval = time.Minute
+
+// VoucherExpireJob 定期将超过 EndTime 的秒杀券标记为过期, 并清理 seckill:stock:* 与 seckill:order:* 等缓存
+type VoucherExpireJob struct {
+	voucherService service.VoucherService
+}
+
+func NewVoucherExpireJob(svc service.VoucherService) *VoucherExpireJob {
+	return &VoucherExpireJob{
+		voucherService: svc,
+	}
+}
+
+func (j *VoucherExpireJob) Start(ctx context.Context) {
+	slog.Info("Voucher expire job started", "interval", voucherExpireInterval)
+
+	ticker := time.NewTicker(voucherExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Voucher expire job stopped")
+			return
+		case <-ticker.C:
+			n, err := j.voucherService.ExpireVouchers(ctx)
+			if err != nil {
+				slog.Error("failed to expire vouchers", "err", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("Expired seckill vouchers", "count", n)
+			}
+		}
+	}
+}
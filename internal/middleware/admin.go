@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/pkg/code"
+)
+
+// Admin 校验当前用户是否为管理员, 必须在 JWT 中间件之后使用
+func Admin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			code.WriteResponse(c, code.ErrTokenInvalid, nil)
+			c.Abort()
+			return
+		}
+		if !IsAdmin(userID) {
+			slog.Warn("non-admin user tried to access admin api", "user_id", userID, "path", c.Request.URL.Path)
+			code.WriteResponse(c, code.ErrPermissionDenied, nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin 用户是否在配置的管理员列表中
+func IsAdmin(userID uint64) bool {
+	return config.AdminOptions != nil && slices.Contains(config.AdminOptions.UserIDs, userID)
+}
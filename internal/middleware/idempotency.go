@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/pkg/code"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotency-Replayed"
+
+	idempotencyKeyPrefix  = "idempotency:"
+	idempotencyProcessing = "processing:"
+	maxIdempotencyKeyLen  = 128
+	// 处理中标记的过期时间, 避免进程崩溃后同一个幂等键被长时间锁住
+	idempotencyLockTTL = 30 * time.Second
+	// 未配置 TTL 时首次响应的保存时间
+	defaultIdempotencyTTL = 24 * time.Hour
+)
+
+// idempotentResponse 保存在 Redis 中的首次响应
+type idempotentResponse struct {
+	BodyHash    string `json:"body_hash"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// responseRecorder 在写出响应的同时记录响应体
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+type Idempotency struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewIdempotency(rdb *redis.Client, setting *config.IdempotencySetting) *Idempotency {
+	ttl := defaultIdempotencyTTL
+	if setting != nil && setting.TTL > 0 {
+		ttl = setting.TTL
+	}
+	return &Idempotency{
+		rdb: rdb,
+		ttl: ttl,
+	}
+}
+
+// Handle 对携带 Idempotency-Key 请求头的请求保证幂等:
+// 首次请求的响应会在 Redis 中保存 TTL 时长, 重复请求直接重放该响应;
+// 首次请求仍在处理中时返回 409; 同一个幂等键携带不同请求体时返回 422;
+// 服务端错误(5xx)不保存, 允许客户端重试. 幂等键按用户隔离, 未登录的请求不能使用
+func (i *Idempotency) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			code.WriteResponse(c, code.ErrValidation, "Idempotency-Key is too long")
+			c.Abort()
+			return
+		}
+
+		userID, ok := GetUserID(c)
+		if !ok {
+			code.WriteResponse(c, code.ErrTokenInvalid, "Idempotency-Key requires a logged-in user")
+			c.Abort()
+			return
+		}
+		bodyHash, err := hashRequestBody(c)
+		if err != nil {
+			code.WriteResponse(c, code.ErrBind, nil)
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		redisKey := i.redisKey(c, userID, key)
+		acquired, err := i.rdb.SetNX(ctx, redisKey, idempotencyProcessing+bodyHash, idempotencyLockTTL).Result()
+		if err != nil {
+			// Redis 不可用时降级为普通请求
+			slog.Error("failed to acquire idempotency key", "err", err, "key", redisKey)
+			c.Next()
+			return
+		}
+		if !acquired {
+			i.replay(c, redisKey, bodyHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err = i.rdb.Del(ctx, redisKey).Err(); err != nil {
+				slog.Error("failed to release idempotency key", "err", err, "key", redisKey)
+			}
+			return
+		}
+
+		data, err := json.Marshal(idempotentResponse{
+			BodyHash:    bodyHash,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err == nil {
+			err = i.rdb.Set(ctx, redisKey, data, i.ttl).Err()
+		}
+		if err != nil {
+			slog.Error("failed to save idempotent response", "err", err, "key", redisKey)
+		}
+	}
+}
+
+func (i *Idempotency) replay(c *gin.Context, redisKey, bodyHash string) {
+	data, err := i.rdb.Get(c.Request.Context(), redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// 首次请求恰好失败并释放了幂等键, 让客户端重试
+		code.WriteResponse(c, code.ErrRequestInProgress, nil)
+		c.Abort()
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get idempotent response", "err", err, "key", redisKey)
+		code.WriteResponse(c, code.ErrUnknown, nil)
+		c.Abort()
+		return
+	}
+	if hash, ok := strings.CutPrefix(string(data), idempotencyProcessing); ok {
+		if hash != bodyHash {
+			code.WriteResponse(c, code.ErrIdempotencyKeyReused, nil)
+		} else {
+			code.WriteResponse(c, code.ErrRequestInProgress, nil)
+		}
+		c.Abort()
+		return
+	}
+
+	var resp idempotentResponse
+	if err = json.Unmarshal(data, &resp); err != nil {
+		slog.Error("failed to decode idempotent response", "err", err, "key", redisKey)
+		code.WriteResponse(c, code.ErrDecodingJSON, nil)
+		c.Abort()
+		return
+	}
+	if resp.BodyHash != bodyHash {
+		code.WriteResponse(c, code.ErrIdempotencyKeyReused, nil)
+		c.Abort()
+		return
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Data(resp.Status, resp.ContentType, resp.Body)
+	c.Abort()
+}
+
+// redisKey 幂等键按接口和用户隔离
+func (i *Idempotency) redisKey(c *gin.Context, userID uint64, key string) string {
+	return fmt.Sprintf("%s%s:%s:%d:%s", idempotencyKeyPrefix, c.Request.Method, c.FullPath(), userID, key)
+}
+
+// hashRequestBody 计算请求体的摘要, 并把读出的请求体放回供后续处理
+func hashRequestBody(c *gin.Context) (string, error) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserHeader = "X-Test-User"
+
+// newIdempotencyRouter 返回挂载幂等中间件的路由, 用 X-Test-User 请求头模拟 JWT 中间件写入的用户
+func newIdempotencyRouter(t *testing.T, setting *config.IdempotencySetting) (*gin.Engine, *miniredis.Miniredis, *int) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	idem := NewIdempotency(rdb, setting)
+
+	calls := 0
+	r := gin.New()
+	r.POST("/order", func(c *gin.Context) {
+		switch c.GetHeader(testUserHeader) {
+		case "1":
+			c.Set(ContextUserIDKey, uint64(1))
+		case "2":
+			c.Set(ContextUserIDKey, uint64(2))
+		}
+	}, idem.Handle(), func(c *gin.Context) {
+		calls++
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, "user=%s body=%s", c.GetHeader(testUserHeader), body)
+	})
+	return r, mr, &calls
+}
+
+func doIdempotent(r *gin.Engine, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if user != "" {
+		req.Header.Set(testUserHeader, user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	r, mr, calls := newIdempotencyRouter(t, &config.IdempotencySetting{TTL: time.Hour})
+
+	first := doIdempotent(r, "1", "k1", `{"id":1}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	second := doIdempotent(r, "1", "k1", `{"id":1}`)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, 1, *calls)
+
+	// 保存的响应按配置的 TTL 过期
+	ttl := mr.TTL("idempotency:POST:/order:1:k1")
+	assert.Equal(t, time.Hour, ttl)
+}
+
+// 不同用户使用相同的幂等键互不影响, 不能拿到别人的响应
+func TestIdempotencyScopedByUser(t *testing.T) {
+	r, _, calls := newIdempotencyRouter(t, nil)
+
+	doIdempotent(r, "1", "k1", `{"id":1}`)
+	w := doIdempotent(r, "2", "k1", `{"id":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotencyReplayedHeader))
+	assert.Contains(t, w.Body.String(), "user=2")
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyRequiresUser(t *testing.T) {
+	r, _, calls := newIdempotencyRouter(t, nil)
+
+	w := doIdempotent(r, "", "k1", `{"id":1}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestIdempotencyBodyMismatch(t *testing.T) {
+	r, mr, calls := newIdempotencyRouter(t, nil)
+
+	doIdempotent(r, "1", "k1", `{"id":1}`)
+	w := doIdempotent(r, "1", "k1", `{"id":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, *calls)
+
+	// 首次请求仍在处理中时, 请求体不同也返回 422, 相同则返回 409
+	require.NoError(t, mr.Set("idempotency:POST:/order:1:k2", idempotencyProcessing+"other"))
+	w = doIdempotent(r, "1", "k2", `{"id":1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	r, mr, calls := newIdempotencyRouter(t, nil)
+
+	sum := sha256.Sum256([]byte(`{"id":1}`))
+	require.NoError(t, mr.Set("idempotency:POST:/order:1:k1", idempotencyProcessing+hex.EncodeToString(sum[:])))
+	w := doIdempotent(r, "1", "k1", `{"id":1}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+// 未配置 TTL 时使用默认值, 处理中标记始终带过期时间
+func TestNewIdempotencyDefaultTTL(t *testing.T) {
+	assert.Equal(t, defaultIdempotencyTTL, NewIdempotency(nil, nil).ttl)
+	assert.Equal(t, defaultIdempotencyTTL, NewIdempotency(nil, &config.IdempotencySetting{}).ttl)
+}
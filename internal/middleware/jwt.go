@@ -10,6 +10,9 @@ import (
 	"github.com/hmmm42/city-picks/pkg/code"
 )
 
+// ContextUserIDKey JWT 校验通过后, 用户 ID 在 gin.Context 中的键
+const ContextUserIDKey = "user_id"
+
 type UserClaims struct {
 	UserID uint64 `json:"user_id"`
 	jwt.RegisteredClaims
@@ -57,11 +60,11 @@ func JWT() gin.HandlerFunc {
 		if token == "" {
 			ecode = code.ErrInvalidAuthHeader
 		} else {
-			_, err := ParseToken(token)
+			claims, err := ParseToken(token)
 			if err != nil {
 				ecode = code.ErrTokenInvalid
 			} else {
-				c.Set("user_id", token)
+				c.Set(ContextUserIDKey, claims.UserID)
 			}
 		}
 		if ecode != code.ErrSuccess {
@@ -72,3 +75,13 @@ func JWT() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// GetUserID 获取 JWT 中间件写入的当前用户 ID
+func GetUserID(c *gin.Context) (uint64, bool) {
+	v, exists := c.Get(ContextUserIDKey)
+	if !exists {
+		return 0, false
+	}
+	userID, ok := v.(uint64)
+	return userID, ok
+}
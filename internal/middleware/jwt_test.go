@@ -2,14 +2,23 @@ package middleware
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hmmm42/city-picks/internal/config"
 )
 
 func Test_JWT(t *testing.T) {
-	t.Log("Testing JWT secret retrieval")
-	config.InitConfig(config.GetDefaultConfigPath())
+	config.JWTOptions = &config.JWTSetting{Secret: "test-secret", Expire: time.Hour}
 
-	//t.Log(viper.Get("jwt.Secret"))
-	t.Log(string(getJWTSecret()))
+	token, err := GenerateToken(42)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := ParseToken(token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Fatalf("user id = %d, want 42", claims.UserID)
+	}
 }
@@ -0,0 +1,139 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hmmm42/city-picks/internal/config"
+)
+
+const (
+	regexPrefix  = "re:"
+	reviewPrefix = "review:"
+)
+
+// rule 词表中的一条规则, 关键词统一转为小写匹配
+type rule struct {
+	raw     string
+	keyword string
+	re      *regexp.Regexp
+	review  bool
+}
+
+func (r *rule) match(lower, text string) bool {
+	if r.re != nil {
+		return r.re.MatchString(text)
+	}
+	return strings.Contains(lower, r.keyword)
+}
+
+// localModerator 基于本地词表的关键词与正则过滤, 配置文件变更时重新加载词表
+type localModerator struct {
+	mu     sync.RWMutex
+	rules  []*rule
+	logger *slog.Logger
+}
+
+func (m *localModerator) Check(ctx context.Context, text string) (*Result, error) {
+	m.mu.RLock()
+	rules := m.rules
+	m.mu.RUnlock()
+
+	res := &Result{Verdict: VerdictPass}
+	lower := strings.ToLower(text)
+	for _, r := range rules {
+		if !r.match(lower, text) {
+			continue
+		}
+		res.Hits = append(res.Hits, r.raw)
+		if r.review {
+			res.Verdict = max(res.Verdict, VerdictReview)
+		} else {
+			res.Verdict = VerdictBlock
+		}
+	}
+	return res, nil
+}
+
+// reload 重新加载词表, 失败时保留原词表
+func (m *localModerator) reload() {
+	rules, err := loadRules(wordsFile())
+	if err != nil {
+		m.logger.Error("failed to load moderation words, keep the previous list", "err", err)
+		return
+	}
+	m.mu.Lock()
+	m.rules = rules
+	m.mu.Unlock()
+	m.logger.Info("moderation words loaded", "count", len(rules))
+}
+
+func wordsFile() string {
+	if config.ModerationOptions == nil {
+		return ""
+	}
+	return config.ModerationOptions.WordsFile
+}
+
+// loadRules 读取词表文件, 未配置词表时不过滤任何内容
+func loadRules(path string) ([]*rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open words file: %w", err)
+	}
+	defer f.Close()
+	return parseRules(f)
+}
+
+// parseRules 每行一条规则, 忽略空行和 # 开头的注释; review: 前缀表示命中后转人工审核, re: 前缀为正则表达式
+func parseRules(r io.Reader) ([]*rule, error) {
+	var rules []*rule
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ru := &rule{raw: line}
+		if rest, ok := strings.CutPrefix(line, reviewPrefix); ok {
+			ru.review = true
+			line = strings.TrimSpace(rest)
+		}
+		expr, isRegex := strings.CutPrefix(line, regexPrefix)
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		if isRegex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid regexp %q: %w", lineNo, expr, err)
+			}
+			ru.re = re
+		} else {
+			ru.keyword = strings.ToLower(expr)
+		}
+		rules = append(rules, ru)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read words file: %w", err)
+	}
+	return rules, nil
+}
+
+func NewLocalModerator(logger *slog.Logger) Moderator {
+	m := &localModerator{logger: logger}
+	m.reload()
+	config.OnChange(m.reload)
+	return m
+}
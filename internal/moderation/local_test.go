@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(strings.NewReader(`
+# 注释
+赌博
+review:代购
+re:加\s*微\s*信
+review:re:\d{11}
+re:
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 4)
+	assert.Equal(t, "赌博", rules[0].keyword)
+	assert.True(t, rules[1].review)
+	assert.NotNil(t, rules[2].re)
+	assert.True(t, rules[3].review)
+	assert.NotNil(t, rules[3].re)
+
+	_, err = parseRules(strings.NewReader("re:("))
+	assert.Error(t, err)
+}
+
+func TestLocalModeratorCheck(t *testing.T) {
+	rules, err := parseRules(strings.NewReader("spam\nreview:代购\nre:加\\s*微\\s*信"))
+	require.NoError(t, err)
+	m := &localModerator{rules: rules, logger: slog.Default()}
+	ctx := context.Background()
+
+	res, err := m.Check(ctx, "这家店很好吃")
+	require.NoError(t, err)
+	assert.Equal(t, VerdictPass, res.Verdict)
+
+	res, _ = m.Check(ctx, "可以代购")
+	assert.Equal(t, VerdictReview, res.Verdict)
+
+	// 同时命中时以最严格的结论为准
+	res, _ = m.Check(ctx, "代购请加 微信")
+	assert.Equal(t, VerdictBlock, res.Verdict)
+	assert.Len(t, res.Hits, 2)
+
+	res, _ = m.Check(ctx, "SPAM")
+	assert.Equal(t, VerdictBlock, res.Verdict)
+}
+
+// 重新加载失败时保留原词表
+func TestLocalModeratorReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	require.NoError(t, os.WriteFile(path, []byte("spam\n"), 0o644))
+	old := config.ModerationOptions
+	config.ModerationOptions = &config.ModerationSetting{WordsFile: path}
+	t.Cleanup(func() { config.ModerationOptions = old })
+
+	m := &localModerator{logger: slog.Default()}
+	m.reload()
+	require.Len(t, m.rules, 1)
+
+	require.NoError(t, os.WriteFile(path, []byte("spam\nscam\n"), 0o644))
+	m.reload()
+	require.Len(t, m.rules, 2)
+
+	require.NoError(t, os.WriteFile(path, []byte("re:("), 0o644))
+	m.reload()
+	assert.Len(t, m.rules, 2)
+}
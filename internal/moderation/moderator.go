@@ -0,0 +1,23 @@
+package moderation
+
+import "context"
+
+// Verdict 审核结论
+type Verdict uint8
+
+const (
+	VerdictPass   Verdict = iota // 通过
+	VerdictReview                // 需要人工审核, 内容先以待审核状态保存
+	VerdictBlock                 // 拒绝发布
+)
+
+// Result 审核结果, Hits 为命中的词条
+type Result struct {
+	Verdict Verdict
+	Hits    []string
+}
+
+// Moderator 内容审核, 接入第三方审核服务时实现该接口并替换 wire 中的 Provider
+type Moderator interface {
+	Check(ctx context.Context, text string) (*Result, error)
+}
@@ -0,0 +1,141 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/hmmm42/city-picks/internal/service"
+)
+
+const (
+	defaultConsumers          = 1
+	defaultDeadConsumerIdle   = time.Hour
+	deadConsumerCheckInterval = time.Minute
+)
+
+// OrderConsumerPool 在一个进程内运行多个订单消费者, 消费者名称为 <名称前缀>-<序号>, 名称前缀默认为 <主机名>-<进程号>,
+// 同一主机上的多个进程不会使用相同的名称; 进程重启后遗留的待确认消息由 ReclaimMessages 认领, 旧名称由失效消费者清理移除
+type OrderConsumerPool struct {
+	mq        repository.MessageQueue
+	consumers []*OrderConsumer
+	wg        sync.WaitGroup
+}
+
+func NewOrderConsumerPool(mq repository.MessageQueue, svc service.VoucherService) *OrderConsumerPool {
+	prefix, n := consumerNamePrefix(), defaultConsumers
+	if opts := config.MQOptions; opts != nil && opts.Consumers > 0 {
+		n = opts.Consumers
+	}
+
+	consumers := make([]*OrderConsumer, n)
+	for i := range consumers {
+		consumers[i] = NewOrderConsumer(fmt.Sprintf("%s-%d", prefix, i+1), mq, svc)
+	}
+	return &OrderConsumerPool{
+		mq:        mq,
+		consumers: consumers,
+	}
+}
+
+func consumerNamePrefix() string {
+	if opts := config.MQOptions; opts != nil && opts.ConsumerName != "" {
+		return opts.ConsumerName
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "consumer"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// Start 启动所有消费者、闲置消息认领、延时重试投递和失效消费者清理, ctx 取消后停止
+func (p *OrderConsumerPool) Start(ctx context.Context) {
+	if err := p.mq.CreateGroup(ctx); err != nil {
+		panic(err)
+	}
+
+	for _, c := range p.consumers {
+		p.run(func() { c.ConsumeMessages(ctx) })
+		slog.Info("Order consumer started", "consumer", c.consumerName)
+	}
+	// 认领闲置消息和投递到期的重试消息只需要一个消费者
+	p.run(func() { p.consumers[0].ReclaimMessages(ctx) })
+	p.run(func() { p.consumers[0].PromoteRetries(ctx) })
+	p.run(func() { p.cleanupDeadConsumers(ctx) })
+}
+
+func (p *OrderConsumerPool) run(f func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		f()
+	}()
+}
+
+// Wait 等待所有消费者处理完已读取的消息后退出, ctx 超时则放弃等待, 未确认的消息稍后会被重新认领
+func (p *OrderConsumerPool) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("All order consumers drained")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("order consumers did not drain in time: %w", ctx.Err())
+	}
+}
+
+func (p *OrderConsumerPool) cleanupDeadConsumers(ctx context.Context) {
+	ticker := time.NewTicker(deadConsumerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.removeDeadConsumers(ctx)
+		}
+	}
+}
+
+// removeDeadConsumers 将闲置过久且没有待确认消息的其他消费者移出消费者组,
+// 有待确认消息的消费者要等其消息被认领后再移除, 避免丢失消息
+func (p *OrderConsumerPool) removeDeadConsumers(ctx context.Context) int {
+	maxIdle := defaultDeadConsumerIdle
+	if opts := config.MQOptions; opts != nil && opts.DeadConsumerIdle > 0 {
+		maxIdle = opts.DeadConsumerIdle
+	}
+
+	consumers, err := p.mq.ListConsumers(ctx)
+	if err != nil {
+		slog.Error("failed to list order consumers", "err", err)
+		return 0
+	}
+
+	removed := 0
+	for _, info := range consumers {
+		own := slices.ContainsFunc(p.consumers, func(c *OrderConsumer) bool { return c.consumerName == info.Name })
+		if own || info.Pending > 0 || info.Idle < maxIdle {
+			continue
+		}
+		if err = p.mq.DeleteConsumer(ctx, info.Name); err != nil {
+			slog.Error("failed to delete dead consumer", "err", err, "consumer", info.Name)
+			continue
+		}
+		slog.Info("Deleted dead consumer", "consumer", info.Name, "idle", info.Idle)
+		removed++
+	}
+	return removed
+}
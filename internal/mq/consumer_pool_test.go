@@ -0,0 +1,82 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 未配置名称前缀时带上进程号, 同一主机上的两个进程不会使用相同的消费者名称
+func TestConsumerNamePrefixDefault(t *testing.T) {
+	old := config.MQOptions
+	config.MQOptions = nil
+	t.Cleanup(func() { config.MQOptions = old })
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s-%d", hostname, os.Getpid()), consumerNamePrefix())
+}
+
+func TestOrderConsumerPoolNamesAndDrain(t *testing.T) {
+	_, rdb, _, svc := setupConsumer(t)
+	config.MQOptions.ConsumerName = "node-a"
+	config.MQOptions.Consumers = 3
+
+	pool := NewOrderConsumerPool(repository.NewRedisMessageQueue(rdb), svc)
+	require.Len(t, pool.consumers, 3)
+	assert.Equal(t, "node-a-1", pool.consumers[0].consumerName)
+	assert.Equal(t, "node-a-3", pool.consumers[2].consumerName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+	addOrderMessages(t, rdb, 50, 10)
+	assert.Eventually(t, func() bool { return svc.createdCount() == 50 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), time.Second)
+	defer cancelWait()
+	require.NoError(t, pool.Wait(waitCtx))
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+}
+
+func TestRemoveDeadConsumers(t *testing.T) {
+	m, rdb, _, svc := setupConsumer(t)
+	config.MQOptions.ConsumerName = "node-a"
+	config.MQOptions.Consumers = 1
+	config.MQOptions.DeadConsumerIdle = time.Hour
+	pool := NewOrderConsumerPool(repository.NewRedisMessageQueue(rdb), svc)
+	ctx := context.Background()
+
+	// dead-idle 没有待确认消息, crashed-consumer 还有一条待确认消息, node-a-1 是当前进程的消费者
+	// 认领不存在的消息只会登记消费者及其活跃时间
+	for _, name := range []string{"dead-idle", "node-a-1"} {
+		require.NoError(t, rdb.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   repository.OrderStreamKey,
+			Group:    repository.OrderGroup,
+			Consumer: name,
+			Messages: []string{"0-1"},
+		}).Err())
+	}
+	crashAfterRead(t, rdb, map[string]any{"userID": "7", "voucherID": "1", "orderID": "100"})
+
+	assert.Equal(t, 0, pool.removeDeadConsumers(ctx))
+
+	m.SetTime(time.Now().Add(2 * time.Hour))
+	assert.Equal(t, 1, pool.removeDeadConsumers(ctx))
+
+	consumers, err := pool.mq.ListConsumers(ctx)
+	require.NoError(t, err)
+	names := make([]string, 0, len(consumers))
+	for _, c := range consumers {
+		names = append(names, c.Name)
+	}
+	assert.ElementsMatch(t, []string{"node-a-1", "crashed-consumer"}, names)
+}
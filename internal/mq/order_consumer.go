@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/event"
 	"github.com/hmmm42/city-picks/internal/repository"
 	"github.com/hmmm42/city-picks/internal/service"
-	"github.com/redis/go-redis/v9"
 )
 
 type OrderConsumer struct {
@@ -20,167 +22,269 @@ type OrderConsumer struct {
 }
 
 const (
-	maxRetries        = 3
 	checkIdleInterval = 2 * time.Second
+
+	// 未配置 mq 时的默认参数
+	defaultBatchSize     = 100
+	defaultBlockTime     = 2 * time.Second
+	defaultWorkers       = 8
+	defaultClaimMinIdle  = 30 * time.Second
+	defaultClaimInterval = 5 * time.Second
+	defaultClaimCount    = 10
 )
 
-func NewOrderConsumer(mq repository.MessageQueue, svc service.VoucherService) *OrderConsumer {
+func NewOrderConsumer(name string, mq repository.MessageQueue, svc service.VoucherService) *OrderConsumer {
 	return &OrderConsumer{
-		// TODO: 这里的 consumerName 可以从配置中获取
-		consumerName:   "consumer-1",
+		consumerName:   name,
 		mq:             mq,
 		voucherService: svc,
 	}
 }
 
-func (c *OrderConsumer) Start(ctx context.Context) {
-	if err := c.mq.CreateGroup(ctx); err != nil {
-		panic(err)
-	}
-
-	slog.Info("Order consumer started", "consumer", c.consumerName)
-
-	go c.ConsumeMessages(ctx)
-}
-
+// ConsumeMessages 阻塞读取一批新消息并交给 processBatch 并发处理
+// ctx 取消后不再读取新消息, 已读取的消息会处理完再返回
 func (c *OrderConsumer) ConsumeMessages(ctx context.Context) {
+	processCtx := context.WithoutCancel(ctx)
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Info("Order consumer stopped", "consumer", c.consumerName)
 			return
 		default:
-			msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName)
+			batchSize, blockTime, _ := consumeOptions()
+			msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName, batchSize, blockTime)
 			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
 				slog.Error("failed to read pending messages", "err", err)
 				time.Sleep(checkIdleInterval) // 避免错误循环
 				continue
 			}
 			if len(msgs) == 0 {
-				//time.Sleep(checkIdleInterval) // 没有消息时稍作等待
 				continue
 			}
+			c.processBatch(processCtx, msgs)
+		}
+	}
+}
 
-			for _, msg := range msgs {
-				var retryCount int
-				if count, ok := msg.Values["retry_count"].(string); ok {
-					retryCount, _ = strconv.Atoi(count)
-				}
+// consumeOptions 读取批量消费配置, 支持热更新
+func consumeOptions() (batchSize int64, blockTime time.Duration, workers int) {
+	batchSize, blockTime, workers = defaultBatchSize, defaultBlockTime, defaultWorkers
+	if opts := config.MQOptions; opts != nil {
+		if opts.BatchSize > 0 {
+			batchSize = opts.BatchSize
+		}
+		if opts.BlockTime > 0 {
+			blockTime = opts.BlockTime
+		}
+		if opts.Workers > 0 {
+			workers = opts.Workers
+		}
+	}
+	return
+}
 
-				if retryCount >= maxRetries {
-					slog.Warn("Message has reached max retries, moving to DLQ", "messageID", msg.ID, "retryCount", retryCount)
-					c.moveToDLQ(ctx, msg, fmt.Errorf("reached max retries: (%d)", retryCount))
-					continue
-				}
+// processBatch 按用户 ID 将消息分配给有限数量的协程并发处理,
+// 同一用户的消息总是由同一个协程按读取顺序处理
+func (c *OrderConsumer) processBatch(ctx context.Context, msgs []repository.Message) {
+	_, _, workers := consumeOptions()
+	workers = min(workers, len(msgs))
 
-				if err = c.handleOrderMsg(ctx, msg); err != nil {
-					slog.Error("failed to handle order message, requeueing", "messageID", msg.ID, "error", err)
-					c.requeueMessage(ctx, msg, retryCount+1)
-					continue
-				}
+	partitions := make([][]repository.Message, workers)
+	for _, msg := range msgs {
+		// 格式错误的消息都分到第一个协程, 由 processMessage 移入死信队列
+		var userID uint64
+		if order, err := parseOrderMsg(msg); err == nil {
+			userID = order.UserID
+		}
+		i := userID % uint64(workers)
+		partitions[i] = append(partitions[i], msg)
+	}
 
-				if err = c.mq.Ack(ctx, repository.OrderStreamKey, repository.OrderGroup, msg.ID); err != nil {
-					slog.Error("failed to ACK message", "err", err, "messageID", msg.ID)
-					// 如果 ACK 失败，我们选择不重试，可能是因为消息已经被处理过了
-				}
-			}
+	var wg sync.WaitGroup
+	for _, partition := range partitions {
+		if len(partition) == 0 {
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.processPartition(ctx, partition)
+		}()
 	}
+	wg.Wait()
 }
 
-func (c *OrderConsumer) handleOrderMsg(ctx context.Context, msg redis.XMessage) error {
-	//slog.Debug("Received message", "messageID", msg.ID, "values", msg.Values)
-	voucherID, _ := strconv.ParseUint(msg.Values["voucherID"].(string), 10, 64)
-	userID, _ := strconv.ParseUint(msg.Values["userID"].(string), 10, 64)
-	orderID, _ := strconv.ParseInt(msg.Values["orderID"].(string), 10, 64)
+// processPartition 先尝试在一个事务中批量写入整组订单, 失败时逐条处理, 由 processMessage 负责重试和死信
+func (c *OrderConsumer) processPartition(ctx context.Context, msgs []repository.Message) {
+	if len(msgs) == 1 {
+		c.processMessage(ctx, msgs[0])
+		return
+	}
 
-	order := &model.TbVoucherOrder{
-		ID:        orderID,
-		VoucherID: voucherID,
-		UserID:    userID,
+	batch := make([]repository.Message, 0, len(msgs))
+	orders := make([]*model.TbVoucherOrder, 0, len(msgs))
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		order, err := parseOrderMsg(msg)
+		if err != nil || retryCountOf(msg) > 0 {
+			// 格式错误或重试过的消息单独处理
+			c.processMessage(ctx, msg)
+			continue
+		}
+		batch = append(batch, msg)
+		orders = append(orders, order)
+		ids = append(ids, msg.ID)
+	}
+	if len(orders) == 0 {
+		return
+	}
+
+	if err := c.voucherService.CreateVoucherOrdersDB(ctx, orders); err != nil {
+		slog.Warn("failed to create orders in batch, falling back to one by one", "count", len(orders), "err", err)
+		for _, msg := range batch {
+			c.processMessage(ctx, msg)
+		}
+		return
+	}
+	if err := c.mq.Ack(ctx, repository.OrderStreamKey, repository.OrderGroup, ids...); err != nil {
+		slog.Error("failed to ACK messages", "err", err, "count", len(ids))
+	}
+}
+
+// ReclaimMessages 定期认领闲置过久的待确认消息, 例如消费者在 XREADGROUP 之后、XACK 之前崩溃遗留的消息,
+// 按相同的重试和死信规则重新处理
+func (c *OrderConsumer) ReclaimMessages(ctx context.Context) {
+	interval := defaultClaimInterval
+	if opts := config.MQOptions; opts != nil && opts.ClaimInterval > 0 {
+		interval = opts.ClaimInterval
 	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Order reclaimer stopped", "consumer", c.consumerName)
+			return
+		case <-ticker.C:
+			c.reclaimOnce(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+func (c *OrderConsumer) reclaimOnce(ctx context.Context) int {
+	minIdle, count := defaultClaimMinIdle, int64(defaultClaimCount)
+	if opts := config.MQOptions; opts != nil {
+		if opts.ClaimMinIdle > 0 {
+			minIdle = opts.ClaimMinIdle
+		}
+		if opts.ClaimCount > 0 {
+			count = opts.ClaimCount
+		}
+	}
+
+	msgs, err := c.mq.ClaimMessage(ctx, c.consumerName, minIdle, count)
+	if err != nil {
+		slog.Error("failed to claim idle messages", "err", err)
+	}
+	if len(msgs) == 0 {
+		return 0
+	}
+	slog.Info("Claimed idle messages", "consumer", c.consumerName, "count", len(msgs))
+	for _, msg := range msgs {
+		c.processMessage(ctx, msg)
+	}
+	return len(msgs)
+}
+
+// processMessage 处理一条订单消息: 成功后确认; 可重试的失败按指数退避放入延时重试集合,
+// 不可重试或超过最大重试次数的失败移入死信队列
+func (c *OrderConsumer) processMessage(ctx context.Context, msg repository.Message) {
+	err := c.handleOrderMsg(ctx, msg)
+	if err == nil {
+		if err = c.mq.Ack(ctx, repository.OrderStreamKey, repository.OrderGroup, msg.ID); err != nil {
+			slog.Error("failed to ACK message", "err", err, "messageID", msg.ID)
+			// 如果 ACK 失败，我们选择不重试，可能是因为消息已经被处理过了
+		}
+		return
+	}
+
+	retryCount := retryCountOf(msg)
+	maxRetries, _, _, _ := retryOptions()
+	switch {
+	case !isRetryable(err):
+		slog.Warn("Order message failed with permanent error, moving to DLQ", "messageID", msg.ID, "error", err)
+		c.moveToDLQ(ctx, msg, err)
+	case retryCount >= maxRetries:
+		slog.Warn("Message has reached max retries, moving to DLQ", "messageID", msg.ID, "retryCount", retryCount)
+		c.moveToDLQ(ctx, msg, fmt.Errorf("reached max retries (%d): %w", retryCount, err))
+	default:
+		delay := retryDelay(retryCount + 1)
+		slog.Error("failed to handle order message, retrying later", "messageID", msg.ID, "error", err, "delay", delay)
+		c.scheduleRetry(ctx, msg, retryCount+1, delay)
+	}
+}
+
+func (c *OrderConsumer) handleOrderMsg(ctx context.Context, msg repository.Message) error {
+	order, err := parseOrderMsg(msg)
+	if err != nil {
+		return err
+	}
 	return c.voucherService.CreateVoucherOrderDB(ctx, order)
 }
 
-//func (c *OrderConsumer) processNewMsgs(ctx context.Context) {
-//	for {
-//		select {
-//		case <-ctx.Done():
-//			slog.Info("Order consumer stopped", "consumer", c.consumerName)
-//			return
-//		default:
-//			messages, err := c.mq.ReadPendingMessages(ctx, c.consumerName)
-//			if err != nil {
-//				slog.Error("failed to read pending messages", "err", err)
-//				time.Sleep(1 * time.Second) // 避免错误循环
-//				continue
-//			}
-//			if len(messages) == 0 {
-//				continue
-//			}
-//			for _, msg := range messages {
-//				if err = c.handleOrderMsg(ctx, msg); err != nil {
-//					slog.Error("failed to handle new message, will be retried later")
-//				}
-//			}
-//		}
-//	}
-//}
-//
-//func (c *OrderConsumer) recoverAndHandleDeadLetters(ctx context.Context) {
-//	ticker := time.NewTicker(checkIdleInterval)
-//	defer ticker.Stop()
-//
-//	for {
-//		select {
-//		case <-ctx.Done():
-//			slog.Info("Order consumer stopped", "consumer", c.consumerName)
-//			return
-//		case <-ticker.C:
-//			claimedMessages, err := c.mq.ClaimMessage(ctx, c.consumerName, minIdleTime, 10)
-//			if err != nil {
-//				slog.Error("failed to claim messages", "err", err)
-//				continue
-//			}
-//			if len(claimedMessages) == 0 {
-//				continue
-//			}
-//			slog.Info("Claimed messages", "count", len(claimedMessages))
-//			for _, msg := range claimedMessages {
-//				if err = c.handleOrderMsg(ctx, msg); err != nil {
-//					slog.Error("failed to handle message", "err", err)
-//
-//				}
-//			}
-//		}
-//	}
-//}
-
-func (c *OrderConsumer) requeueMessage(ctx context.Context, msg redis.XMessage, newRetryCount int) {
-	requeueValues := make(map[string]any)
+// parseOrderMsg 解码订单事件, 兼容旧版本的消息; 格式错误时返回 errInvalidOrderMsg, 不会重试
+func parseOrderMsg(msg repository.Message) (*model.TbVoucherOrder, error) {
+	o, err := event.DecodeOrder(msg.Values)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", errInvalidOrderMsg, msg.ID, err)
+	}
+
+	order := &model.TbVoucherOrder{
+		ID:        o.OrderID,
+		VoucherID: o.VoucherID,
+		UserID:    o.UserID,
+	}
+	// 没有快照的旧消息由 VoucherService 按当前优惠券补全
+	if snap := o.Snapshot; snap != nil {
+		order.ShopID = snap.ShopID
+		order.Title = snap.Title
+		order.PayValue = snap.PayValue
+		order.ActualValue = snap.ActualValue
+	}
+	return order, nil
+}
+
+func retryCountOf(msg repository.Message) int {
+	retryCount, _ := strconv.Atoi(msg.Values["retry_count"])
+	return retryCount
+}
+
+// scheduleRetry 带上新的重试次数放入延时重试集合, 成功后确认原消息
+func (c *OrderConsumer) scheduleRetry(ctx context.Context, msg repository.Message, newRetryCount int, delay time.Duration) {
+	retryValues := make(map[string]string, len(msg.Values)+1)
 	for k, v := range msg.Values {
-		requeueValues[k] = v
+		retryValues[k] = v
 	}
 	// 更新或添加重试次数字段
-	requeueValues["retry_count"] = strconv.Itoa(newRetryCount)
+	retryValues["retry_count"] = strconv.Itoa(newRetryCount)
 
-	// 将新消息添加到流的末尾
-	if _, err := c.mq.AddToStream(ctx, repository.OrderStreamKey, requeueValues); err != nil {
-		slog.Error("failed to requeue message", "err", err, "originalMessageID", msg.ID)
-		// 如果重入队列失败，我们选择不ACK原消息，让它被其他消费者认领，这是降级策略
+	if err := c.mq.ScheduleRetry(ctx, msg.ID, retryValues, time.Now().Add(delay)); err != nil {
+		slog.Error("failed to schedule retry", "err", err, "originalMessageID", msg.ID)
+		// 如果放入重试集合失败，我们选择不ACK原消息，让它被其他消费者认领，这是降级策略
 		return
 	}
 
-	// 确认原消息，将其从PEL中移除
+	// 确认原消息，将其从PEL中移除; 即使 ACK 失败导致重复消费, 订单落库也是幂等的
 	if err := c.mq.Ack(ctx, repository.OrderStreamKey, repository.OrderGroup, msg.ID); err != nil {
-		slog.Error("failed to ACK message after requeueing", "err", err, "messageID", msg.ID)
+		slog.Error("failed to ACK message after scheduling retry", "err", err, "messageID", msg.ID)
 	}
 }
 
-func (c *OrderConsumer) moveToDLQ(ctx context.Context, msg redis.XMessage, processErr error) {
-	slog.Warn("Message failed after max retries, moving to DLQ", "messageID", msg.ID, "error", processErr.Error())
-
+func (c *OrderConsumer) moveToDLQ(ctx context.Context, msg repository.Message, processErr error) {
 	// 在消息中添加失败信息
 	dlqValues := map[string]any{
 		"original_id": msg.ID,
@@ -198,6 +302,13 @@ func (c *OrderConsumer) moveToDLQ(ctx context.Context, msg redis.XMessage, proce
 		// 如果连DLQ都失败，还是要尝试ACK原消息，防止阻塞
 	}
 
+	// 通知轮询的客户端订单创建失败
+	if order, err := parseOrderMsg(msg); err == nil {
+		if err = c.voucherService.MarkSeckillOrderFailed(ctx, order.ID); err != nil {
+			slog.Error("failed to mark seckill order failed", "err", err, "orderID", order.ID)
+		}
+	}
+
 	// 从主队列中 ACK，移除该消息
 	if err := c.mq.Ack(ctx, repository.OrderStreamKey, repository.OrderGroup, msg.ID); err != nil {
 		slog.Error("failed to ACK message after moving to DLQ", "err", err, "messageID", msg.ID)
@@ -0,0 +1,347 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/event"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVoucherService 只实现消费者用到的方法
+type fakeVoucherService struct {
+	service.VoucherService
+
+	mu      sync.Mutex
+	err     error
+	latency time.Duration // 模拟一次数据库写入的耗时
+	created []int64
+	failed  []int64
+}
+
+func (s *fakeVoucherService) CreateVoucherOrderDB(ctx context.Context, order *model.TbVoucherOrder) error {
+	return s.CreateVoucherOrdersDB(ctx, []*model.TbVoucherOrder{order})
+}
+
+func (s *fakeVoucherService) CreateVoucherOrdersDB(_ context.Context, orders []*model.TbVoucherOrder) error {
+	time.Sleep(s.latency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	for _, order := range orders {
+		s.created = append(s.created, order.ID)
+	}
+	return nil
+}
+
+func (s *fakeVoucherService) createdCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.created)
+}
+
+func (s *fakeVoucherService) MarkSeckillOrderFailed(_ context.Context, orderID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = append(s.failed, orderID)
+	return nil
+}
+
+func setupConsumer(t testing.TB) (*miniredis.Miniredis, *redis.Client, *OrderConsumer, *fakeVoucherService) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	m.SetTime(time.Now())
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	old := config.MQOptions
+	config.MQOptions = &config.MQSetting{
+		BlockTime: 10 * time.Millisecond, BatchSize: 100, Workers: 4, ClaimMinIdle: time.Minute, ClaimCount: 10,
+		RetryBaseDelay: 20 * time.Millisecond, RetryMaxDelay: 20 * time.Millisecond,
+	}
+	t.Cleanup(func() { config.MQOptions = old })
+
+	svc := &fakeVoucherService{}
+	c := NewOrderConsumer("consumer-1", repository.NewRedisMessageQueue(rdb), svc)
+	require.NoError(t, c.mq.CreateGroup(context.Background()))
+	return m, rdb, c, svc
+}
+
+// crashAfterRead 模拟消费者读取消息后、确认前崩溃
+func crashAfterRead(t testing.TB, rdb *redis.Client, values map[string]any) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: repository.OrderStreamKey, Values: values}).Err())
+	msgs, err := repository.NewRedisMessageQueue(rdb).ReadPendingMessages(ctx, "crashed-consumer", 1, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+}
+
+func pendingCount(t *testing.T, rdb *redis.Client) int64 {
+	t.Helper()
+	pending, err := rdb.XPending(context.Background(), repository.OrderStreamKey, repository.OrderGroup).Result()
+	require.NoError(t, err)
+	return pending.Count
+}
+
+func TestReclaimMessagesFromCrashedConsumer(t *testing.T) {
+	m, rdb, c, svc := setupConsumer(t)
+	ctx := context.Background()
+	crashAfterRead(t, rdb, map[string]any{"userID": "7", "voucherID": "1", "orderID": "100"})
+
+	// 闲置时间未达到阈值, 不认领
+	assert.Equal(t, 0, c.reclaimOnce(ctx))
+	assert.Equal(t, int64(1), pendingCount(t, rdb))
+
+	m.SetTime(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 1, c.reclaimOnce(ctx))
+	assert.Equal(t, []int64{100}, svc.created)
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+
+	// 已确认的消息不会再次被认领
+	m.SetTime(time.Now().Add(4 * time.Minute))
+	assert.Equal(t, 0, c.reclaimOnce(ctx))
+}
+
+func TestReclaimedMessageFollowsRetryRules(t *testing.T) {
+	m, rdb, c, svc := setupConsumer(t)
+	ctx := context.Background()
+	svc.err = errors.New("db unavailable")
+	crashAfterRead(t, rdb, map[string]any{"userID": "7", "voucherID": "1", "orderID": "100"})
+
+	m.SetTime(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 1, c.reclaimOnce(ctx))
+	assert.Empty(t, svc.created)
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+
+	// 可重试的失败不会立即重新入队, 而是带上重试次数放入延时重试集合
+	msgs, err := rdb.XRange(ctx, repository.OrderStreamKey, "-", "+").Result()
+	require.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	retries, err := rdb.ZRangeWithScores(ctx, repository.RetryDelayKey, 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, retries, 1)
+	assert.Contains(t, retries[0].Member, `"retry_count":"1"`)
+	assert.Greater(t, int64(retries[0].Score), time.Now().UnixMilli())
+}
+
+func TestReclaimedMessageMovesToDLQAfterMaxRetries(t *testing.T) {
+	m, rdb, c, svc := setupConsumer(t)
+	ctx := context.Background()
+	svc.err = errors.New("db unavailable")
+	crashAfterRead(t, rdb, map[string]any{"userID": "7", "voucherID": "1", "orderID": "100", "retry_count": "3"})
+
+	m.SetTime(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 1, c.reclaimOnce(ctx))
+	assert.Empty(t, svc.created)
+	assert.Equal(t, []int64{100}, svc.failed)
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+
+	dead, err := rdb.XRange(ctx, repository.DeadLetterStreamKey, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "100", dead[0].Values["orderID"])
+	assert.Contains(t, dead[0].Values["error"], "db unavailable")
+}
+
+func TestPermanentErrorMovesToDLQWithoutRetry(t *testing.T) {
+	_, rdb, c, svc := setupConsumer(t)
+	ctx := context.Background()
+	svc.err = fmt.Errorf("voucher 1: %w", repository.ErrStockInsufficient)
+	addOrderMessages(t, rdb, 1, 1)
+
+	msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName, 1, time.Millisecond)
+	require.NoError(t, err)
+	c.processMessage(ctx, msgs[0])
+
+	assert.Equal(t, []int64{1}, svc.failed)
+	assert.Equal(t, int64(0), rdb.ZCard(ctx, repository.RetryDelayKey).Val())
+	assert.Equal(t, int64(1), rdb.XLen(ctx, repository.DeadLetterStreamKey).Val())
+}
+
+// 格式错误或版本不支持的订单事件不重试, 直接移入死信队列
+func TestMalformedOrderEventMovesToDLQ(t *testing.T) {
+	for name, values := range map[string]map[string]any{
+		"invalid json":        {event.FieldOrder: "{"},
+		"unsupported version": {event.FieldOrder: `{"v":99,"order_id":"1","user_id":"1","voucher_id":"1"}`},
+		"missing field":       {event.FieldOrder: `{"v":1,"order_id":"1","user_id":"1"}`},
+		"legacy":              {"userID": "7", "voucherID": "x", "orderID": "100"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, rdb, c, svc := setupConsumer(t)
+			ctx := context.Background()
+			require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: repository.OrderStreamKey, Values: values}).Err())
+
+			msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName, 1, time.Millisecond)
+			require.NoError(t, err)
+			c.processMessage(ctx, msgs[0])
+
+			assert.Empty(t, svc.created)
+			assert.Equal(t, int64(0), rdb.ZCard(ctx, repository.RetryDelayKey).Val())
+			assert.Equal(t, int64(1), rdb.XLen(ctx, repository.DeadLetterStreamKey).Val())
+			assert.Equal(t, int64(0), pendingCount(t, rdb))
+		})
+	}
+}
+
+func TestParseOrderMsgSnapshot(t *testing.T) {
+	values, err := event.EncodeOrder(&event.Order{OrderID: 100, UserID: 7, VoucherID: 1,
+		Snapshot: &event.OrderSnapshot{ShopID: 2, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}})
+	require.NoError(t, err)
+	order, err := parseOrderMsg(repository.Message{ID: "1-0", Values: map[string]string{event.FieldOrder: values[event.FieldOrder].(string)}})
+	require.NoError(t, err)
+	assert.Equal(t, &model.TbVoucherOrder{ID: 100, UserID: 7, VoucherID: 1, ShopID: 2, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}, order)
+
+	// 没有快照的旧消息留给 VoucherService 补全
+	order, err = parseOrderMsg(repository.Message{ID: "2-0", Values: map[string]string{"userID": "7", "voucherID": "1", "orderID": "101"}})
+	require.NoError(t, err)
+	assert.Equal(t, &model.TbVoucherOrder{ID: 101, UserID: 7, VoucherID: 1}, order)
+}
+
+func TestPromoteDueRetries(t *testing.T) {
+	_, rdb, c, svc := setupConsumer(t)
+	ctx := context.Background()
+	svc.err = errors.New("db unavailable")
+	addOrderMessages(t, rdb, 1, 1)
+
+	msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName, 1, time.Millisecond)
+	require.NoError(t, err)
+	c.processMessage(ctx, msgs[0])
+
+	// 未到期不投递
+	assert.Equal(t, int64(0), c.promoteOnce(ctx))
+	time.Sleep(config.MQOptions.RetryMaxDelay)
+	assert.Equal(t, int64(1), c.promoteOnce(ctx))
+	assert.Equal(t, int64(0), c.promoteOnce(ctx))
+
+	svc.err = nil
+	msgs, err = c.mq.ReadPendingMessages(ctx, c.consumerName, 1, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "1", msgs[0].Values["retry_count"])
+	order, err := parseOrderMsg(msgs[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), order.ID)
+	c.processMessage(ctx, msgs[0])
+	assert.Equal(t, []int64{1}, svc.created)
+}
+
+func TestRetryDelayBackoff(t *testing.T) {
+	old := config.MQOptions
+	config.MQOptions = &config.MQSetting{RetryBaseDelay: time.Second, RetryMaxDelay: 10 * time.Second}
+	t.Cleanup(func() { config.MQOptions = old })
+
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		d := retryDelay(n)
+		assert.GreaterOrEqual(t, d, want/2, "retry %d", n)
+		assert.LessOrEqual(t, d, want, "retry %d", n)
+	}
+}
+
+func addOrderMessages(t testing.TB, rdb *redis.Client, n int, users int) {
+	t.Helper()
+	ctx := context.Background()
+	pipe := rdb.Pipeline()
+	for i := 0; i < n; i++ {
+		values, err := event.EncodeOrder(&event.Order{OrderID: int64(i + 1), UserID: uint64(i%users + 1), VoucherID: 1})
+		require.NoError(t, err)
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: repository.OrderStreamKey, Values: values})
+	}
+	_, err := pipe.Exec(ctx)
+	require.NoError(t, err)
+}
+
+func TestProcessBatchKeepsPerUserOrder(t *testing.T) {
+	_, rdb, c, svc := setupConsumer(t)
+	ctx := context.Background()
+	addOrderMessages(t, rdb, 20, 3)
+
+	msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName, 100, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, msgs, 20)
+
+	var mu sync.Mutex
+	seen := make(map[string][]string)
+	c.voucherService = &orderRecorder{fakeVoucherService: svc, record: func(userID, orderID string) {
+		mu.Lock()
+		seen[userID] = append(seen[userID], orderID)
+		mu.Unlock()
+	}}
+	c.processBatch(ctx, msgs)
+
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+	for _, orderIDs := range seen {
+		for i := 1; i < len(orderIDs); i++ {
+			prev, _ := strconv.Atoi(orderIDs[i-1])
+			cur, _ := strconv.Atoi(orderIDs[i])
+			assert.Less(t, prev, cur)
+		}
+	}
+	assert.Equal(t, 20, svc.createdCount())
+}
+
+// orderRecorder 记录每个用户订单的处理顺序, 批量写入总是失败, 以验证逐条处理时的顺序
+type orderRecorder struct {
+	*fakeVoucherService
+	record func(userID, orderID string)
+}
+
+func (r *orderRecorder) CreateVoucherOrdersDB(ctx context.Context, orders []*model.TbVoucherOrder) error {
+	if len(orders) > 1 {
+		return errors.New("batch insert failed")
+	}
+	for _, order := range orders {
+		r.record(strconv.FormatUint(order.UserID, 10), strconv.FormatInt(order.ID, 10))
+	}
+	return r.fakeVoucherService.CreateVoucherOrdersDB(ctx, orders)
+}
+
+func (r *orderRecorder) CreateVoucherOrderDB(ctx context.Context, order *model.TbVoucherOrder) error {
+	return r.CreateVoucherOrdersDB(ctx, []*model.TbVoucherOrder{order})
+}
+
+// BenchmarkConsumeOrders 对比逐条处理与批量并发处理的吞吐量, 每次数据库写入模拟 200µs 延迟
+func BenchmarkConsumeOrders(b *testing.B) {
+	cases := []struct {
+		name      string
+		batchSize int64
+		workers   int
+	}{
+		{"sequential", 1, 1},
+		{"batch100-workers1", 100, 1},
+		{"batch100-workers8", 100, 8},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			_, rdb, c, svc := setupConsumer(b)
+			config.MQOptions.BatchSize = tc.batchSize
+			config.MQOptions.Workers = tc.workers
+			svc.latency = 200 * time.Microsecond
+			addOrderMessages(b, rdb, b.N, 1000)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for svc.createdCount() < b.N {
+				msgs, err := c.mq.ReadPendingMessages(ctx, c.consumerName, tc.batchSize, time.Millisecond)
+				require.NoError(b, err)
+				c.processBatch(ctx, msgs)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "orders/s")
+		})
+	}
+}
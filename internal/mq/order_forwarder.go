@@ -0,0 +1,88 @@
+package mq
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// OrderForwarder 秒杀脚本在扣减 Redis 库存的同时把订单消息写入 Redis 的 stream:orders,
+// 配置了其他消息中间件时, 由它把消息转发到中间件的订单流, 转发成功后才确认, 保证至少投递一次
+type OrderForwarder struct {
+	name string
+	src  repository.MessageQueue // 为空表示使用 Redis Streams, 不需要转发
+	dst  repository.MessageQueue
+}
+
+func NewOrderForwarder(rdb *redis.Client, broker repository.MessageQueue) *OrderForwarder {
+	f := &OrderForwarder{
+		name: consumerNamePrefix() + "-forwarder",
+		dst:  broker,
+	}
+	if opts := config.MQOptions; opts != nil && opts.Broker != "" && opts.Broker != repository.BrokerRedis {
+		f.src = repository.NewRedisMessageQueue(rdb)
+	}
+	return f
+}
+
+func (f *OrderForwarder) Start(ctx context.Context) {
+	if f.src == nil {
+		return
+	}
+	if err := f.src.CreateGroup(ctx); err != nil {
+		slog.Error("failed to create group for order forwarder", "err", err)
+		return
+	}
+	slog.Info("Order forwarder started", "consumer", f.name, "broker", config.MQOptions.Broker)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Order forwarder stopped", "consumer", f.name)
+			return
+		default:
+			if _, err := f.forwardOnce(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("failed to forward order messages", "err", err)
+				time.Sleep(checkIdleInterval)
+			}
+		}
+	}
+}
+
+// forwardOnce 优先认领转发失败或转发进程崩溃遗留的消息, 没有时阻塞读取新消息, 返回转发的数量
+func (f *OrderForwarder) forwardOnce(ctx context.Context) (int, error) {
+	batchSize, blockTime, _ := consumeOptions()
+	minIdle := defaultClaimMinIdle
+	if opts := config.MQOptions; opts != nil && opts.ClaimMinIdle > 0 {
+		minIdle = opts.ClaimMinIdle
+	}
+
+	msgs, err := f.src.ClaimMessage(ctx, f.name, minIdle, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		if msgs, err = f.src.ReadPendingMessages(ctx, f.name, batchSize, blockTime); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, msg := range msgs {
+		values := make(map[string]any, len(msg.Values))
+		for k, v := range msg.Values {
+			values[k] = v
+		}
+		// 转发失败的消息不确认, 闲置超过 ClaimMinIdle 后重新认领
+		if _, err = f.dst.AddOrderToStream(ctx, values); err != nil {
+			return i, err
+		}
+		if err = f.src.Ack(ctx, repository.OrderStreamKey, repository.OrderGroup, msg.ID); err != nil {
+			slog.Error("failed to ACK forwarded message", "err", err, "messageID", msg.ID)
+		}
+	}
+	return len(msgs), nil
+}
@@ -0,0 +1,69 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingQueue 模拟中间件不可用
+type failingQueue struct {
+	repository.MessageQueue
+}
+
+func (failingQueue) AddOrderToStream(context.Context, map[string]any) (string, error) {
+	return "", errors.New("broker unavailable")
+}
+
+func TestOrderForwarder(t *testing.T) {
+	m, rdb, _, _ := setupConsumer(t)
+	config.MQOptions.Broker = repository.BrokerMemory
+	ctx := context.Background()
+
+	broker := repository.NewMemoryMessageQueue()
+	require.NoError(t, broker.CreateGroup(ctx))
+	f := NewOrderForwarder(rdb, broker)
+	require.NoError(t, f.src.CreateGroup(ctx))
+	addOrderMessages(t, rdb, 3, 1)
+
+	n, err := f.forwardOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+
+	msgs, err := broker.ReadPendingMessages(ctx, "c1", 10, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	order, err := parseOrderMsg(msgs[0])
+	require.NoError(t, err)
+	assert.Equal(t, &model.TbVoucherOrder{ID: 1, UserID: 1, VoucherID: 1}, order)
+
+	// 转发失败的消息保留在待确认列表中, 闲置后重新认领转发
+	require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: repository.OrderStreamKey, Values: map[string]any{"userID": "1", "voucherID": "1", "orderID": "4"}}).Err())
+	f.dst = failingQueue{}
+	_, err = f.forwardOnce(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), pendingCount(t, rdb))
+
+	f.dst = broker
+	m.SetTime(time.Now().Add(2 * time.Minute))
+	n, err = f.forwardOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int64(0), pendingCount(t, rdb))
+}
+
+func TestOrderForwarderDisabledForRedis(t *testing.T) {
+	_, rdb, c, _ := setupConsumer(t)
+	f := NewOrderForwarder(rdb, c.mq)
+	assert.Nil(t, f.src)
+	f.Start(context.Background()) // 直接返回
+}
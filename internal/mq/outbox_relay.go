@@ -0,0 +1,86 @@
+package mq
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/repository"
+)
+
+const (
+	outboxRelayInterval = time.Second
+	outboxRelayBatch    = 100
+	// 已发送的事件保留 outboxRetention 后分批删除
+	outboxRetention     = 7 * 24 * time.Hour
+	outboxPurgeInterval = time.Hour
+	outboxPurgeBatch    = 1000
+)
+
+// OutboxRelay 将发件箱中待发送的事件转发到事件流, 发送成功后才标记为已发送, 保证至少投递一次
+type OutboxRelay struct {
+	outbox repository.OutboxRepo
+	mq     repository.MessageQueue
+}
+
+func NewOutboxRelay(outbox repository.OutboxRepo, mq repository.MessageQueue) *OutboxRelay {
+	return &OutboxRelay{outbox: outbox, mq: mq}
+}
+
+func (r *OutboxRelay) Start(ctx context.Context) {
+	slog.Info("Outbox relay started")
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(outboxPurgeInterval)
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			// 一批发满说明还有积压, 继续发送直到清空
+			for r.relayOnce(context.WithoutCancel(ctx)) == outboxRelayBatch && ctx.Err() == nil {
+			}
+		case <-purgeTicker.C:
+			r.purge(ctx, time.Now().Add(-outboxRetention))
+		}
+	}
+}
+
+func (r *OutboxRelay) relayOnce(ctx context.Context) int {
+	n, err := r.outbox.RelayPending(ctx, outboxRelayBatch, func(event *model.TbOutbox) error {
+		_, err := r.mq.AddToStream(ctx, repository.EventStreamKey, map[string]any{
+			"event_id": strconv.FormatUint(event.ID, 10),
+			"topic":    event.Topic,
+			"payload":  event.Payload,
+		})
+		return err
+	})
+	if err != nil {
+		slog.Error("failed to relay outbox events", "err", err, "sent", n)
+	}
+	return n
+}
+
+// purge 分批删除过期的已发送事件, 避免一次删除过多行长时间持有锁
+func (r *OutboxRelay) purge(ctx context.Context, sentBefore time.Time) {
+	var total int64
+	for ctx.Err() == nil {
+		n, err := r.outbox.PurgeSent(ctx, sentBefore, outboxPurgeBatch)
+		if err != nil {
+			slog.Error("failed to purge sent outbox events", "err", err, "purged", total)
+			return
+		}
+		total += n
+		if n < outboxPurgeBatch {
+			break
+		}
+	}
+	if total > 0 {
+		slog.Info("purged sent outbox events", "count", total)
+	}
+}
@@ -0,0 +1,98 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+)
+
+// 未配置 mq 时的默认重试参数
+const (
+	defaultMaxRetries        = 3
+	defaultRetryBaseDelay    = time.Second
+	defaultRetryMaxDelay     = time.Minute
+	defaultRetryPollInterval = time.Second
+	retryPromoteCount        = 100
+)
+
+var errInvalidOrderMsg = errors.New("invalid order message")
+
+// permanentErrors 重试也无法成功的错误, 直接移入死信队列
+var permanentErrors = []error{
+	errInvalidOrderMsg,
+	repository.ErrStockInsufficient,
+	repository.ErrUserOrderExists,
+}
+
+// isRetryable 判断订单消息处理失败后是否值得重试, 未知错误(如数据库暂时不可用)默认重试
+func isRetryable(err error) bool {
+	for _, target := range permanentErrors {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
+
+func retryOptions() (maxRetries int, baseDelay, maxDelay, pollInterval time.Duration) {
+	maxRetries, baseDelay, maxDelay, pollInterval = defaultMaxRetries, defaultRetryBaseDelay, defaultRetryMaxDelay, defaultRetryPollInterval
+	if opts := config.MQOptions; opts != nil {
+		if opts.MaxRetries > 0 {
+			maxRetries = opts.MaxRetries
+		}
+		if opts.RetryBaseDelay > 0 {
+			baseDelay = opts.RetryBaseDelay
+		}
+		if opts.RetryMaxDelay > 0 {
+			maxDelay = opts.RetryMaxDelay
+		}
+		if opts.RetryPollInterval > 0 {
+			pollInterval = opts.RetryPollInterval
+		}
+	}
+	return
+}
+
+// retryDelay 第 n 次重试的等待时间, 指数退避并在 [d/2, d] 内随机抖动, 避免大量失败消息同时重试
+func retryDelay(n int) time.Duration {
+	_, baseDelay, maxDelay, _ := retryOptions()
+	d := maxDelay
+	if n < 32 && baseDelay<<(n-1) < maxDelay {
+		d = baseDelay << (n - 1)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// PromoteRetries 定期将到期的重试消息写回订单流
+func (c *OrderConsumer) PromoteRetries(ctx context.Context) {
+	_, _, _, interval := retryOptions()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Order retry promoter stopped", "consumer", c.consumerName)
+			return
+		case <-ticker.C:
+			c.promoteOnce(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+func (c *OrderConsumer) promoteOnce(ctx context.Context) int64 {
+	n, err := c.mq.PromoteDueRetries(ctx, time.Now(), retryPromoteCount)
+	if err != nil {
+		slog.Error("failed to promote due retries", "err", err)
+		return 0
+	}
+	if n > 0 {
+		slog.Info("Promoted due retries", "count", n)
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package mq
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/hmmm42/city-picks/internal/service"
+)
+
+const (
+	unpaidOrderPollInterval = time.Second
+	unpaidOrderBatchSize    = 100
+	// 取出订单后的租约时间, 取消失败或实例崩溃时租约到期后重新取出
+	unpaidOrderLease = 30 * time.Second
+	// 兜底扫描 MySQL 的间隔, 处理写入延时队列失败或队列数据丢失的订单
+	unpaidOrderScanInterval = time.Minute
+)
+
+// UnpaidOrderCanceller 轮询延时队列, 取消超时未支付的订单; 并定期扫描 MySQL 兜底
+type UnpaidOrderCanceller struct {
+	voucherOrderRepo repository.VoucherOrderRepo
+	orderService     service.OrderService
+}
+
+func NewUnpaidOrderCanceller(repo repository.VoucherOrderRepo, svc service.OrderService) *UnpaidOrderCanceller {
+	return &UnpaidOrderCanceller{
+		voucherOrderRepo: repo,
+		orderService:     svc,
+	}
+}
+
+func (c *UnpaidOrderCanceller) Start(ctx context.Context) {
+	slog.Info("Unpaid order canceller started", "interval", unpaidOrderPollInterval)
+
+	ticker := time.NewTicker(unpaidOrderPollInterval)
+	defer ticker.Stop()
+	scanTicker := time.NewTicker(unpaidOrderScanInterval)
+	defer scanTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Unpaid order canceller stopped")
+			return
+		case <-ticker.C:
+			c.cancelExpired(ctx)
+		case <-scanTicker.C:
+			c.scanExpired(ctx)
+		}
+	}
+}
+
+func (c *UnpaidOrderCanceller) cancelExpired(ctx context.Context) {
+	for {
+		ids, err := c.voucherOrderRepo.ClaimExpiredUnpaidOrders(ctx, time.Now(), unpaidOrderLease, unpaidOrderBatchSize)
+		if err != nil {
+			slog.Error("failed to claim expired unpaid orders", "err", err)
+			return
+		}
+
+		for _, id := range ids {
+			c.cancel(ctx, id)
+		}
+		if len(ids) < unpaidOrderBatchSize {
+			return
+		}
+	}
+}
+
+// scanExpired 按 ID 分批扫描 MySQL 中超过支付期限仍未支付的订单
+func (c *UnpaidOrderCanceller) scanExpired(ctx context.Context) {
+	opts := config.OrderOptions
+	if opts == nil || opts.UnpaidTimeout <= 0 {
+		return
+	}
+	createdBefore := time.Now().Add(-opts.UnpaidTimeout)
+	var afterID int64
+	for {
+		ids, err := c.voucherOrderRepo.ListExpiredUnpaidOrderIDs(ctx, createdBefore, afterID, unpaidOrderBatchSize)
+		if err != nil {
+			slog.Error("failed to list expired unpaid orders", "err", err)
+			return
+		}
+		if len(ids) > 0 {
+			slog.Warn("found expired unpaid orders in MySQL", "count", len(ids))
+		}
+
+		for _, id := range ids {
+			c.cancel(ctx, id)
+		}
+		if len(ids) < unpaidOrderBatchSize {
+			return
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// cancel 取消成功后才从延时队列删除, 失败时等租约到期或下次扫描重试
+func (c *UnpaidOrderCanceller) cancel(ctx context.Context, orderID int64) {
+	if err := c.orderService.CancelExpired(ctx, orderID); err != nil {
+		slog.Error("failed to cancel unpaid order, retry later", "err", err, "orderID", orderID)
+		return
+	}
+	if err := c.voucherOrderRepo.RemoveUnpaidOrder(ctx, orderID); err != nil {
+		slog.Error("failed to remove unpaid order from delay queue", "err", err, "orderID", orderID)
+	}
+}
@@ -0,0 +1,94 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/hmmm42/city-picks/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUnpaidOrderRepo 只实现超时任务用到的方法
+type fakeUnpaidOrderRepo struct {
+	repository.VoucherOrderRepo
+
+	due           []int64
+	unpaid        []int64
+	createdBefore time.Time
+	removed       []int64
+}
+
+func (r *fakeUnpaidOrderRepo) ClaimExpiredUnpaidOrders(_ context.Context, _ time.Time, _ time.Duration, _ int) ([]int64, error) {
+	ids := r.due
+	r.due = nil
+	return ids, nil
+}
+
+func (r *fakeUnpaidOrderRepo) ListExpiredUnpaidOrderIDs(_ context.Context, createdBefore time.Time, afterID int64, limit int) ([]int64, error) {
+	r.createdBefore = createdBefore
+	var ids []int64
+	for _, id := range r.unpaid {
+		if id > afterID && len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeUnpaidOrderRepo) RemoveUnpaidOrder(_ context.Context, orderID int64) error {
+	r.removed = append(r.removed, orderID)
+	return nil
+}
+
+type fakeOrderService struct {
+	service.OrderService
+
+	failed    map[int64]bool
+	cancelled []int64
+}
+
+func (s *fakeOrderService) CancelExpired(_ context.Context, orderID int64) error {
+	if s.failed[orderID] {
+		return errors.New("db down")
+	}
+	s.cancelled = append(s.cancelled, orderID)
+	return nil
+}
+
+// 取消失败的订单保留在延时队列中, 等租约到期后重试
+func TestCancelExpiredKeepsFailedOrders(t *testing.T) {
+	repo := &fakeUnpaidOrderRepo{due: []int64{1, 2, 3}}
+	svc := &fakeOrderService{failed: map[int64]bool{2: true}}
+	c := NewUnpaidOrderCanceller(repo, svc)
+
+	c.cancelExpired(context.Background())
+	assert.Equal(t, []int64{1, 3}, svc.cancelled)
+	assert.Equal(t, []int64{1, 3}, repo.removed)
+}
+
+func TestScanExpiredUnpaidOrders(t *testing.T) {
+	old := config.OrderOptions
+	t.Cleanup(func() { config.OrderOptions = old })
+	config.OrderOptions = &config.OrderSetting{UnpaidTimeout: 15 * time.Minute}
+
+	repo := &fakeUnpaidOrderRepo{}
+	for id := int64(1); id <= unpaidOrderBatchSize+20; id++ {
+		repo.unpaid = append(repo.unpaid, id)
+	}
+	svc := &fakeOrderService{}
+	c := NewUnpaidOrderCanceller(repo, svc)
+
+	c.scanExpired(context.Background())
+	assert.Equal(t, repo.unpaid, svc.cancelled)
+	assert.WithinDuration(t, time.Now().Add(-15*time.Minute), repo.createdBefore, time.Second)
+
+	// 未配置支付超时时不自动取消
+	config.OrderOptions = &config.OrderSetting{}
+	svc.cancelled = nil
+	c.scanExpired(context.Background())
+	assert.Empty(t, svc.cancelled)
+}
@@ -0,0 +1,47 @@
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+// 支付方式, 对应 tb_voucher_order.pay_type
+const (
+	PayTypeBalance uint8 = 1 // 余额支付
+	PayTypeAlipay  uint8 = 2 // 支付宝
+	PayTypeWechat  uint8 = 3 // 微信
+)
+
+var ErrUnsupportedPayType = errors.New("unsupported pay type")
+
+// PayRequest 支付请求, Amount 单位为分
+type PayRequest struct {
+	OrderID int64
+	UserID  uint64
+	Amount  uint64
+	PayType uint8
+}
+
+// PayResult 支付结果, TradeNo 为支付渠道返回的交易号
+type PayResult struct {
+	TradeNo string
+}
+
+// RefundRequest 退款请求, Amount 单位为分; RefundNo 为退款单号, 同一笔退款重试时不变, 渠道据此去重
+type RefundRequest struct {
+	RefundNo string
+	OrderID  int64
+	UserID   uint64
+	Amount   uint64
+	PayType  uint8
+}
+
+// Gateway 支付渠道, 接入真实渠道时实现该接口并替换 wire 中的 Provider
+type Gateway interface {
+	Pay(ctx context.Context, req *PayRequest) (*PayResult, error)
+	Refund(ctx context.Context, req *RefundRequest) error
+}
+
+func ValidPayType(payType uint8) bool {
+	return payType >= PayTypeBalance && payType <= PayTypeWechat
+}
@@ -0,0 +1,31 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// localGateway 本地模拟支付渠道, 所有支付和退款都直接成功, 仅用于开发和测试
+type localGateway struct {
+	logger *slog.Logger
+}
+
+func (g *localGateway) Pay(ctx context.Context, req *PayRequest) (*PayResult, error) {
+	if !ValidPayType(req.PayType) {
+		return nil, ErrUnsupportedPayType
+	}
+	g.logger.Info("local gateway pay", "order_id", req.OrderID, "amount", req.Amount, "pay_type", req.PayType)
+	return &PayResult{TradeNo: fmt.Sprintf("local-%d", req.OrderID)}, nil
+}
+
+func (g *localGateway) Refund(ctx context.Context, req *RefundRequest) error {
+	g.logger.Info("local gateway refund", "refund_no", req.RefundNo, "order_id", req.OrderID, "amount", req.Amount, "pay_type", req.PayType)
+	return nil
+}
+
+func NewLocalGateway(logger *slog.Logger) Gateway {
+	return &localGateway{
+		logger: logger,
+	}
+}
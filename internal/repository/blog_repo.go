@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/dal/query"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// toggleLikeScript 已点赞则取消, 否则以点赞时间为分数加入点赞集合; 返回 1 表示点赞, 0 表示取消
+var toggleLikeScript = redis.NewScript(`
+if(redis.call('zscore', KEYS[1], ARGV[1])) then
+    redis.call('zrem', KEYS[1], ARGV[1])
+    return 0
+end
+redis.call('zadd', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+
+// 笔记状态, 对应 tb_blog.status
+const (
+	BlogStatusNormal  uint8 = 0
+	BlogStatusPending uint8 = 1 // 待审核
+	BlogStatusHidden  uint8 = 2 // 禁止查看
+)
+
+type BlogRepo interface {
+	CreateBlog(ctx context.Context, blog *model.TbBlog) error
+	GetBlogByID(ctx context.Context, id uint64) (*model.TbBlog, error)
+	DeleteBlog(ctx context.Context, id, userID uint64) (bool, []uint64, error)
+	ListHotBlogs(ctx context.Context, offset, limit int) ([]*model.TbBlog, error)
+	ListBlogsByStatus(ctx context.Context, status uint8, offset, limit int) ([]*model.TbBlog, error)
+	UpdateBlogStatus(ctx context.Context, id uint64, from, to uint8) (bool, error)
+	IncrBlogLiked(ctx context.Context, id uint64, delta int) error
+	ToggleBlogLikeCache(ctx context.Context, id, userID uint64, at time.Time) (bool, error)
+	GetBlogLikedCache(ctx context.Context, userID uint64, ids ...uint64) (map[uint64]bool, error)
+	ListBlogLikersCache(ctx context.Context, id uint64, n int64) ([]uint64, error)
+	DeleteBlogLikeCache(ctx context.Context, id uint64) error
+}
+
+type blogRepo struct {
+	q   *query.Query
+	rdb *redis.Client
+}
+
+func (r *blogRepo) CreateBlog(ctx context.Context, blog *model.TbBlog) error {
+	return r.q.TbBlog.WithContext(ctx).Create(blog)
+}
+
+func (r *blogRepo) GetBlogByID(ctx context.Context, id uint64) (*model.TbBlog, error) {
+	b := r.q.TbBlog
+	return b.WithContext(ctx).Where(b.ID.Eq(id)).First()
+}
+
+// DeleteBlog 只删除 userID 发布的笔记, 并在同一事务中删除笔记下的评论和举报记录, 返回被删除的评论 ID;
+// 笔记不存在或不属于该用户时返回 false
+func (r *blogRepo) DeleteBlog(ctx context.Context, id, userID uint64) (bool, []uint64, error) {
+	deleted := false
+	var commentIDs []uint64
+	err := r.q.Transaction(func(tx *query.Query) error {
+		b := tx.TbBlog
+		info, err := b.WithContext(ctx).Where(b.ID.Eq(id), b.UserID.Eq(userID)).Delete()
+		if err != nil || info.RowsAffected == 0 {
+			return err
+		}
+		deleted = true
+
+		c := tx.TbBlogComment
+		if err = c.WithContext(ctx).Where(c.BlogID.Eq(id)).Pluck(c.ID, &commentIDs); err != nil || len(commentIDs) == 0 {
+			return err
+		}
+		if _, err = c.WithContext(ctx).Where(c.ID.In(commentIDs...)).Delete(); err != nil {
+			return err
+		}
+		cr := tx.TbBlogCommentReport
+		_, err = cr.WithContext(ctx).Where(cr.CommentID.In(commentIDs...)).Delete()
+		return err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return deleted, commentIDs, nil
+}
+
+// ListHotBlogs 按点赞数从高到低分页查询正常状态的笔记, 点赞数相同时新发布的在前
+func (r *blogRepo) ListHotBlogs(ctx context.Context, offset, limit int) ([]*model.TbBlog, error) {
+	b := r.q.TbBlog
+	return b.WithContext(ctx).
+		Where(b.Status.Eq(BlogStatusNormal)).
+		Order(b.Liked.Desc(), b.ID.Desc()).
+		Offset(offset).
+		Limit(limit).
+		Find()
+}
+
+// ListBlogsByStatus 按发布顺序分页查询指定状态的笔记
+func (r *blogRepo) ListBlogsByStatus(ctx context.Context, status uint8, offset, limit int) ([]*model.TbBlog, error) {
+	b := r.q.TbBlog
+	return b.WithContext(ctx).Where(b.Status.Eq(status)).Order(b.ID).Offset(offset).Limit(limit).Find()
+}
+
+// UpdateBlogStatus 笔记状态为 from 时修改为 to, 返回是否修改成功
+func (r *blogRepo) UpdateBlogStatus(ctx context.Context, id uint64, from, to uint8) (bool, error) {
+	b := r.q.TbBlog
+	info, err := b.WithContext(ctx).Where(b.ID.Eq(id), b.Status.Eq(from)).UpdateSimple(b.Status.Value(to))
+	if err != nil {
+		return false, err
+	}
+	return info.RowsAffected > 0, nil
+}
+
+// IncrBlogLiked 调整点赞数, 减少时不会低于 0
+func (r *blogRepo) IncrBlogLiked(ctx context.Context, id uint64, delta int) error {
+	b := r.q.TbBlog
+	do := b.WithContext(ctx).Where(b.ID.Eq(id))
+	if delta < 0 {
+		do = do.Where(b.Liked.Gte(uint64(-delta)))
+		_, err := do.UpdateSimple(b.Liked.Sub(uint64(-delta)))
+		return err
+	}
+	_, err := do.UpdateSimple(b.Liked.Add(uint64(delta)))
+	return err
+}
+
+// getBlogLikedKey 笔记的点赞集合, member 为用户 ID, score 为点赞时间(毫秒)
+func getBlogLikedKey(id uint64) string {
+	return fmt.Sprintf("blog:liked:%d", id)
+}
+
+func (r *blogRepo) ToggleBlogLikeCache(ctx context.Context, id, userID uint64, at time.Time) (bool, error) {
+	return toggleLikeCache(ctx, r.rdb, getBlogLikedKey(id), userID, at)
+}
+
+// GetBlogLikedCache 批量查询用户是否点赞了这些笔记
+func (r *blogRepo) GetBlogLikedCache(ctx context.Context, userID uint64, ids ...uint64) (map[uint64]bool, error) {
+	return getLikedCache(ctx, r.rdb, getBlogLikedKey, userID, ids)
+}
+
+// ListBlogLikersCache 按点赞时间顺序返回前 n 个点赞的用户
+func (r *blogRepo) ListBlogLikersCache(ctx context.Context, id uint64, n int64) ([]uint64, error) {
+	members, err := r.rdb.ZRange(ctx, getBlogLikedKey(id), 0, n-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint64, 0, len(members))
+	for _, m := range members {
+		userID, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid liker %q of blog %d: %w", m, id, err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+func (r *blogRepo) DeleteBlogLikeCache(ctx context.Context, id uint64) error {
+	return r.rdb.Del(ctx, getBlogLikedKey(id)).Err()
+}
+
+func toggleLikeCache(ctx context.Context, rdb *redis.Client, key string, userID uint64, at time.Time) (bool, error) {
+	n, err := toggleLikeScript.Run(ctx, rdb, []string{key}, userID, at.UnixMilli()).Int64()
+	return n == 1, err
+}
+
+// getLikedCache 查询用户是否在各 ID 对应的点赞集合中
+func getLikedCache(ctx context.Context, rdb *redis.Client, keyOf func(uint64) string, userID uint64, ids []uint64) (map[uint64]bool, error) {
+	member := strconv.FormatUint(userID, 10)
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.ZScore(ctx, keyOf(id), member)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	liked := make(map[uint64]bool, len(ids))
+	for i, id := range ids {
+		liked[id] = cmds[i].Err() == nil
+	}
+	return liked, nil
+}
+
+func NewBlogRepo(db *gorm.DB, rdb *redis.Client) BlogRepo {
+	return &blogRepo{q: query.Use(db), rdb: rdb}
+}
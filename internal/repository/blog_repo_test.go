@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListHotBlogs(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewBlogRepo(db, nil)
+
+	mock.ExpectQuery("SELECT \\* FROM `tb_blog` WHERE `tb_blog`.`status` = \\? ORDER BY `tb_blog`.`liked` DESC,`tb_blog`.`id` DESC LIMIT \\? OFFSET \\?").
+		WithArgs(0, 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "liked"}).AddRow(4, 2, 104).AddRow(5, 2, 1))
+
+	blogs, err := repo.ListHotBlogs(context.Background(), 20, 10)
+	require.NoError(t, err)
+	require.Len(t, blogs, 2)
+	assert.Equal(t, uint64(4), blogs[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 只能删除自己发布的笔记
+func TestDeleteBlogOfOtherUser(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewBlogRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `tb_blog` WHERE `tb_blog`.`id` = \\? AND `tb_blog`.`user_id` = \\?").
+		WithArgs(4, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ok, _, err := repo.DeleteBlog(context.Background(), 4, 3)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 删除笔记时在同一事务中删除其下的评论和举报记录
+func TestDeleteBlog(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewBlogRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `tb_blog` WHERE `tb_blog`.`id` = \\? AND `tb_blog`.`user_id` = \\?").
+		WithArgs(4, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT `id` FROM `tb_blog_comments` WHERE `tb_blog_comments`.`blog_id` = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+	mock.ExpectExec("DELETE FROM `tb_blog_comments` WHERE `tb_blog_comments`.`id` IN \\(\\?,\\?\\)").
+		WithArgs(10, 11).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM `tb_blog_comment_report` WHERE `tb_blog_comment_report`.`comment_id` IN \\(\\?,\\?\\)").
+		WithArgs(10, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ok, commentIDs, err := repo.DeleteBlog(context.Background(), 4, 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{10, 11}, commentIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestToggleBlogLikeCache(t *testing.T) {
+	db, _ := newMockDB(t)
+	m := miniredis.RunT(t)
+	repo := NewBlogRepo(db, redis.NewClient(&redis.Options{Addr: m.Addr()}))
+	ctx := context.Background()
+	now := time.Now()
+
+	// 用户 3 先点赞, 用户 1 后点赞, 点赞列表按时间排序
+	for i, userID := range []uint64{3, 1, 2} {
+		liked, err := repo.ToggleBlogLikeCache(ctx, 4, userID, now.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+		assert.True(t, liked)
+	}
+	liked, err := repo.ToggleBlogLikeCache(ctx, 4, 2, now)
+	require.NoError(t, err)
+	assert.False(t, liked)
+
+	likers, err := repo.ListBlogLikersCache(ctx, 4, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 1}, likers)
+
+	status, err := repo.GetBlogLikedCache(ctx, 1, 4, 5)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]bool{4: true, 5: false}, status)
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/dal/query"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyReported 已举报过该评论
+var ErrAlreadyReported = errors.New("already reported the comment")
+
+// 评论状态, 对应 tb_blog_comments.status
+const (
+	CommentStatusNormal   uint8 = 0
+	CommentStatusReported uint8 = 1 // 被举报, 等待审核
+	CommentStatusHidden   uint8 = 2 // 禁止查看
+)
+
+type CommentRepo interface {
+	CreateComment(ctx context.Context, comment *model.TbBlogComment) error
+	GetCommentByID(ctx context.Context, id uint64) (*model.TbBlogComment, error)
+	GetCommentsByIDs(ctx context.Context, ids []uint64) ([]*model.TbBlogComment, error)
+	ListTopComments(ctx context.Context, blogID, viewerID uint64, visibleOnly bool, offset, limit int) ([]*model.TbBlogComment, error)
+	ListReplies(ctx context.Context, parentID, viewerID uint64, visibleOnly bool, offset, limit int) ([]*model.TbBlogComment, error)
+	CountReplies(ctx context.Context, parentIDs []uint64, viewerID uint64, visibleOnly bool) (map[uint64]int64, error)
+	ListCommentsByStatus(ctx context.Context, status uint8, offset, limit int) ([]*model.TbBlogComment, error)
+	UpdateCommentStatus(ctx context.Context, id uint64, from, to uint8) (bool, error)
+	ReportComment(ctx context.Context, id, userID uint64) (bool, error)
+	ApproveComment(ctx context.Context, id uint64) (bool, error)
+	DeleteComment(ctx context.Context, comment *model.TbBlogComment) ([]uint64, error)
+	IncrCommentLiked(ctx context.Context, id uint64, delta int) error
+	ToggleCommentLikeCache(ctx context.Context, id, userID uint64, at time.Time) (bool, error)
+	GetCommentLikedCache(ctx context.Context, userID uint64, ids ...uint64) (map[uint64]bool, error)
+	DeleteCommentLikeCache(ctx context.Context, ids ...uint64) error
+}
+
+type commentRepo struct {
+	q   *query.Query
+	rdb *redis.Client
+}
+
+// CreateComment 在同一事务中写入评论并增加笔记的评论数, 笔记不存在或不是正常状态时返回 gorm.ErrRecordNotFound
+func (r *commentRepo) CreateComment(ctx context.Context, comment *model.TbBlogComment) error {
+	return r.q.Transaction(func(tx *query.Query) error {
+		b := tx.TbBlog
+		// 旧数据的评论数可能为 NULL
+		info, err := b.WithContext(ctx).Where(b.ID.Eq(comment.BlogID), b.Status.Eq(BlogStatusNormal)).
+			UpdateColumn(b.Comments, gorm.Expr("IFNULL(?, 0) + 1", b.Comments.RawExpr()))
+		if err != nil {
+			return err
+		}
+		if info.RowsAffected == 0 {
+			return fmt.Errorf("blog %d: %w", comment.BlogID, gorm.ErrRecordNotFound)
+		}
+		return tx.TbBlogComment.WithContext(ctx).Create(comment)
+	})
+}
+
+func (r *commentRepo) GetCommentByID(ctx context.Context, id uint64) (*model.TbBlogComment, error) {
+	c := r.q.TbBlogComment
+	return c.WithContext(ctx).Where(c.ID.Eq(id)).First()
+}
+
+func (r *commentRepo) GetCommentsByIDs(ctx context.Context, ids []uint64) ([]*model.TbBlogComment, error) {
+	c := r.q.TbBlogComment
+	return c.WithContext(ctx).Where(c.ID.In(ids...)).Find()
+}
+
+// visibleComments visibleOnly 为 true 时只查询正常状态的评论和 viewerID 自己发表的评论
+func (r *commentRepo) visibleComments(ctx context.Context, viewerID uint64, visibleOnly bool) query.ITbBlogCommentDo {
+	c := r.q.TbBlogComment
+	do := c.WithContext(ctx)
+	if visibleOnly {
+		do = do.Where(c.WithContext(ctx).Where(c.Status.Eq(CommentStatusNormal)).Or(c.UserID.Eq(viewerID)))
+	}
+	return do
+}
+
+// ListTopComments 分页查询笔记的一级评论, 新评论在前
+func (r *commentRepo) ListTopComments(ctx context.Context, blogID, viewerID uint64, visibleOnly bool, offset, limit int) ([]*model.TbBlogComment, error) {
+	c := r.q.TbBlogComment
+	return r.visibleComments(ctx, viewerID, visibleOnly).Where(c.BlogID.Eq(blogID), c.ParentID.Eq(0)).
+		Order(c.ID.Desc()).Offset(offset).Limit(limit).Find()
+}
+
+// ListReplies 分页查询一级评论下的回复, 按回复时间顺序
+func (r *commentRepo) ListReplies(ctx context.Context, parentID, viewerID uint64, visibleOnly bool, offset, limit int) ([]*model.TbBlogComment, error) {
+	c := r.q.TbBlogComment
+	return r.visibleComments(ctx, viewerID, visibleOnly).Where(c.ParentID.Eq(parentID)).
+		Order(c.ID).Offset(offset).Limit(limit).Find()
+}
+
+// CountReplies 统计各一级评论下的回复数
+func (r *commentRepo) CountReplies(ctx context.Context, parentIDs []uint64, viewerID uint64, visibleOnly bool) (map[uint64]int64, error) {
+	var rows []struct {
+		ParentID uint64
+		Count    int64
+	}
+	c := r.q.TbBlogComment
+	do := r.visibleComments(ctx, viewerID, visibleOnly).Where(c.ParentID.In(parentIDs...))
+	err := do.Select(c.ParentID, c.ID.Count().As("count")).Group(c.ParentID).Scan(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ParentID] = row.Count
+	}
+	return counts, nil
+}
+
+// ListCommentsByStatus 按发布顺序分页查询指定状态的评论
+func (r *commentRepo) ListCommentsByStatus(ctx context.Context, status uint8, offset, limit int) ([]*model.TbBlogComment, error) {
+	c := r.q.TbBlogComment
+	return c.WithContext(ctx).Where(c.Status.Eq(status)).Order(c.ID).Offset(offset).Limit(limit).Find()
+}
+
+// UpdateCommentStatus 评论状态为 from 时修改为 to, 返回是否修改成功
+func (r *commentRepo) UpdateCommentStatus(ctx context.Context, id uint64, from, to uint8) (bool, error) {
+	c := r.q.TbBlogComment
+	info, err := c.WithContext(ctx).Where(c.ID.Eq(id), c.Status.Eq(from)).UpdateSimple(c.Status.Value(to))
+	if err != nil {
+		return false, err
+	}
+	return info.RowsAffected > 0, nil
+}
+
+// ReportComment 在同一事务中记录举报, 并将未经管理员审核通过的正常评论改为被举报状态, 返回是否进入审核队列;
+// 同一用户重复举报时返回 ErrAlreadyReported
+func (r *commentRepo) ReportComment(ctx context.Context, id, userID uint64) (bool, error) {
+	queued := false
+	err := r.q.Transaction(func(tx *query.Query) error {
+		if err := tx.TbBlogCommentReport.WithContext(ctx).Create(&model.TbBlogCommentReport{CommentID: id, UserID: userID}); err != nil {
+			return err
+		}
+		c := tx.TbBlogComment
+		info, err := c.WithContext(ctx).Where(c.ID.Eq(id), c.Status.Eq(CommentStatusNormal), c.Reviewed.Eq(0)).
+			UpdateSimple(c.Status.Value(CommentStatusReported))
+		if err != nil {
+			return err
+		}
+		queued = info.RowsAffected > 0
+		return nil
+	})
+	if isDuplicateKeyErr(err) {
+		return false, ErrAlreadyReported
+	}
+	return queued, err
+}
+
+// ApproveComment 审核通过被举报的评论, 恢复为正常状态并记录已审核, 之后的举报不再进入审核队列
+func (r *commentRepo) ApproveComment(ctx context.Context, id uint64) (bool, error) {
+	c := r.q.TbBlogComment
+	info, err := c.WithContext(ctx).Where(c.ID.Eq(id), c.Status.Eq(CommentStatusReported)).
+		UpdateSimple(c.Status.Value(CommentStatusNormal), c.Reviewed.Value(1))
+	if err != nil {
+		return false, err
+	}
+	return info.RowsAffected > 0, nil
+}
+
+// DeleteComment 删除评论, 一级评论连同其下的回复一起删除, 并在同一事务中减少笔记的评论数和删除举报记录; 返回被删除的评论 ID
+func (r *commentRepo) DeleteComment(ctx context.Context, comment *model.TbBlogComment) ([]uint64, error) {
+	var ids []uint64
+	err := r.q.Transaction(func(tx *query.Query) error {
+		c := tx.TbBlogComment
+		do := c.WithContext(ctx).Where(c.ID.Eq(comment.ID))
+		if comment.ParentID == 0 {
+			do = do.Or(c.ParentID.Eq(comment.ID))
+		}
+		if err := do.Pluck(c.ID, &ids); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		// 并发删除同一评论时 Pluck 会读到相同的 ID, 按实际删除的行数减少评论数
+		info, err := c.WithContext(ctx).Where(c.ID.In(ids...)).Delete()
+		if err != nil {
+			return err
+		}
+		if info.RowsAffected == 0 {
+			ids = nil
+			return nil
+		}
+		cr := tx.TbBlogCommentReport
+		if _, err := cr.WithContext(ctx).Where(cr.CommentID.In(ids...)).Delete(); err != nil {
+			return err
+		}
+
+		b := tx.TbBlog
+		n := uint64(info.RowsAffected)
+		_, err = b.WithContext(ctx).Where(b.ID.Eq(comment.BlogID), b.Comments.Gte(n)).UpdateSimple(b.Comments.Sub(n))
+		return err
+	})
+	return ids, err
+}
+
+// IncrCommentLiked 调整评论点赞数, 减少时不会低于 0
+func (r *commentRepo) IncrCommentLiked(ctx context.Context, id uint64, delta int) error {
+	c := r.q.TbBlogComment
+	do := c.WithContext(ctx).Where(c.ID.Eq(id))
+	if delta < 0 {
+		_, err := do.Where(c.Liked.Gte(uint64(-delta))).UpdateSimple(c.Liked.Sub(uint64(-delta)))
+		return err
+	}
+	_, err := do.UpdateSimple(c.Liked.Add(uint64(delta)))
+	return err
+}
+
+// getCommentLikedKey 评论的点赞集合, 与笔记点赞相同以点赞时间为分数
+func getCommentLikedKey(id uint64) string {
+	return fmt.Sprintf("blog:comment:liked:%d", id)
+}
+
+func (r *commentRepo) ToggleCommentLikeCache(ctx context.Context, id, userID uint64, at time.Time) (bool, error) {
+	return toggleLikeCache(ctx, r.rdb, getCommentLikedKey(id), userID, at)
+}
+
+func (r *commentRepo) GetCommentLikedCache(ctx context.Context, userID uint64, ids ...uint64) (map[uint64]bool, error) {
+	return getLikedCache(ctx, r.rdb, getCommentLikedKey, userID, ids)
+}
+
+func (r *commentRepo) DeleteCommentLikeCache(ctx context.Context, ids ...uint64) error {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, getCommentLikedKey(id))
+	}
+	return r.rdb.Del(ctx, keys...).Err()
+}
+
+func NewCommentRepo(db *gorm.DB, rdb *redis.Client) CommentRepo {
+	return &commentRepo{q: query.Use(db), rdb: rdb}
+}
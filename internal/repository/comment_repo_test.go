@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCreateComment(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tb_blog` SET `comments`=IFNULL\\(`tb_blog`.`comments`, 0\\) \\+ 1 WHERE `tb_blog`.`id` = \\? AND `tb_blog`.`status` = \\?").
+		WithArgs(4, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `tb_blog_comments`").
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.ExpectCommit()
+
+	comment := &model.TbBlogComment{UserID: 1, BlogID: 4, Content: "好吃"}
+	require.NoError(t, repo.CreateComment(context.Background(), comment))
+	assert.Equal(t, uint64(10), comment.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateCommentBlogNotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tb_blog` SET `comments`=").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.CreateComment(context.Background(), &model.TbBlogComment{UserID: 1, BlogID: 4, Content: "好吃"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 删除一级评论时连同回复一起删除, 评论数按删除的条数减少
+func TestDeleteTopComment(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `tb_blog_comments` WHERE `tb_blog_comments`.`id` = \\? OR `tb_blog_comments`.`parent_id` = \\?").
+		WithArgs(10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11).AddRow(12))
+	mock.ExpectExec("DELETE FROM `tb_blog_comments` WHERE `tb_blog_comments`.`id` IN \\(\\?,\\?,\\?\\)").
+		WithArgs(10, 11, 12).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM `tb_blog_comment_report` WHERE `tb_blog_comment_report`.`comment_id` IN \\(\\?,\\?,\\?\\)").
+		WithArgs(10, 11, 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `tb_blog` SET `comments`=`tb_blog`.`comments`-\\? WHERE `tb_blog`.`id` = \\? AND `tb_blog`.`comments` >= \\?").
+		WithArgs(3, 4, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ids, err := repo.DeleteComment(context.Background(), &model.TbBlogComment{ID: 10, BlogID: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{10, 11, 12}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 并发删除时另一个事务已删除评论, Delete 没有影响任何行, 不再减少笔记的评论数
+func TestDeleteCommentAlreadyDeleted(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `tb_blog_comments` WHERE `tb_blog_comments`.`id` = \\? OR `tb_blog_comments`.`parent_id` = \\?").
+		WithArgs(10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+	mock.ExpectExec("DELETE FROM `tb_blog_comments` WHERE `tb_blog_comments`.`id` IN \\(\\?,\\?\\)").
+		WithArgs(10, 11).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ids, err := repo.DeleteComment(context.Background(), &model.TbBlogComment{ID: 10, BlogID: 4})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 只有处于 from 状态的评论才会被修改, 重复举报不会覆盖审核结果
+func TestUpdateCommentStatus(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tb_blog_comments` SET `status`=\\? WHERE `tb_blog_comments`.`id` = \\? AND `tb_blog_comments`.`status` = \\?").
+		WithArgs(CommentStatusReported, 10, CommentStatusNormal).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ok, err := repo.UpdateCommentStatus(context.Background(), 10, CommentStatusNormal, CommentStatusReported)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 其他用户只能看到正常状态的评论, 作者还能看到自己待审核的评论
+func TestListTopCommentsVisibleToAuthor(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectQuery("SELECT \\* FROM `tb_blog_comments` WHERE \\(`tb_blog_comments`.`status` = \\? OR `tb_blog_comments`.`user_id` = \\?\\) AND `tb_blog_comments`.`blog_id` = \\? AND `tb_blog_comments`.`parent_id` = \\? ORDER BY `tb_blog_comments`.`id` DESC LIMIT \\?").
+		WithArgs(CommentStatusNormal, 1, 4, 0, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "status"}).AddRow(11, 1, CommentStatusReported).AddRow(10, 2, CommentStatusNormal))
+
+	comments, err := repo.ListTopComments(context.Background(), 4, 1, true, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 举报记录与状态修改在同一事务中, 已审核通过的评论不会重新进入审核队列
+func TestReportComment(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_blog_comment_report`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `tb_blog_comments` SET `status`=\\? WHERE `tb_blog_comments`.`id` = \\? AND `tb_blog_comments`.`status` = \\? AND `tb_blog_comments`.`reviewed` = \\?").
+		WithArgs(CommentStatusReported, 10, CommentStatusNormal, 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	queued, err := repo.ReportComment(context.Background(), 10, 1)
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportCommentTwice(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_blog_comment_report`").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry '10-1' for key 'PRIMARY'"})
+	mock.ExpectRollback()
+
+	_, err := repo.ReportComment(context.Background(), 10, 1)
+	assert.ErrorIs(t, err, ErrAlreadyReported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApproveComment(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tb_blog_comments` SET `status`=\\?,`reviewed`=\\? WHERE `tb_blog_comments`.`id` = \\? AND `tb_blog_comments`.`status` = \\?").
+		WithArgs(CommentStatusNormal, 1, 10, CommentStatusReported).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ok, err := repo.ApproveComment(context.Background(), 10)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/dal/query"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// followsCacheTTL 关注集合按需从 MySQL 加载, 过期后重新加载以修正缓存与数据库的偏差
+	followsCacheTTL = 2 * time.Hour
+	// followsVersionTTL 需要长于一次从 MySQL 加载关注集合的时间
+	followsVersionTTL = time.Minute
+)
+
+// ErrAlreadyFollowing 已关注该用户
+var ErrAlreadyFollowing = errors.New("already following the user")
+
+// updateFollowScript 关注集合已缓存时才加入(ARGV[1] 为 sadd)或移除关注的用户, 避免缓存中只有部分关注;
+// 未缓存时增加版本号, 使正在从 MySQL 加载的旧数据不再写入
+var updateFollowScript = redis.NewScript(`
+if redis.call('exists', KEYS[1]) == 1 then
+    return redis.call(ARGV[1], KEYS[1], ARGV[2])
+end
+redis.call('incr', KEYS[2])
+redis.call('pexpire', KEYS[2], ARGV[3])
+return 0
+`)
+
+// loadFollowsScript 关注集合仍未缓存且加载期间版本号没有变化时, 写入临时 key 后 rename 为关注集合
+var loadFollowsScript = redis.NewScript(`
+if redis.call('exists', KEYS[1]) == 1 then
+    return 0
+end
+if (redis.call('get', KEYS[2]) or '0') ~= ARGV[1] then
+    return 0
+end
+redis.call('del', KEYS[3])
+-- 分批 sadd, 避免关注过多时 unpack 超出 Lua 栈的限制
+for i = 3, #ARGV, 1000 do
+    redis.call('sadd', KEYS[3], unpack(ARGV, i, math.min(i + 999, #ARGV)))
+end
+redis.call('pexpire', KEYS[3], ARGV[2])
+redis.call('rename', KEYS[3], KEYS[1])
+return 1
+`)
+
+type FollowRepo interface {
+	Follow(ctx context.Context, userID, followUserID uint64) error
+	Unfollow(ctx context.Context, userID, followUserID uint64) (bool, error)
+	IsFollowing(ctx context.Context, userID, followUserID uint64) (bool, error)
+	ListFollowerIDs(ctx context.Context, userID uint64, offset, limit int) ([]uint64, error)
+	ListFolloweeIDs(ctx context.Context, userID uint64, offset, limit int) ([]uint64, error)
+	AddFollowCache(ctx context.Context, userID, followUserID uint64) error
+	RemoveFollowCache(ctx context.Context, userID, followUserID uint64) error
+	LoadFollowsCache(ctx context.Context, userID uint64) error
+	DeleteFollowsCache(ctx context.Context, userID uint64) error
+	CommonFollowsCache(ctx context.Context, userID, otherID uint64) ([]uint64, error)
+}
+
+type followRepo struct {
+	q   *query.Query
+	rdb *redis.Client
+}
+
+// Follow 在同一事务中写入关注关系并增加双方的关注数与粉丝数, 已关注时返回 ErrAlreadyFollowing
+func (r *followRepo) Follow(ctx context.Context, userID, followUserID uint64) error {
+	err := r.q.Transaction(func(tx *query.Query) error {
+		err := tx.TbFollow.WithContext(ctx).Create(&model.TbFollow{UserID: userID, FollowUserID: followUserID})
+		if err != nil {
+			return err
+		}
+		ui := tx.TbUserInfo
+		return inUserIDOrder(userID, followUserID,
+			func() error {
+				return incrFollowCount(ctx, tx, &model.TbUserInfo{UserID: userID, Followee: 1}, ui.Followee)
+			},
+			func() error {
+				return incrFollowCount(ctx, tx, &model.TbUserInfo{UserID: followUserID, Fans: 1}, ui.Fans)
+			})
+	})
+	if isDuplicateKeyErr(err) {
+		return ErrAlreadyFollowing
+	}
+	return err
+}
+
+// Unfollow 在同一事务中删除关注关系并减少双方的关注数与粉丝数; 未关注时返回 false
+func (r *followRepo) Unfollow(ctx context.Context, userID, followUserID uint64) (bool, error) {
+	deleted := false
+	err := r.q.Transaction(func(tx *query.Query) error {
+		f := tx.TbFollow
+		info, err := f.WithContext(ctx).Where(f.UserID.Eq(userID), f.FollowUserID.Eq(followUserID)).Delete()
+		if err != nil || info.RowsAffected == 0 {
+			return err
+		}
+		deleted = true
+
+		ui := tx.TbUserInfo
+		return inUserIDOrder(userID, followUserID,
+			func() error {
+				_, err := ui.WithContext(ctx).Where(ui.UserID.Eq(userID), ui.Followee.Gt(0)).UpdateSimple(ui.Followee.Sub(1))
+				return err
+			},
+			func() error {
+				_, err := ui.WithContext(ctx).Where(ui.UserID.Eq(followUserID), ui.Fans.Gt(0)).UpdateSimple(ui.Fans.Sub(1))
+				return err
+			})
+	})
+	return deleted, err
+}
+
+// inUserIDOrder 先更新 user_id 较小的用户, 互相关注或取关的并发事务按相同顺序锁定 tb_user_info 的两行, 不会死锁
+func inUserIDOrder(userID, followUserID uint64, updateUser, updateFollowUser func() error) error {
+	if followUserID < userID {
+		updateUser, updateFollowUser = updateFollowUser, updateUser
+	}
+	if err := updateUser(); err != nil {
+		return err
+	}
+	return updateFollowUser()
+}
+
+// incrFollowCount 关注数或粉丝数加一, 用户还没有 tb_user_info 记录时以 info 插入
+func incrFollowCount(ctx context.Context, tx *query.Query, info *model.TbUserInfo, col field.Uint64) error {
+	ui := tx.TbUserInfo
+	return ui.WithContext(ctx).Select(ui.UserID, col).Clauses(clause.OnConflict{
+		DoUpdates: clause.Set{{Column: clause.Column{Name: col.ColumnName().String()}, Value: col.Add(1)}},
+	}).Create(info)
+}
+
+func (r *followRepo) IsFollowing(ctx context.Context, userID, followUserID uint64) (bool, error) {
+	f := r.q.TbFollow
+	n, err := f.WithContext(ctx).Where(f.UserID.Eq(userID), f.FollowUserID.Eq(followUserID)).Count()
+	return n > 0, err
+}
+
+// ListFollowerIDs 分页查询关注了 userID 的用户, 最近关注的在前
+func (r *followRepo) ListFollowerIDs(ctx context.Context, userID uint64, offset, limit int) ([]uint64, error) {
+	var ids []uint64
+	f := r.q.TbFollow
+	err := f.WithContext(ctx).Where(f.FollowUserID.Eq(userID)).
+		Order(f.ID.Desc()).Offset(offset).Limit(limit).Pluck(f.UserID, &ids)
+	return ids, err
+}
+
+// ListFolloweeIDs 分页查询 userID 关注的用户, 最近关注的在前; limit 为负数时不分页
+func (r *followRepo) ListFolloweeIDs(ctx context.Context, userID uint64, offset, limit int) ([]uint64, error) {
+	var ids []uint64
+	f := r.q.TbFollow
+	err := f.WithContext(ctx).Where(f.UserID.Eq(userID)).
+		Order(f.ID.Desc()).Offset(offset).Limit(limit).Pluck(f.FollowUserID, &ids)
+	return ids, err
+}
+
+// getFollowsKey 用户关注的用户集合, 用于求共同关注
+func getFollowsKey(userID uint64) string {
+	return fmt.Sprintf("user:follows:%d", userID)
+}
+
+// getFollowsVersionKey 关注集合未缓存时的修改次数, 用于丢弃加载期间已过时的数据
+func getFollowsVersionKey(userID uint64) string {
+	return fmt.Sprintf("user:follows:%d:version", userID)
+}
+
+func (r *followRepo) updateFollowCache(ctx context.Context, cmd string, userID, followUserID uint64) error {
+	keys := []string{getFollowsKey(userID), getFollowsVersionKey(userID)}
+	return updateFollowScript.Run(ctx, r.rdb, keys, cmd, followUserID, followsVersionTTL.Milliseconds()).Err()
+}
+
+func (r *followRepo) AddFollowCache(ctx context.Context, userID, followUserID uint64) error {
+	return r.updateFollowCache(ctx, "sadd", userID, followUserID)
+}
+
+func (r *followRepo) RemoveFollowCache(ctx context.Context, userID, followUserID uint64) error {
+	return r.updateFollowCache(ctx, "srem", userID, followUserID)
+}
+
+// LoadFollowsCache 关注集合未缓存时从 MySQL 加载; 没有关注任何人时不写入缓存, 求交集时不存在的 key 按空集合处理;
+// 加载期间关注关系发生变化时放弃写入, 下次再加载
+func (r *followRepo) LoadFollowsCache(ctx context.Context, userID uint64) error {
+	key, versionKey := getFollowsKey(userID), getFollowsVersionKey(userID)
+	n, err := r.rdb.Exists(ctx, key).Result()
+	if err != nil || n > 0 {
+		return err
+	}
+	version, err := r.rdb.Get(ctx, versionKey).Result()
+	if errors.Is(err, redis.Nil) {
+		version, err = "0", nil
+	}
+	if err != nil {
+		return err
+	}
+	ids, err := r.ListFolloweeIDs(ctx, userID, 0, -1)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	args := make([]any, 0, len(ids)+2)
+	args = append(args, version, followsCacheTTL.Milliseconds())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return loadFollowsScript.Run(ctx, r.rdb, []string{key, versionKey, key + ":loading"}, args...).Err()
+}
+
+func (r *followRepo) DeleteFollowsCache(ctx context.Context, userID uint64) error {
+	return r.rdb.Del(ctx, getFollowsKey(userID)).Err()
+}
+
+// CommonFollowsCache 两个用户关注集合的交集, 调用前需要先加载双方的关注集合
+func (r *followRepo) CommonFollowsCache(ctx context.Context, userID, otherID uint64) ([]uint64, error) {
+	members, err := r.rdb.SInter(ctx, getFollowsKey(userID), getFollowsKey(otherID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid followee %q: %w", m, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func NewFollowRepo(db *gorm.DB, rdb *redis.Client) FollowRepo {
+	return &followRepo{q: query.Use(db), rdb: rdb}
+}
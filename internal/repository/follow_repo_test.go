@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-sql-driver/mysql"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 关注时在同一事务中增加双方的计数, 用户信息不存在时插入
+func TestFollow(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewFollowRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_follow`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `tb_user_info` \\(`followee`,`user_id`\\) VALUES \\(\\?,\\?\\) ON DUPLICATE KEY UPDATE `followee`=`tb_user_info`.`followee`\\+\\?").
+		WithArgs(1, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `tb_user_info` \\(`fans`,`user_id`\\) VALUES \\(\\?,\\?\\) ON DUPLICATE KEY UPDATE `fans`=`tb_user_info`.`fans`\\+\\?").
+		WithArgs(1, 2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Follow(context.Background(), 1, 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFollowTwice(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewFollowRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_follow`").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry '1-2' for key 'uk_user_follow'"})
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, repo.Follow(context.Background(), 1, 2), ErrAlreadyFollowing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 无论谁关注谁, 都先更新 user_id 较小的用户, 互相关注的并发事务不会死锁
+func TestFollowLocksLowerUserFirst(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewFollowRepo(db, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_follow`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `tb_user_info` \\(`fans`,`user_id`\\)").
+		WithArgs(1, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO `tb_user_info` \\(`followee`,`user_id`\\)").
+		WithArgs(1, 2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Follow(context.Background(), 2, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 关注集合未加载时不写入新关注, 加载后才能求共同关注
+func TestCommonFollowsCache(t *testing.T) {
+	db, mock := newMockDB(t)
+	m := miniredis.RunT(t)
+	repo := NewFollowRepo(db, redis.NewClient(&redis.Options{Addr: m.Addr()}))
+	ctx := context.Background()
+
+	require.NoError(t, repo.AddFollowCache(ctx, 1, 5))
+	assert.False(t, m.Exists(getFollowsKey(1)))
+
+	mock.ExpectQuery("SELECT `follow_user_id` FROM `tb_follow` WHERE `tb_follow`.`user_id` = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"follow_user_id"}).AddRow(5).AddRow(3).AddRow(2))
+	require.NoError(t, repo.LoadFollowsCache(ctx, 1))
+	// 已加载时不再查询 MySQL
+	require.NoError(t, repo.LoadFollowsCache(ctx, 1))
+	require.NoError(t, repo.AddFollowCache(ctx, 1, 7))
+
+	_, err := m.SAdd(getFollowsKey(4), "3", "7", "9")
+	require.NoError(t, err)
+	common, err := repo.CommonFollowsCache(ctx, 1, 4)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint64{3, 7}, common)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 加载期间新增的关注没有写入缓存, 加载的旧数据也不能写入
+func TestLoadFollowsCacheConcurrentFollow(t *testing.T) {
+	db, mock := newMockDB(t)
+	m := miniredis.RunT(t)
+	repo := NewFollowRepo(db, redis.NewClient(&redis.Options{Addr: m.Addr()}))
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT `follow_user_id` FROM `tb_follow`").
+		WithArgs(1).
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"follow_user_id"}).AddRow(3))
+	done := make(chan error, 1)
+	go func() { done <- repo.LoadFollowsCache(ctx, 1) }()
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, repo.AddFollowCache(ctx, 1, 5))
+	require.NoError(t, <-done)
+	assert.False(t, m.Exists(getFollowsKey(1)))
+
+	mock.ExpectQuery("SELECT `follow_user_id` FROM `tb_follow`").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"follow_user_id"}).AddRow(5).AddRow(3))
+	require.NoError(t, repo.LoadFollowsCache(ctx, 1))
+	members, err := m.Members(getFollowsKey(1))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"3", "5"}, members)
+	assert.False(t, m.Exists(getFollowsKey(1)+":loading"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
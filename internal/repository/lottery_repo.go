@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/dal/query"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type LotteryRepo interface {
+	ListPendingDrawVoucherIDs(ctx context.Context, now time.Time) ([]uint64, error)
+	CreateDrawRecord(ctx context.Context, record *model.TbSeckillLottery, participants []uint64) error
+	GetDrawRecord(ctx context.Context, voucherID uint64) (*model.TbSeckillLottery, error)
+	UpdateDrawIssued(ctx context.Context, voucherID, issued uint64) error
+	GetParticipants(ctx context.Context, voucherID uint64) ([]uint64, error)
+	ListDrawParticipants(ctx context.Context, voucherID uint64) ([]uint64, error)
+	GetWinnerOrderID(ctx context.Context, voucherID, userID uint64) (int64, error)
+}
+
+type lotteryRepo struct {
+	q      *query.Query
+	rdb    *redis.Client
+	logger *slog.Logger
+}
+
+// ListPendingDrawVoucherIDs 查询已到开始时间、仍在上架, 且尚未抽签或中签订单尚未全部发放的抽签券
+func (r *lotteryRepo) ListPendingDrawVoucherIDs(ctx context.Context, now time.Time) ([]uint64, error) {
+	v, sv, l := r.q.TbVoucher, r.q.TbSeckillVoucher, r.q.TbSeckillLottery
+	var ids []uint64
+	err := sv.WithContext(ctx).
+		Join(v, v.ID.EqCol(sv.VoucherID)).
+		LeftJoin(l, l.VoucherID.EqCol(sv.VoucherID)).
+		Where(
+			sv.Mode.Eq(SeckillModeLottery),
+			sv.BeginTime.Lte(now),
+			v.Status.Eq(VoucherStatusOnline),
+			l.WithContext(ctx).Where(l.VoucherID.IsNull()).Or(l.Issued.LtCol(l.Winners)),
+		).
+		// 带表名查询, 避免与 tb_seckill_lottery.voucher_id 冲突
+		Select(sv.VoucherID).
+		Scan(&ids)
+	return ids, err
+}
+
+// lotteryParticipantBatch 每条 INSERT 写入的预约名单条数
+const lotteryParticipantBatch = 1000
+
+// CreateDrawRecord 在同一事务中写入抽签记录与预约名单, 主键冲突说明已被其他实例抽签, 返回 gorm.ErrDuplicatedKey
+func (r *lotteryRepo) CreateDrawRecord(ctx context.Context, record *model.TbSeckillLottery, participants []uint64) error {
+	err := r.q.Transaction(func(tx *query.Query) error {
+		if err := tx.TbSeckillLottery.WithContext(ctx).Create(record); err != nil {
+			return err
+		}
+		rows := make([]*model.TbSeckillLotteryParticipant, 0, len(participants))
+		for _, userID := range participants {
+			rows = append(rows, &model.TbSeckillLotteryParticipant{VoucherID: record.VoucherID, UserID: userID})
+		}
+		return tx.TbSeckillLotteryParticipant.WithContext(ctx).CreateInBatches(rows, lotteryParticipantBatch)
+	})
+	if isDuplicateKeyErr(err) {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
+
+func (r *lotteryRepo) GetDrawRecord(ctx context.Context, voucherID uint64) (*model.TbSeckillLottery, error) {
+	l := r.q.TbSeckillLottery
+	return l.WithContext(ctx).Where(l.VoucherID.Eq(voucherID)).First()
+}
+
+// UpdateDrawIssued 记录已发放订单的中签人数, 只会增加
+func (r *lotteryRepo) UpdateDrawIssued(ctx context.Context, voucherID, issued uint64) error {
+	l := r.q.TbSeckillLottery
+	_, err := l.WithContext(ctx).Where(l.VoucherID.Eq(voucherID), l.Issued.Lt(issued)).UpdateSimple(l.Issued.Value(issued))
+	return err
+}
+
+// ListDrawParticipants 按用户 ID 升序返回抽签时保存的预约名单
+func (r *lotteryRepo) ListDrawParticipants(ctx context.Context, voucherID uint64) ([]uint64, error) {
+	p := r.q.TbSeckillLotteryParticipant
+	var userIDs []uint64
+	err := p.WithContext(ctx).Where(p.VoucherID.Eq(voucherID)).Order(p.UserID).Pluck(p.UserID, &userIDs)
+	return userIDs, err
+}
+
+// GetParticipants 从 Redis 读取当前的预约名单, 抽签后以 MySQL 中保存的名单为准
+func (r *lotteryRepo) GetParticipants(ctx context.Context, voucherID uint64) ([]uint64, error) {
+	members, err := r.rdb.SMembers(ctx, getLotteryRegisterKey(voucherID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uint64, 0, len(members))
+	for _, m := range members {
+		userID, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			r.logger.Warn("invalid lottery participant", "voucher_id", voucherID, "member", m)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// GetWinnerOrderID 返回中签用户的订单 ID, 未中签时返回 redis.Nil
+func (r *lotteryRepo) GetWinnerOrderID(ctx context.Context, voucherID, userID uint64) (int64, error) {
+	return r.rdb.HGet(ctx, getLotteryWinnerKey(voucherID), strconv.FormatUint(userID, 10)).Int64()
+}
+
+func getLotteryRegisterKey(voucherID uint64) string {
+	return fmt.Sprintf("seckill:lottery:register:%d", voucherID)
+}
+
+func getLotteryWinnerKey(voucherID uint64) string {
+	return fmt.Sprintf("seckill:lottery:winner:%d", voucherID)
+}
+
+func NewLotteryRepo(db *gorm.DB, rdb *redis.Client, logger *slog.Logger) LotteryRepo {
+	return &lotteryRepo{
+		q:      query.Use(db),
+		rdb:    rdb,
+		logger: logger,
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 尚未抽签或中签订单尚未全部发放的抽签券都需要处理
+func TestListPendingDrawVoucherIDs(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewLotteryRepo(db, nil, nil)
+
+	mock.ExpectQuery("SELECT `tb_seckill_voucher`.`voucher_id` FROM `tb_seckill_voucher` .* WHERE .* AND \\(`tb_seckill_lottery`.`voucher_id` IS NULL OR `tb_seckill_lottery`.`issued` < `tb_seckill_lottery`.`winners`\\)").WillReturnRows(sqlmock.NewRows([]string{"voucher_id"}).AddRow(7))
+	ids, err := repo.ListPendingDrawVoucherIDs(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{7}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateDrawRecord(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewLotteryRepo(db, nil, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_seckill_lottery` ").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `tb_seckill_lottery_participant` \\(`voucher_id`,`user_id`\\) VALUES \\(\\?,\\?\\),\\(\\?,\\?\\)").
+		WithArgs(7, 1, 7, 3).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := repo.CreateDrawRecord(context.Background(), &model.TbSeckillLottery{VoucherID: 7, Winners: 1}, []uint64{1, 3})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
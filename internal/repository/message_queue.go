@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"time"
 
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -12,86 +14,106 @@ const (
 	OrderStreamKey      = "stream:orders"
 	OrderGroup          = "group:orders"
 	DeadLetterStreamKey = "stream:orders:dead"
+	// RetryDelayKey 等待重试的订单消息, Redis 中为 score 是到期毫秒时间戳的有序集合
+	RetryDelayKey = "stream:orders:retry"
+	// EventStreamKey 发件箱转发的业务事件
+	EventStreamKey = "stream:events"
 )
 
-type MessageQueue interface {
-	AddToStream(ctx context.Context, streamKey string, values map[string]any) (string, error)
-	AddOrderToStream(ctx context.Context, values map[string]any) (string, error)
+// eventStreamMaxLen 事件流只保留最近的事件, 发件箱中的记录仍可用于补发
+const eventStreamMaxLen = 100000
 
-	CreateGroup(ctx context.Context) error
-	ReadPendingMessages(ctx context.Context, consumerName string) ([]redis.XMessage, error)
-	Ack(ctx context.Context, streamKey, groupName, msgID string) error
+// streamMaxLen 各流保留的最大消息数, 0 表示不裁剪; 订单流和死信流中的消息须处理后才能删除, 不能按长度裁剪
+func streamMaxLen(streamKey string) int64 {
+	if streamKey == EventStreamKey {
+		return eventStreamMaxLen
+	}
+	return 0
 }
 
-type messageQueue struct {
-	rdb *redis.Client
-}
+// inFlightPageSize 列出未确认消息时每次读取的数量
+const inFlightPageSize = 1000
 
-func (m *messageQueue) AddToStream(ctx context.Context, streamKey string, values map[string]any) (string, error) {
-	return m.rdb.XAdd(ctx, &redis.XAddArgs{
-		Stream: streamKey,
-		Values: values,
-	}).Result()
-}
+// 消息中间件类型, 对应配置 mq.Broker
+const (
+	BrokerRedis  = "redis"  // Redis Streams, 默认
+	BrokerMemory = "memory" // 进程内队列, 仅用于测试和单机调试
+	BrokerNATS   = "nats"   // NATS JetStream
+)
 
-func (m *messageQueue) AddOrderToStream(ctx context.Context, values map[string]any) (string, error) {
-	return m.AddToStream(ctx, OrderStreamKey, values)
+// Message 与具体消息中间件无关的消息, ID 在同一个流内唯一且递增
+type Message struct {
+	ID     string
+	Values map[string]string
 }
 
-func (m *messageQueue) CreateGroup(ctx context.Context) error {
-	_, err := m.rdb.XGroupCreateMkStream(ctx, OrderStreamKey, OrderGroup, "0").Result()
-	// 0 标识从头开始消费, $ 标识从最新消息开始消费
-	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
-		return err
-	}
-	return nil
+// ConsumerInfo 消费者组中一个消费者的状态
+type ConsumerInfo struct {
+	Name    string
+	Pending int64         // 已读取未确认的消息数
+	Idle    time.Duration // 距离上次读取或认领的时间
 }
 
-func (m *messageQueue) ReadPendingMessages(ctx context.Context, consumerName string) ([]redis.XMessage, error) {
-	streams, err := m.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
-		Group:    OrderGroup,
-		Consumer: consumerName,
-		Streams:  []string{OrderStreamKey, ">"}, // ">" 表示只读取未被消费的消息
-		Count:    1,                             // 每次读取1条消息
-		Block:    0,                             // 阻塞时间为0表示立即返回
-	}).Result()
-
-	if err != nil {
-		return nil, err
-	}
-	if len(streams) == 0 || len(streams[0].Messages) == 0 {
-		return nil, nil // 没有新消息
-	}
-
-	return streams[0].Messages, nil
+// retryEntry 延时重试的消息, 带上原消息 ID 保证唯一
+type retryEntry struct {
+	ID     string            `json:"id"`
+	DueAt  int64             `json:"due_at,omitempty"`
+	Values map[string]string `json:"values"`
 }
 
-func (m *messageQueue) Ack(ctx context.Context, streamKey, groupName, msgID string) error {
-	return m.rdb.XAck(ctx, streamKey, msgID, groupName).Err()
-}
+// MessageQueue 订单消息队列, 语义以 Redis Streams 为准:
+// 同一消费者组内每条消息只投递给一个消费者, 确认前保留在待确认列表中, 闲置过久可被其他消费者认领;
+// RangeMessages 的 start/end 支持 "-"、"+" 和 "(" 前缀表示的开区间
+type MessageQueue interface {
+	AddToStream(ctx context.Context, streamKey string, values map[string]any) (string, error)
+	AddOrderToStream(ctx context.Context, values map[string]any) (string, error)
 
-// ClaimMessage 从全体闲置消息中认领一条消息
-func (m *messageQueue) ClaimMessage(ctx context.Context, consumerName string, minIdleTime time.Duration, count int64) ([]redis.XMessage, error) {
-	msgs, _, err := m.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
-		Stream:   OrderStreamKey,
-		Group:    OrderGroup,
-		Consumer: consumerName,
-		MinIdle:  minIdleTime,
-		Start:    "0-0", // 每次都从头开始扫描待处理列表
-		Count:    count,
-	}).Result()
+	CreateGroup(ctx context.Context) error
+	ReadPendingMessages(ctx context.Context, consumerName string, count int64, block time.Duration) ([]Message, error)
+	ClaimMessage(ctx context.Context, consumerName string, minIdleTime time.Duration, count int64) ([]Message, error)
+	Ack(ctx context.Context, streamKey, groupName string, msgIDs ...string) error
+	RangeMessages(ctx context.Context, streamKey, start, end string, count int64) ([]Message, error)
+	DeleteMessages(ctx context.Context, streamKey string, msgIDs ...string) (int64, error)
+	ScheduleRetry(ctx context.Context, msgID string, values map[string]string, dueAt time.Time) error
+	PromoteDueRetries(ctx context.Context, now time.Time, count int64) (int64, error)
+	ListConsumers(ctx context.Context) ([]ConsumerInfo, error)
+	DeleteConsumer(ctx context.Context, consumerName string) error
+	// InFlightMessages 列出订单流中尚未确认的消息(包括尚未投递的)和等待重试的消息, 用于核对库存
+	InFlightMessages(ctx context.Context) ([]Message, error)
+}
 
-	if errors.Is(err, redis.Nil) {
-		return nil, nil // 没有待处理消息
+// NewMessageQueue 按配置选择消息中间件, 未配置时使用 Redis Streams
+func NewMessageQueue(setting *config.MQSetting, rdb *redis.Client) (MessageQueue, func(), error) {
+	broker := BrokerRedis
+	if setting != nil && setting.Broker != "" {
+		broker = setting.Broker
 	}
-	if err != nil {
-		return nil, err
+
+	switch broker {
+	case BrokerRedis:
+		return NewRedisMessageQueue(rdb), func() {}, nil
+	case BrokerMemory:
+		return NewMemoryMessageQueue(), func() {}, nil
+	case BrokerNATS:
+		nc, err := nats.Connect(setting.NATSURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to nats: %w", err)
+		}
+		mq, err := NewNATSMessageQueue(nc, setting.ClaimMinIdle)
+		if err != nil {
+			nc.Close()
+			return nil, nil, err
+		}
+		return mq, nc.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown message broker %q", broker)
 	}
-	return msgs, nil
 }
 
-func NewMessageQueue(rdb *redis.Client) MessageQueue {
-	return &messageQueue{
-		rdb: rdb,
+func stringValues(values map[string]any) map[string]string {
+	res := make(map[string]string, len(values))
+	for k, v := range values {
+		res[k] = fmt.Sprint(v)
 	}
+	return res
 }
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errGroupNotCreated = errors.New("consumer group not created")
+
+type memoryStream struct {
+	lastSeq uint64
+	entries []Message // 按 ID 递增排列
+}
+
+type memoryPending struct {
+	consumer    string
+	deliveredAt time.Time
+}
+
+type memoryRetry struct {
+	dueAt time.Time
+	entry retryEntry
+}
+
+// memoryQueue 进程内的消息队列, 按 Redis Streams 的消费者组语义实现, 仅用于测试和单机调试
+type memoryQueue struct {
+	mu        sync.Mutex
+	streams   map[string]*memoryStream
+	notify    chan struct{} // 订单流有新消息时关闭并替换, 唤醒阻塞读取的消费者
+	grouped   bool
+	delivered uint64 // 消费者组已投递到的最大序号
+	pending   map[string]*memoryPending
+	consumers map[string]time.Time // 消费者最近一次读取或认领的时间
+	retries   []memoryRetry
+}
+
+func (m *memoryQueue) stream(key string) *memoryStream {
+	s, ok := m.streams[key]
+	if !ok {
+		s = &memoryStream{}
+		m.streams[key] = s
+	}
+	return s
+}
+
+func (m *memoryQueue) add(streamKey string, values map[string]string) string {
+	s := m.stream(streamKey)
+	s.lastSeq++
+	id := strconv.FormatUint(s.lastSeq, 10)
+	s.entries = append(s.entries, Message{ID: id, Values: values})
+	if maxLen := streamMaxLen(streamKey); maxLen > 0 && int64(len(s.entries)) > maxLen {
+		s.entries = slices.Delete(s.entries, 0, len(s.entries)-int(maxLen))
+	}
+	if streamKey == OrderStreamKey {
+		close(m.notify)
+		m.notify = make(chan struct{})
+	}
+	return id
+}
+
+func (m *memoryQueue) AddToStream(_ context.Context, streamKey string, values map[string]any) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add(streamKey, stringValues(values)), nil
+}
+
+func (m *memoryQueue) AddOrderToStream(ctx context.Context, values map[string]any) (string, error) {
+	return m.AddToStream(ctx, OrderStreamKey, values)
+}
+
+func (m *memoryQueue) CreateGroup(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.grouped = true
+	return nil
+}
+
+// ReadPendingMessages 读取最多 count 条新消息, 没有新消息时最长等待 block, block <= 0 时一直等待
+func (m *memoryQueue) ReadPendingMessages(ctx context.Context, consumerName string, count int64, block time.Duration) ([]Message, error) {
+	var timeout <-chan time.Time
+	if block > 0 {
+		timer := time.NewTimer(block)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		m.mu.Lock()
+		if !m.grouped {
+			m.mu.Unlock()
+			return nil, errGroupNotCreated
+		}
+		m.consumers[consumerName] = time.Now()
+		var msgs []Message
+		for _, msg := range m.stream(OrderStreamKey).entries {
+			if int64(len(msgs)) >= count {
+				break
+			}
+			if seq := parseSeq(msg.ID); seq > m.delivered {
+				m.delivered = seq
+				m.pending[msg.ID] = &memoryPending{consumer: consumerName, deliveredAt: time.Now()}
+				msgs = append(msgs, msg)
+			}
+		}
+		notify := m.notify
+		m.mu.Unlock()
+
+		if len(msgs) > 0 {
+			return msgs, nil
+		}
+		select {
+		case <-notify:
+		case <-timeout:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (m *memoryQueue) ClaimMessage(_ context.Context, consumerName string, minIdleTime time.Duration, count int64) ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.grouped {
+		return nil, errGroupNotCreated
+	}
+
+	ids := make([]string, 0, len(m.pending))
+	for id := range m.pending {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(parseSeq(a), parseSeq(b)) })
+
+	now := time.Now()
+	m.consumers[consumerName] = now
+	var claimed []Message
+	for _, id := range ids {
+		if int64(len(claimed)) >= count {
+			break
+		}
+		p := m.pending[id]
+		if now.Sub(p.deliveredAt) < minIdleTime {
+			continue
+		}
+		msg, ok := m.find(OrderStreamKey, id)
+		if !ok {
+			// 与 XAUTOCLAIM 一致, 已被删除的消息直接移出待确认列表
+			delete(m.pending, id)
+			continue
+		}
+		p.consumer, p.deliveredAt = consumerName, now
+		claimed = append(claimed, msg)
+	}
+	return claimed, nil
+}
+
+func (m *memoryQueue) find(streamKey, id string) (Message, bool) {
+	entries := m.stream(streamKey).entries
+	i, ok := slices.BinarySearchFunc(entries, parseSeq(id), func(msg Message, seq uint64) int {
+		return cmp.Compare(parseSeq(msg.ID), seq)
+	})
+	if !ok {
+		return Message{}, false
+	}
+	return entries[i], true
+}
+
+func (m *memoryQueue) Ack(_ context.Context, streamKey, _ string, msgIDs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if streamKey != OrderStreamKey {
+		return nil
+	}
+	for _, id := range msgIDs {
+		delete(m.pending, id)
+	}
+	return nil
+}
+
+func (m *memoryQueue) RangeMessages(_ context.Context, streamKey, start, end string, count int64) ([]Message, error) {
+	from, to, err := parseSeqRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []Message
+	for _, msg := range m.stream(streamKey).entries {
+		if int64(len(msgs)) >= count {
+			break
+		}
+		if seq := parseSeq(msg.ID); seq >= from && seq <= to {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (m *memoryQueue) DeleteMessages(_ context.Context, streamKey string, msgIDs ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stream(streamKey)
+	before := len(s.entries)
+	s.entries = slices.DeleteFunc(s.entries, func(msg Message) bool {
+		return slices.Contains(msgIDs, msg.ID)
+	})
+	return int64(before - len(s.entries)), nil
+}
+
+func (m *memoryQueue) ScheduleRetry(_ context.Context, msgID string, values map[string]string, dueAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, memoryRetry{dueAt: dueAt, entry: retryEntry{ID: msgID, Values: values}})
+	return nil
+}
+
+func (m *memoryQueue) PromoteDueRetries(_ context.Context, now time.Time, count int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	slices.SortStableFunc(m.retries, func(a, b memoryRetry) int { return a.dueAt.Compare(b.dueAt) })
+
+	var promoted int64
+	for promoted < count && promoted < int64(len(m.retries)) && !m.retries[promoted].dueAt.After(now) {
+		m.add(OrderStreamKey, m.retries[promoted].entry.Values)
+		promoted++
+	}
+	m.retries = m.retries[promoted:]
+	return promoted, nil
+}
+
+func (m *memoryQueue) ListConsumers(context.Context) ([]ConsumerInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	infos := make([]ConsumerInfo, 0, len(m.consumers))
+	for name, seen := range m.consumers {
+		info := ConsumerInfo{Name: name, Idle: time.Since(seen)}
+		for _, p := range m.pending {
+			if p.consumer == name {
+				info.Pending++
+			}
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b ConsumerInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos, nil
+}
+
+func (m *memoryQueue) DeleteConsumer(_ context.Context, consumerName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.consumers, consumerName)
+	for id, p := range m.pending {
+		if p.consumer == consumerName {
+			delete(m.pending, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryQueue) InFlightMessages(context.Context) ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []Message
+	for _, msg := range m.stream(OrderStreamKey).entries {
+		if _, pending := m.pending[msg.ID]; pending || parseSeq(msg.ID) > m.delivered {
+			msgs = append(msgs, msg)
+		}
+	}
+	for _, r := range m.retries {
+		msgs = append(msgs, Message{ID: r.entry.ID, Values: r.entry.Values})
+	}
+	return msgs, nil
+}
+
+func NewMemoryMessageQueue() MessageQueue {
+	return &memoryQueue{
+		streams:   make(map[string]*memoryStream),
+		notify:    make(chan struct{}),
+		pending:   make(map[string]*memoryPending),
+		consumers: make(map[string]time.Time),
+	}
+}
+
+// parseSeq 解析内存队列和 NATS 使用的数字消息 ID, 无法解析时返回 0
+func parseSeq(id string) uint64 {
+	seq, _ := strconv.ParseUint(id, 10, 64)
+	return seq
+}
+
+// parseSeqRange 将 Redis 风格的 start/end 转换为闭区间 [from, to]
+func parseSeqRange(start, end string) (from, to uint64, err error) {
+	from, to = 1, math.MaxUint64
+	switch {
+	case start == "-":
+	case strings.HasPrefix(start, "("):
+		if from, err = strconv.ParseUint(start[1:], 10, 64); err != nil {
+			return 0, 0, err
+		}
+		from++
+	default:
+		if from, err = strconv.ParseUint(start, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	switch {
+	case end == "+":
+	case strings.HasPrefix(end, "("):
+		if to, err = strconv.ParseUint(end[1:], 10, 64); err != nil {
+			return 0, 0, err
+		}
+		to--
+	default:
+		if to, err = strconv.ParseUint(end, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	return from, to, nil
+}
@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	defaultNATSAckWait = 30 * time.Second
+	natsRetryConsumer  = "retry_promoter"
+	natsSetupTimeout   = 5 * time.Second
+)
+
+// natsQueue 基于 NATS JetStream 的消息队列
+// 每个流对应一个 JetStream stream, 消费者组对应一个持久化的 pull consumer, 消费者名称只用于日志;
+// 未确认的消息超过 AckWait 后由服务端自动重新投递, 因此 ClaimMessage 不需要做任何事
+type natsQueue struct {
+	js      jetstream.JetStream
+	ackWait time.Duration
+
+	mu      sync.Mutex
+	orders  jetstream.Consumer
+	retries jetstream.Consumer
+	unacked map[string]natsUnacked // 已读取未确认的消息, 确认时需要原始消息
+}
+
+type natsUnacked struct {
+	msg    jetstream.Msg
+	readAt time.Time
+}
+
+// natsStreamName stream:orders:dead -> stream_orders_dead
+func natsStreamName(streamKey string) string {
+	return strings.ReplaceAll(streamKey, ":", "_")
+}
+
+// natsSubject stream:orders:dead -> stream.orders.dead
+func natsSubject(streamKey string) string {
+	return strings.ReplaceAll(streamKey, ":", ".")
+}
+
+func (m *natsQueue) AddToStream(ctx context.Context, streamKey string, values map[string]any) (string, error) {
+	return m.publish(ctx, streamKey, stringValues(values))
+}
+
+func (m *natsQueue) publish(ctx context.Context, streamKey string, values map[string]string) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	ack, err := m.js.Publish(ctx, natsSubject(streamKey), data)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(ack.Sequence, 10), nil
+}
+
+func (m *natsQueue) AddOrderToStream(ctx context.Context, values map[string]any) (string, error) {
+	return m.AddToStream(ctx, OrderStreamKey, values)
+}
+
+// CreateGroup 创建订单流的持久化消费者和重试流的消费者, 已存在时更新配置
+func (m *natsQueue) CreateGroup(ctx context.Context) error {
+	orders, err := m.js.CreateOrUpdateConsumer(ctx, natsStreamName(OrderStreamKey), jetstream.ConsumerConfig{
+		Durable:   natsStreamName(OrderGroup),
+		AckPolicy: jetstream.AckExplicitPolicy,
+		AckWait:   m.ackWait,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create order consumer: %w", err)
+	}
+	retries, err := m.js.CreateOrUpdateConsumer(ctx, natsStreamName(RetryDelayKey), jetstream.ConsumerConfig{
+		Durable:   natsRetryConsumer,
+		AckPolicy: jetstream.AckExplicitPolicy,
+		AckWait:   m.ackWait,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create retry consumer: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orders, m.retries = orders, retries
+	return nil
+}
+
+func (m *natsQueue) consumers() (orders, retries jetstream.Consumer, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.orders == nil {
+		return nil, nil, errGroupNotCreated
+	}
+	return m.orders, m.retries, nil
+}
+
+// ReadPendingMessages 先取出已有的最多 count 条消息, 没有时最长等待 block 直到有一条新消息; ctx 取消时立即返回
+func (m *natsQueue) ReadPendingMessages(ctx context.Context, _ string, count int64, block time.Duration) ([]Message, error) {
+	orders, _, err := m.consumers()
+	if err != nil {
+		return nil, err
+	}
+	m.evictRedelivered(time.Now())
+
+	batch, err := orders.FetchNoWait(int(count))
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := m.fetch(ctx, batch)
+	if err != nil || len(msgs) > 0 {
+		return msgs, err
+	}
+
+	// block <= 0 时使用客户端默认的等待时间
+	var opts []jetstream.FetchOpt
+	if block > 0 {
+		opts = append(opts, jetstream.FetchMaxWait(block))
+	}
+	if batch, err = orders.Fetch(1, opts...); err != nil {
+		return nil, err
+	}
+	msgs, err = m.fetch(ctx, batch)
+	if err != nil || len(msgs) == 0 || count == 1 {
+		return msgs, err
+	}
+	if batch, err = orders.FetchNoWait(int(count) - 1); err != nil {
+		return msgs, err
+	}
+	more, err := m.fetch(ctx, batch)
+	return append(msgs, more...), err
+}
+
+// fetch ctx 取消时不再等待这一批消息, 之后到达的消息没有被记录, 会在 AckWait 后重新投递
+func (m *natsQueue) fetch(ctx context.Context, batch jetstream.MessageBatch) ([]Message, error) {
+	var msgs []Message
+	for {
+		var msg jetstream.Msg
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return msgs, ctx.Err()
+		case msg, ok = <-batch.Messages():
+		}
+		if !ok {
+			return msgs, batch.Error()
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return msgs, err
+		}
+		id := strconv.FormatUint(meta.Sequence.Stream, 10)
+		var values map[string]string
+		// 无法解析的消息保留空字段, 由消费者按格式错误处理
+		_ = json.Unmarshal(msg.Data(), &values)
+
+		// 重新投递的消息替换之前记录的原始消息
+		m.mu.Lock()
+		m.unacked[id] = natsUnacked{msg: msg, readAt: time.Now()}
+		m.mu.Unlock()
+		msgs = append(msgs, Message{ID: id, Values: values})
+	}
+}
+
+// evictRedelivered 读取后超过 AckWait 仍未确认的消息已由服务端重新投递(可能投递给其他进程), 不再保留原始消息
+func (m *natsQueue) evictRedelivered(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, u := range m.unacked {
+		if now.Sub(u.readAt) >= m.ackWait {
+			delete(m.unacked, id)
+		}
+	}
+}
+
+// ClaimMessage JetStream 会在 AckWait 后自动重新投递未确认的消息, 不需要主动认领
+func (m *natsQueue) ClaimMessage(context.Context, string, time.Duration, int64) ([]Message, error) {
+	return nil, nil
+}
+
+// Ack 确认订单流中的消息, 只能确认本进程读取的消息, 其他消息忽略
+func (m *natsQueue) Ack(ctx context.Context, streamKey, _ string, msgIDs ...string) error {
+	if streamKey != OrderStreamKey {
+		return nil
+	}
+
+	var errs []error
+	for _, id := range msgIDs {
+		m.mu.Lock()
+		u, ok := m.unacked[id]
+		delete(m.unacked, id)
+		m.mu.Unlock()
+		if ok {
+			errs = append(errs, u.msg.DoubleAck(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *natsQueue) RangeMessages(ctx context.Context, streamKey, start, end string, count int64) ([]Message, error) {
+	from, to, err := parseSeqRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []Message
+	err = m.rangeRaw(ctx, streamKey, from, to, count, func(raw *jetstream.RawStreamMsg) {
+		var values map[string]string
+		_ = json.Unmarshal(raw.Data, &values)
+		msgs = append(msgs, Message{ID: strconv.FormatUint(raw.Sequence, 10), Values: values})
+	})
+	return msgs, err
+}
+
+// rangeRaw 按序号顺序对 [from, to] 内最多 count 条消息调用 fn
+func (m *natsQueue) rangeRaw(ctx context.Context, streamKey string, from, to uint64, count int64, fn func(raw *jetstream.RawStreamMsg)) error {
+	stream, err := m.js.Stream(ctx, natsStreamName(streamKey))
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for seq := from; n < count && seq <= to; n++ {
+		// 按主题取序号不小于 seq 的第一条消息, 跳过已删除的序号
+		raw, err := stream.GetMsg(ctx, seq, jetstream.WithGetMsgSubject(natsSubject(streamKey)))
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if raw.Sequence > to {
+			break
+		}
+		fn(raw)
+		seq = raw.Sequence + 1
+	}
+	return nil
+}
+
+func (m *natsQueue) DeleteMessages(ctx context.Context, streamKey string, msgIDs ...string) (int64, error) {
+	stream, err := m.js.Stream(ctx, natsStreamName(streamKey))
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, id := range msgIDs {
+		seq, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		err = stream.DeleteMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// ScheduleRetry 写入重试流, 由 PromoteDueRetries 在到期后转发到订单流
+func (m *natsQueue) ScheduleRetry(ctx context.Context, msgID string, values map[string]string, dueAt time.Time) error {
+	data, err := json.Marshal(retryEntry{ID: msgID, DueAt: dueAt.UnixMilli(), Values: values})
+	if err != nil {
+		return err
+	}
+	_, err = m.js.Publish(ctx, natsSubject(RetryDelayKey), data)
+	return err
+}
+
+// PromoteDueRetries 取出重试流中的消息, 到期的转发到订单流, 未到期的延迟到到期时再投递
+func (m *natsQueue) PromoteDueRetries(ctx context.Context, now time.Time, count int64) (int64, error) {
+	_, retries, err := m.consumers()
+	if err != nil {
+		return 0, err
+	}
+	batch, err := retries.FetchNoWait(int(count))
+	if err != nil {
+		return 0, err
+	}
+
+	var promoted int64
+	var errs []error
+	for msg := range batch.Messages() {
+		var entry retryEntry
+		if err := json.Unmarshal(msg.Data(), &entry); err != nil {
+			errs = append(errs, msg.Term())
+			continue
+		}
+		if dueAt := time.UnixMilli(entry.DueAt); dueAt.After(now) {
+			errs = append(errs, msg.NakWithDelay(dueAt.Sub(now)))
+			continue
+		}
+		if _, err := m.publish(ctx, OrderStreamKey, entry.Values); err != nil {
+			errs = append(errs, err, msg.Nak())
+			continue
+		}
+		errs = append(errs, msg.Ack())
+		promoted++
+	}
+	errs = append(errs, batch.Error())
+	return promoted, errors.Join(errs...)
+}
+
+// ListConsumers JetStream 的 pull consumer 不区分客户端, 没有需要清理的消费者
+func (m *natsQueue) ListConsumers(context.Context) ([]ConsumerInfo, error) {
+	return nil, nil
+}
+
+func (m *natsQueue) DeleteConsumer(context.Context, string) error {
+	return nil
+}
+
+// InFlightMessages 订单流和重试流使用 WorkQueue 保留策略, 确认后即删除, 流中剩余的都是未确认的消息;
+// 按消费者状态中未确认和未投递的消息数读取, 读够即停止
+func (m *natsQueue) InFlightMessages(ctx context.Context) ([]Message, error) {
+	var msgs []Message
+	err := m.rangeUnacked(ctx, OrderStreamKey, natsStreamName(OrderGroup), func(raw *jetstream.RawStreamMsg) {
+		var values map[string]string
+		_ = json.Unmarshal(raw.Data, &values)
+		msgs = append(msgs, Message{ID: strconv.FormatUint(raw.Sequence, 10), Values: values})
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = m.rangeUnacked(ctx, RetryDelayKey, natsRetryConsumer, func(raw *jetstream.RawStreamMsg) {
+		var entry retryEntry
+		if json.Unmarshal(raw.Data, &entry) == nil {
+			msgs = append(msgs, Message{ID: entry.ID, Values: entry.Values})
+		}
+	})
+	return msgs, err
+}
+
+// rangeUnacked 从消费者的确认位置之后读取 NumAckPending + NumPending 条消息; 消费者创建前流中的消息都未投递, 按流的状态读取
+func (m *natsQueue) rangeUnacked(ctx context.Context, streamKey, durable string, fn func(raw *jetstream.RawStreamMsg)) error {
+	var from uint64
+	var count int64
+	consumer, err := m.js.Consumer(ctx, natsStreamName(streamKey), durable)
+	switch {
+	case errors.Is(err, jetstream.ErrConsumerNotFound):
+		stream, err := m.js.Stream(ctx, natsStreamName(streamKey))
+		if err != nil {
+			return err
+		}
+		info, err := stream.Info(ctx)
+		if err != nil {
+			return err
+		}
+		from, count = info.State.FirstSeq, int64(info.State.Msgs)
+	case err != nil:
+		return err
+	default:
+		info, err := consumer.Info(ctx)
+		if err != nil {
+			return err
+		}
+		from, count = info.AckFloor.Stream+1, int64(info.NumAckPending)+int64(info.NumPending)
+	}
+	if count == 0 {
+		return nil
+	}
+	return m.rangeRaw(ctx, streamKey, from, math.MaxUint64, count, fn)
+}
+
+// NewNATSMessageQueue 创建订单流、死信流、重试流和事件流, ackWait 为消息未确认时重新投递的等待时间
+func NewNATSMessageQueue(nc *nats.Conn, ackWait time.Duration) (MessageQueue, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	if ackWait <= 0 {
+		ackWait = defaultNATSAckWait
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+	streams := map[string]jetstream.RetentionPolicy{
+		OrderStreamKey:      jetstream.WorkQueuePolicy,
+		RetryDelayKey:       jetstream.WorkQueuePolicy,
+		DeadLetterStreamKey: jetstream.LimitsPolicy,
+		EventStreamKey:      jetstream.LimitsPolicy,
+	}
+	for key, retention := range streams {
+		cfg := jetstream.StreamConfig{
+			Name:      natsStreamName(key),
+			Subjects:  []string{natsSubject(key)},
+			Retention: retention,
+		}
+		if maxLen := streamMaxLen(key); maxLen > 0 {
+			cfg.MaxMsgs = maxLen
+		}
+		_, err = js.CreateOrUpdateStream(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stream %s: %w", key, err)
+		}
+	}
+
+	return &natsQueue{
+		js:      js,
+		ackWait: ackWait,
+		unacked: make(map[string]natsUnacked),
+	}, nil
+}
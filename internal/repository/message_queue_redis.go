@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// promoteRetriesScript 原子地取出到期的重试消息并重新写入订单流, 多个进程同时执行也不会重复投递
+var promoteRetriesScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local promoted = 0
+for _, member in ipairs(due) do
+	if redis.call('ZREM', KEYS[1], member) == 1 then
+		local entry = cjson.decode(member)
+		local fields = {}
+		for k, v in pairs(entry.values) do
+			table.insert(fields, k)
+			table.insert(fields, tostring(v))
+		end
+		redis.call('XADD', KEYS[2], '*', unpack(fields))
+		promoted = promoted + 1
+	end
+end
+return promoted
+`)
+
+type redisQueue struct {
+	rdb *redis.Client
+}
+
+func (m *redisQueue) AddToStream(ctx context.Context, streamKey string, values map[string]any) (string, error) {
+	return m.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey,
+		MaxLen: streamMaxLen(streamKey),
+		Approx: true,
+		Values: values,
+	}).Result()
+}
+
+func (m *redisQueue) AddOrderToStream(ctx context.Context, values map[string]any) (string, error) {
+	return m.AddToStream(ctx, OrderStreamKey, values)
+}
+
+func (m *redisQueue) CreateGroup(ctx context.Context) error {
+	_, err := m.rdb.XGroupCreateMkStream(ctx, OrderStreamKey, OrderGroup, "0").Result()
+	// 0 标识从头开始消费, $ 标识从最新消息开始消费
+	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
+		return err
+	}
+	return nil
+}
+
+// ReadPendingMessages 阻塞读取最多 count 条新消息, 最长等待 block, 超时没有消息时返回空
+func (m *redisQueue) ReadPendingMessages(ctx context.Context, consumerName string, count int64, block time.Duration) ([]Message, error) {
+	streams, err := m.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    OrderGroup,
+		Consumer: consumerName,
+		Streams:  []string{OrderStreamKey, ">"}, // ">" 表示只读取未被消费的消息
+		Count:    count,
+		Block:    block, // 注意 0 表示一直阻塞
+	}).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, nil // 阻塞超时, 没有新消息
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return nil, nil // 没有新消息
+	}
+
+	return toMessages(streams[0].Messages), nil
+}
+
+func (m *redisQueue) Ack(ctx context.Context, streamKey, groupName string, msgIDs ...string) error {
+	return m.rdb.XAck(ctx, streamKey, groupName, msgIDs...).Err()
+}
+
+// ClaimMessage 从头扫描待处理列表, 认领闲置超过 minIdleTime 的消息, 最多认领 count 条
+// 认领会重置消息的闲置时间, 因此同一条消息不会被多个消费者同时认领
+func (m *redisQueue) ClaimMessage(ctx context.Context, consumerName string, minIdleTime time.Duration, count int64) ([]Message, error) {
+	var claimed []Message
+	start := "0-0" // 每次都从头开始扫描待处理列表
+	for {
+		msgs, next, err := m.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   OrderStreamKey,
+			Group:    OrderGroup,
+			Consumer: consumerName,
+			MinIdle:  minIdleTime,
+			Start:    start,
+			Count:    count,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			return claimed, nil // 没有待处理消息
+		}
+		if err != nil {
+			return claimed, err
+		}
+
+		claimed = append(claimed, toMessages(msgs)...)
+		if next == "0-0" || int64(len(claimed)) >= count {
+			return claimed, nil
+		}
+		start = next
+	}
+}
+
+// RangeMessages 按 ID 范围读取流中的消息, 不经过消费者组, 用于查看死信队列
+func (m *redisQueue) RangeMessages(ctx context.Context, streamKey, start, end string, count int64) ([]Message, error) {
+	msgs, err := m.rdb.XRangeN(ctx, streamKey, start, end, count).Result()
+	return toMessages(msgs), err
+}
+
+func (m *redisQueue) DeleteMessages(ctx context.Context, streamKey string, msgIDs ...string) (int64, error) {
+	return m.rdb.XDel(ctx, streamKey, msgIDs...).Result()
+}
+
+// ScheduleRetry 将失败的消息放入延时重试集合, 到期后由 PromoteDueRetries 重新写入订单流
+func (m *redisQueue) ScheduleRetry(ctx context.Context, msgID string, values map[string]string, dueAt time.Time) error {
+	member, err := json.Marshal(retryEntry{ID: msgID, Values: values})
+	if err != nil {
+		return err
+	}
+	return m.rdb.ZAdd(ctx, RetryDelayKey, redis.Z{
+		Score:  float64(dueAt.UnixMilli()),
+		Member: member,
+	}).Err()
+}
+
+// PromoteDueRetries 将最多 count 条到期的重试消息写回订单流, 返回写回的数量
+func (m *redisQueue) PromoteDueRetries(ctx context.Context, now time.Time, count int64) (int64, error) {
+	return promoteRetriesScript.Run(ctx, m.rdb,
+		[]string{RetryDelayKey, OrderStreamKey}, now.UnixMilli(), count).Int64()
+}
+
+// ListConsumers 列出订单消费者组中的所有消费者
+func (m *redisQueue) ListConsumers(ctx context.Context) ([]ConsumerInfo, error) {
+	consumers, err := m.rdb.XInfoConsumers(ctx, OrderStreamKey, OrderGroup).Result()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]ConsumerInfo, 0, len(consumers))
+	for _, c := range consumers {
+		infos = append(infos, ConsumerInfo{Name: c.Name, Pending: c.Pending, Idle: c.Idle})
+	}
+	return infos, nil
+}
+
+// DeleteConsumer 将消费者移出订单消费者组, 其待确认消息会一并丢弃, 调用前应确认没有待确认消息
+func (m *redisQueue) DeleteConsumer(ctx context.Context, consumerName string) error {
+	return m.rdb.XGroupDelConsumer(ctx, OrderStreamKey, OrderGroup, consumerName).Err()
+}
+
+// InFlightMessages 依次读取消费者组尚未投递的消息、待确认列表中的消息和延时重试集合中的消息
+func (m *redisQueue) InFlightMessages(ctx context.Context) ([]Message, error) {
+	n, err := m.rdb.Exists(ctx, OrderStreamKey).Result()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	groups, err := m.rdb.XInfoGroups(ctx, OrderStreamKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	grouped, start := false, "-"
+	for _, g := range groups {
+		if g.Name == OrderGroup {
+			grouped, start = true, "("+g.LastDeliveredID
+		}
+	}
+
+	undelivered, err := m.rdb.XRange(ctx, OrderStreamKey, start, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+	msgs := toMessages(undelivered)
+
+	if grouped {
+		pending, err := m.pendingMessages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, pending...)
+	}
+
+	members, err := m.rdb.ZRange(ctx, RetryDelayKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		var entry retryEntry
+		if err = json.Unmarshal([]byte(member), &entry); err != nil {
+			continue
+		}
+		msgs = append(msgs, Message{ID: entry.ID, Values: entry.Values})
+	}
+	return msgs, nil
+}
+
+// pendingMessages 分页读取待确认列表, 再逐条取出消息内容, 已被删除的消息跳过
+func (m *redisQueue) pendingMessages(ctx context.Context) ([]Message, error) {
+	var msgs []Message
+	start := "-"
+	for {
+		pending, err := m.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: OrderStreamKey,
+			Group:  OrderGroup,
+			Start:  start,
+			End:    "+",
+			Count:  inFlightPageSize,
+		}).Result()
+		if err != nil {
+			return msgs, err
+		}
+
+		cmds := make([]*redis.XMessageSliceCmd, 0, len(pending))
+		pipe := m.rdb.Pipeline()
+		for _, p := range pending {
+			cmds = append(cmds, pipe.XRangeN(ctx, OrderStreamKey, p.ID, p.ID, 1))
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return msgs, err
+		}
+		for _, cmd := range cmds {
+			msgs = append(msgs, toMessages(cmd.Val())...)
+		}
+
+		if len(pending) < inFlightPageSize {
+			return msgs, nil
+		}
+		start = "(" + pending[len(pending)-1].ID
+	}
+}
+
+func NewRedisMessageQueue(rdb *redis.Client) MessageQueue {
+	return &redisQueue{
+		rdb: rdb,
+	}
+}
+
+func toMessages(msgs []redis.XMessage) []Message {
+	if len(msgs) == 0 {
+		return nil
+	}
+	res := make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		res = append(res, Message{ID: msg.ID, Values: stringValues(msg.Values)})
+	}
+	return res
+}
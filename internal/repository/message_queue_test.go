@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redeliverAfter 测试中未确认消息重新投递的等待时间
+const redeliverAfter = 200 * time.Millisecond
+
+func newTestRedisQueue(t *testing.T) MessageQueue {
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return NewRedisMessageQueue(rdb)
+}
+
+func newTestNATSQueue(t *testing.T) MessageQueue {
+	srv, err := natsserver.NewServer(&natsserver.Options{
+		Host:      "127.0.0.1",
+		Port:      natsserver.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	mq, err := NewNATSMessageQueue(nc, redeliverAfter)
+	require.NoError(t, err)
+	return mq
+}
+
+// TestMessageQueue 各个消息中间件实现的行为应与 Redis Streams 一致
+func TestMessageQueue(t *testing.T) {
+	brokers := map[string]func(t *testing.T) MessageQueue{
+		BrokerRedis:  newTestRedisQueue,
+		BrokerMemory: func(*testing.T) MessageQueue { return NewMemoryMessageQueue() },
+		BrokerNATS:   newTestNATSQueue,
+	}
+	for name, newQueue := range brokers {
+		t.Run(name, func(t *testing.T) {
+			t.Run("ReadAndAck", func(t *testing.T) { testReadAndAck(t, newQueue(t)) })
+			t.Run("Redeliver", func(t *testing.T) { testRedeliver(t, newQueue(t)) })
+			t.Run("RangeAndDelete", func(t *testing.T) { testRangeAndDelete(t, newQueue(t)) })
+			t.Run("Retry", func(t *testing.T) { testRetry(t, newQueue(t)) })
+			t.Run("InFlight", func(t *testing.T) { testInFlight(t, newQueue(t)) })
+		})
+	}
+}
+
+func addOrders(t *testing.T, mq MessageQueue, orderIDs ...string) {
+	t.Helper()
+	for _, id := range orderIDs {
+		_, err := mq.AddOrderToStream(context.Background(), map[string]any{"userID": 7, "voucherID": 1, "orderID": id})
+		require.NoError(t, err)
+	}
+}
+
+func orderIDs(msgs []Message) []string {
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.Values["orderID"])
+	}
+	return ids
+}
+
+func testReadAndAck(t *testing.T, mq MessageQueue) {
+	ctx := context.Background()
+	require.NoError(t, mq.CreateGroup(ctx))
+	addOrders(t, mq, "1", "2", "3")
+
+	msgs, err := mq.ReadPendingMessages(ctx, "c1", 2, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, orderIDs(msgs))
+	assert.Equal(t, map[string]string{"userID": "7", "voucherID": "1", "orderID": "1"}, msgs[0].Values)
+
+	// 同一消费者组内的消息只投递一次
+	more, err := mq.ReadPendingMessages(ctx, "c2", 10, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3"}, orderIDs(more))
+	require.NoError(t, mq.Ack(ctx, OrderStreamKey, OrderGroup, msgs[0].ID, msgs[1].ID, more[0].ID))
+
+	// 没有新消息时等待 block 后返回空
+	start := time.Now()
+	msgs, err = mq.ReadPendingMessages(ctx, "c1", 10, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// 阻塞期间写入的消息立即返回
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		addOrders(t, mq, "4")
+	}()
+	msgs, err = mq.ReadPendingMessages(ctx, "c1", 10, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4"}, orderIDs(msgs))
+	require.NoError(t, mq.Ack(ctx, OrderStreamKey, OrderGroup, msgs[0].ID))
+}
+
+// testRedeliver 未确认的消息闲置一段时间后能被重新取回, Redis 和内存队列通过认领, NATS 由服务端重新投递
+func testRedeliver(t *testing.T, mq MessageQueue) {
+	ctx := context.Background()
+	require.NoError(t, mq.CreateGroup(ctx))
+	addOrders(t, mq, "1")
+
+	msgs, err := mq.ReadPendingMessages(ctx, "crashed", 1, time.Second)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	claimed, err := mq.ClaimMessage(ctx, "c1", redeliverAfter, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	time.Sleep(redeliverAfter + 50*time.Millisecond)
+	claimed, err = mq.ClaimMessage(ctx, "c1", redeliverAfter, 10)
+	require.NoError(t, err)
+	if len(claimed) == 0 {
+		claimed, err = mq.ReadPendingMessages(ctx, "c1", 10, time.Second)
+		require.NoError(t, err)
+	}
+	require.Len(t, claimed, 1)
+	assert.Equal(t, msgs[0].ID, claimed[0].ID)
+	require.NoError(t, mq.Ack(ctx, OrderStreamKey, OrderGroup, claimed[0].ID))
+}
+
+func testRangeAndDelete(t *testing.T, mq MessageQueue) {
+	ctx := context.Background()
+	var ids []string
+	for _, orderID := range []string{"1", "2", "3"} {
+		id, err := mq.AddToStream(ctx, DeadLetterStreamKey, map[string]any{"orderID": orderID, "error": "boom"})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	msgs, err := mq.RangeMessages(ctx, DeadLetterStreamKey, "-", "+", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, orderIDs(msgs))
+	assert.Equal(t, "boom", msgs[0].Values["error"])
+
+	msgs, err = mq.RangeMessages(ctx, DeadLetterStreamKey, "("+msgs[1].ID, "+", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3"}, orderIDs(msgs))
+
+	msgs, err = mq.RangeMessages(ctx, DeadLetterStreamKey, ids[1], ids[1], 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, orderIDs(msgs))
+
+	n, err := mq.DeleteMessages(ctx, DeadLetterStreamKey, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	msgs, err = mq.RangeMessages(ctx, DeadLetterStreamKey, "-", "+", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, orderIDs(msgs))
+}
+
+func testRetry(t *testing.T, mq MessageQueue) {
+	ctx := context.Background()
+	require.NoError(t, mq.CreateGroup(ctx))
+	now := time.Now()
+	require.NoError(t, mq.ScheduleRetry(ctx, "old-1", map[string]string{"orderID": "1", "retry_count": "1"}, now.Add(-time.Millisecond)))
+	require.NoError(t, mq.ScheduleRetry(ctx, "old-2", map[string]string{"orderID": "2", "retry_count": "1"}, now.Add(time.Hour)))
+
+	n, err := mq.PromoteDueRetries(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = mq.PromoteDueRetries(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	msgs, err := mq.ReadPendingMessages(ctx, "c1", 10, time.Second)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, map[string]string{"orderID": "1", "retry_count": "1"}, msgs[0].Values)
+}
+
+// testInFlight 已确认的消息不算在内, 尚未投递、已投递未确认和等待重试的消息都要列出
+func testInFlight(t *testing.T, mq MessageQueue) {
+	ctx := context.Background()
+	msgs, err := mq.InFlightMessages(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+
+	require.NoError(t, mq.CreateGroup(ctx))
+	addOrders(t, mq, "1", "2", "3")
+	read, err := mq.ReadPendingMessages(ctx, "c1", 2, time.Second)
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	require.NoError(t, mq.Ack(ctx, OrderStreamKey, OrderGroup, read[0].ID))
+	require.NoError(t, mq.ScheduleRetry(ctx, "old-4", map[string]string{"orderID": "4", "retry_count": "1"}, time.Now().Add(time.Hour)))
+
+	msgs, err = mq.InFlightMessages(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"2", "3", "4"}, orderIDs(msgs))
+}
+
+// ctx 取消时不等待 block 结束; 超过 AckWait 未确认的消息不再保留
+func TestNATSQueueReadCanceled(t *testing.T) {
+	mq := newTestNATSQueue(t)
+	require.NoError(t, mq.CreateGroup(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := mq.ReadPendingMessages(ctx, "c1", 1, 10*time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	addOrders(t, mq, "1")
+	msgs, err := mq.ReadPendingMessages(context.Background(), "c1", 1, time.Second)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	q := mq.(*natsQueue)
+	q.evictRedelivered(time.Now().Add(redeliverAfter))
+	q.mu.Lock()
+	assert.Empty(t, q.unacked)
+	q.mu.Unlock()
+}
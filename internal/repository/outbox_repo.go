@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/dal/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 发件箱事件状态, 对应 tb_outbox.status
+const (
+	OutboxStatusPending uint8 = 0
+	OutboxStatusSent    uint8 = 1
+)
+
+// 发件箱事件类型, 对应 tb_outbox.topic
+const (
+	TopicOrderCreated         = "order.created"
+	TopicShopUpdated          = "shop.updated"
+	TopicVoucherStatusChanged = "voucher.status_changed"
+)
+
+type OrderCreatedEvent struct {
+	OrderID   int64  `json:"order_id"`
+	UserID    uint64 `json:"user_id"`
+	VoucherID uint64 `json:"voucher_id"`
+}
+
+type ShopUpdatedEvent struct {
+	ShopID uint64 `json:"shop_id"`
+}
+
+type VoucherStatusChangedEvent struct {
+	VoucherID uint64 `json:"voucher_id"`
+	Status    uint8  `json:"status"`
+}
+
+// newOutboxEvent 构造发件箱记录, 须在业务写入的同一事务中插入, 与业务数据一起提交或回滚
+func newOutboxEvent(topic string, payload any) (*model.TbOutbox, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TbOutbox{Topic: topic, Payload: string(data), Status: OutboxStatusPending}, nil
+}
+
+func addOutboxEvents(ctx context.Context, tx *query.Query, events ...*model.TbOutbox) error {
+	o := tx.TbOutbox
+	return o.WithContext(ctx).Omit(o.SentTime).CreateInBatches(events, len(events))
+}
+
+func addOutboxEvent(ctx context.Context, tx *query.Query, topic string, payload any) error {
+	event, err := newOutboxEvent(topic, payload)
+	if err != nil {
+		return err
+	}
+	return addOutboxEvents(ctx, tx, event)
+}
+
+type OutboxRepo interface {
+	RelayPending(ctx context.Context, limit int, publish func(event *model.TbOutbox) error) (int, error)
+	PurgeSent(ctx context.Context, sentBefore time.Time, limit int) (int64, error)
+}
+
+type outboxRepo struct {
+	q *query.Query
+}
+
+// RelayPending 按 ID 顺序锁定最多 limit 条待发送的事件并逐条调用 publish, 发送成功的标记为已发送, 返回发送成功的数量
+// 使用 SKIP LOCKED, 多个进程同时转发时不会互相等待, 也不会重复发送同一批事件;
+// publish 失败时记录失败次数并停止本轮, 后续事件留到下一轮按顺序发送
+func (r *outboxRepo) RelayPending(ctx context.Context, limit int, publish func(event *model.TbOutbox) error) (int, error) {
+	var sent int
+	var publishErr error
+	err := r.q.Transaction(func(tx *query.Query) error {
+		o := tx.TbOutbox
+		events, err := o.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(o.Status.Eq(OutboxStatusPending)).
+			Order(o.ID).
+			Limit(limit).
+			Find()
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint64, 0, len(events))
+		for _, event := range events {
+			if publishErr = publish(event); publishErr != nil {
+				if _, err = o.WithContext(ctx).Where(o.ID.Eq(event.ID)).UpdateSimple(o.Attempts.Add(1)); err != nil {
+					return err
+				}
+				break
+			}
+			ids = append(ids, event.ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// 已发布但标记失败时事务回滚, 这些事件会被再次发送, 消费方需要按事件 ID 去重
+		_, err = o.WithContext(ctx).Where(o.ID.In(ids...)).
+			UpdateSimple(o.Status.Value(OutboxStatusSent), o.SentTime.Value(time.Now()))
+		if err != nil {
+			return err
+		}
+		sent = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sent, publishErr
+}
+
+// PurgeSent 按 ID 顺序删除最多 limit 条在 sentBefore 之前发送的事件, 返回删除的数量
+func (r *outboxRepo) PurgeSent(ctx context.Context, sentBefore time.Time, limit int) (int64, error) {
+	o := r.q.TbOutbox
+	info, err := o.WithContext(ctx).
+		Where(o.Status.Eq(OutboxStatusSent), o.SentTime.Lt(sentBefore)).
+		Order(o.ID).
+		Limit(limit).
+		Delete()
+	if err != nil {
+		return 0, err
+	}
+	return info.RowsAffected, nil
+}
+
+func NewOutboxRepo(db *gorm.DB) OutboxRepo {
+	return &outboxRepo{
+		q: query.Use(db),
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func outboxRows(ids ...uint64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "topic", "payload", "status", "attempts"})
+	for _, id := range ids {
+		rows.AddRow(id, TopicShopUpdated, `{"shop_id":1}`, OutboxStatusPending, 0)
+	}
+	return rows
+}
+
+func TestRelayPending(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewOutboxRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tb_outbox` WHERE `tb_outbox`.`status` = \\? ORDER BY `tb_outbox`.`id` LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(OutboxStatusPending, 10).
+		WillReturnRows(outboxRows(1, 2))
+	mock.ExpectExec("UPDATE `tb_outbox` SET `status`=\\?,`sent_time`=\\? WHERE `tb_outbox`.`id` IN \\(\\?,\\?\\)").
+		WithArgs(OutboxStatusSent, sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	var published []uint64
+	n, err := repo.RelayPending(context.Background(), 10, func(event *model.TbOutbox) error {
+		published = append(published, event.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []uint64{1, 2}, published)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 发送失败时停止本轮, 已发送的仍标记为已发送, 失败的记录失败次数
+func TestRelayPendingPublishFailed(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewOutboxRepo(db)
+	errPublish := errors.New("broker unavailable")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tb_outbox`").WillReturnRows(outboxRows(1, 2, 3))
+	mock.ExpectExec("UPDATE `tb_outbox` SET `attempts`=`tb_outbox`.`attempts`\\+\\? WHERE `tb_outbox`.`id` = \\?").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `tb_outbox` SET `status`=").
+		WithArgs(OutboxStatusSent, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := repo.RelayPending(context.Background(), 10, func(event *model.TbOutbox) error {
+		if event.ID == 2 {
+			return errPublish
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errPublish)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeSent(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewOutboxRepo(db)
+
+	before := time.Now().Add(-time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `tb_outbox` WHERE `tb_outbox`.`status` = \\? AND `tb_outbox`.`sent_time` < \\? ORDER BY `tb_outbox`.`id` LIMIT \\?").
+		WithArgs(OutboxStatusSent, before, 100).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	n, err := repo.PurgeSent(context.Background(), before, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
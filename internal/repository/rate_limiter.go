@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript 对多个令牌桶同时取令牌, 只有全部桶都有令牌时才扣减
+// KEYS: 令牌桶 key; ARGV: 当前时间(毫秒), 之后每个桶依次为 rate(每秒令牌数), burst(桶容量)
+// 返回 {是否允许, 需要等待的毫秒数}
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local allowed = 1
+local wait = 0
+local buckets = {}
+
+for i, key in ipairs(KEYS) do
+    local rate = tonumber(ARGV[i * 2])
+    local burst = tonumber(ARGV[i * 2 + 1])
+    local bucket = redis.call('hmget', key, 'tokens', 'ts')
+    local tokens = tonumber(bucket[1])
+    local ts = tonumber(bucket[2])
+    if(tokens == nil or ts == nil) then
+        tokens = burst
+        ts = now
+    end
+    -- 按流逝的时间补充令牌, 不超过桶容量
+    tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+    if(tokens < 1) then
+        allowed = 0
+        wait = math.max(wait, math.ceil((1 - tokens) * 1000 / rate))
+    end
+    buckets[i] = {key, tokens, math.ceil(burst * 1000 / rate) + 1000}
+end
+
+for _, b in ipairs(buckets) do
+    local tokens = b[2]
+    if(allowed == 1) then
+        tokens = tokens - 1
+    end
+    redis.call('hset', b[1], 'tokens', tostring(tokens), 'ts', now)
+    -- 桶回满后即可过期, 避免残留大量 key
+    redis.call('pexpire', b[1], b[3])
+end
+return {allowed, wait}
+`)
+
+// Bucket 令牌桶定义, Rate 为每秒生成的令牌数, Burst 为桶容量
+type Bucket struct {
+	Key   string
+	Rate  float64
+	Burst int64
+}
+
+type RateLimiter interface {
+	// Allow 从所有令牌桶中各取一个令牌, 任一令牌桶不足时不扣减并返回需要等待的时间
+	Allow(ctx context.Context, buckets ...Bucket) (bool, time.Duration, error)
+}
+
+type rateLimiter struct {
+	rdb *redis.Client
+}
+
+func (r *rateLimiter) Allow(ctx context.Context, buckets ...Bucket) (bool, time.Duration, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]any, 0, len(buckets)*2+1)
+	args = append(args, time.Now().UnixMilli())
+	for _, b := range buckets {
+		if b.Rate <= 0 || b.Burst <= 0 {
+			continue // 未配置的桶不限流
+		}
+		keys = append(keys, b.Key)
+		args = append(args, b.Rate, b.Burst)
+	}
+	if len(keys) == 0 {
+		return true, 0, nil
+	}
+
+	res, err := tokenBucketScript.Run(ctx, r.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected result from token bucket script: %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+func NewRateLimiter(rdb *redis.Client) RateLimiter {
+	return &rateLimiter{
+		rdb: rdb,
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRateLimiter(t *testing.T) (RateLimiter, *miniredis.Miniredis) {
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return NewRateLimiter(rdb), m
+}
+
+// 桶满时可以连续取 burst 个令牌, 之后拒绝并返回补充一个令牌需要等待的时间, 等待后按 rate 补充
+func TestRateLimiterBurstRejectRefill(t *testing.T) {
+	limiter, _ := setupRateLimiter(t)
+	ctx := context.Background()
+	bucket := Bucket{Key: "limit:test", Rate: 10, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := limiter.Allow(ctx, bucket)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, wait, err := limiter.Allow(ctx, bucket)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, 100*time.Millisecond)
+
+	time.Sleep(wait + 20*time.Millisecond)
+	ok, _, err = limiter.Allow(ctx, bucket)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, _, err = limiter.Allow(ctx, bucket)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// 任一桶没有令牌时所有桶都不扣减
+func TestRateLimiterMultipleBuckets(t *testing.T) {
+	limiter, m := setupRateLimiter(t)
+	ctx := context.Background()
+	global := Bucket{Key: "limit:global", Rate: 1, Burst: 5}
+	user := Bucket{Key: "limit:user:1", Rate: 1, Burst: 1}
+
+	ok, _, err := limiter.Allow(ctx, global, user)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, wait, err := limiter.Allow(ctx, global, user)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Greater(t, wait, 900*time.Millisecond)
+
+	tokens, err := strconv.ParseFloat(m.HGet("limit:global", "tokens"), 64)
+	require.NoError(t, err)
+	assert.InDelta(t, 4, tokens, 0.1)
+	assert.Greater(t, m.TTL("limit:global"), time.Duration(0))
+}
+
+// 未配置的桶不限流, 也不写入 Redis
+func TestRateLimiterUnconfigured(t *testing.T) {
+	limiter, m := setupRateLimiter(t)
+
+	ok, wait, err := limiter.Allow(context.Background(), Bucket{Key: "limit:off"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+	assert.False(t, m.Exists("limit:off"))
+}
@@ -109,10 +109,15 @@ func (r *shopRepo) SetShopTypeListCache(ctx context.Context, types []*model.TbSh
 	return err
 }
 
+// UpdateShop 更新商铺信息, 同一事务中写入商铺变更事件
 func (r *shopRepo) UpdateShop(ctx context.Context, shop *model.TbShop) error {
-	s := r.q.TbShop
-	_, err := s.WithContext(ctx).Where(s.ID.Eq(shop.ID)).Updates(shop)
-	return err
+	return r.q.Transaction(func(tx *query.Query) error {
+		s := tx.TbShop
+		if _, err := s.WithContext(ctx).Where(s.ID.Eq(shop.ID)).Updates(shop); err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, tx, TopicShopUpdated, ShopUpdatedEvent{ShopID: shop.ID})
+	})
 }
 
 func (r *shopRepo) DeleteShopCache(ctx context.Context, id uint64) error {
@@ -11,6 +11,7 @@ import (
 type UserRepo interface {
 	FindByPhone(ctx context.Context, phone string) (*model.TbUser, error)
 	Create(ctx context.Context, user *model.TbUser) error
+	FindByIDs(ctx context.Context, ids []uint64) ([]*model.TbUser, error)
 }
 
 func NewUserRepo(db *gorm.DB) UserRepo {
@@ -29,3 +30,8 @@ func (r *userRepo) FindByPhone(ctx context.Context, phone string) (*model.TbUser
 func (r *userRepo) Create(ctx context.Context, user *model.TbUser) error {
 	return r.q.TbUser.WithContext(ctx).Create(user)
 }
+
+func (r *userRepo) FindByIDs(ctx context.Context, ids []uint64) ([]*model.TbUser, error) {
+	u := r.q.TbUser
+	return u.WithContext(ctx).Where(u.ID.In(ids...)).Find()
+}
@@ -2,18 +2,113 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"strconv"
+	"time"
 
+	"github.com/hmmm42/city-picks/dal/model"
 	"github.com/hmmm42/city-picks/dal/query"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gen/field"
 	"gorm.io/gorm"
 )
 
+// 订单状态, 对应 tb_voucher_order.status
+const (
+	OrderStatusUnpaid    uint8 = 1 // 未支付
+	OrderStatusPaid      uint8 = 2 // 已支付
+	OrderStatusUsed      uint8 = 3 // 已核销
+	OrderStatusCancelled uint8 = 4 // 已取消
+	OrderStatusRefunding uint8 = 5 // 退款中
+	OrderStatusRefunded  uint8 = 6 // 已退款
+)
+
+// UnpaidOrderDelayKey 未支付订单的延时队列, member 为订单 ID, score 为支付截止时间(毫秒)
+const UnpaidOrderDelayKey = "order:unpaid:delay"
+
+// claimExpiredScript 原子地取出已到期的订单, 并把 score 推迟到租约到期时间, 租约内其他实例不会再取到;
+// 订单取消成功后才从延时队列删除, 取消失败或实例崩溃时租约到期后重新取出
+// KEYS[1]: 延时队列; ARGV: 当前时间(毫秒), 租约到期时间(毫秒), 最多取出的数量
+var claimExpiredScript = redis.NewScript(`
+local ids = redis.call('zrangebyscore', KEYS[1], '-inf', ARGV[1], 'limit', 0, tonumber(ARGV[3]))
+for _, id in ipairs(ids) do
+    redis.call('zadd', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+// consumeRedeemNonceScript 核销码的 nonce 与当前保存的一致时删除并返回 1, 保证核销码只能使用一次
+var consumeRedeemNonceScript = redis.NewScript(`
+if(redis.call('get', KEYS[1]) == ARGV[1]) then
+    redis.call('del', KEYS[1])
+    return 1
+end
+return 0
+`)
+
+// releaseLockScript 锁的值与持有者标识一致时才删除, 避免锁过期后误删其他请求持有的锁
+var releaseLockScript = redis.NewScript(`
+if(redis.call('get', KEYS[1]) == ARGV[1]) then
+    return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// setExistingStatusScript 状态 key 存在时才更新 status 字段, 避免 key 过期后写入只有 status 的残缺记录
+var setExistingStatusScript = redis.NewScript(`
+if(redis.call('exists', KEYS[1]) == 1) then
+    return redis.call('hset', KEYS[1], 'status', ARGV[1])
+end
+return 0
+`)
+
+// ErrOrderStatusChanged 订单状态已被并发修改
+var ErrOrderStatusChanged = errors.New("order status has been changed")
+
+// 秒杀订单的异步创建状态, 由 Lua 脚本写入 pending, 消费者落库后更新
+const (
+	SeckillOrderPending = "pending"
+	SeckillOrderCreated = "created"
+	SeckillOrderFailed  = "failed"
+
+	// 订单状态 key 的有效期, 过期后以 MySQL 为准
+	SeckillOrderStatusTTL = 24 * time.Hour
+)
+
+// SeckillOrderStatus 保存在 Redis 中的订单创建状态, 以及下单时的优惠券快照
+type SeckillOrderStatus struct {
+	Status      string
+	UserID      uint64
+	VoucherID   uint64
+	ShopID      uint64
+	Title       string
+	PayValue    uint64
+	ActualValue int64
+}
+
 type VoucherOrderRepo interface {
 	HasUserPurchasedVoucher(ctx context.Context, voucherID, userID uint64) (bool, error)
+	GetVoucherOrderByID(ctx context.Context, orderID int64) (*model.TbVoucherOrder, error)
+	ListExistingOrderIDs(ctx context.Context, orderIDs []int64) ([]int64, error)
+	CountActiveOrders(ctx context.Context, voucherID uint64) (int64, error)
+	GetSeckillOrderStatus(ctx context.Context, orderID int64) (*SeckillOrderStatus, error)
+	SetSeckillOrderStatus(ctx context.Context, orderID int64, status string) error
+	UpdateOrderStatus(ctx context.Context, order *model.TbVoucherOrder, from uint8) error
+	UpdateOrderStatusAndReturnStock(ctx context.Context, order *model.TbVoucherOrder, from uint8) error
+	AddUnpaidOrder(ctx context.Context, orderID int64, deadline time.Time) error
+	RemoveUnpaidOrder(ctx context.Context, orderID int64) error
+	ClaimExpiredUnpaidOrders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]int64, error)
+	ListExpiredUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, afterID int64, limit int) ([]int64, error)
+	LockOrderPayment(ctx context.Context, orderID int64, token string, ttl time.Duration) (bool, error)
+	UnlockOrderPayment(ctx context.Context, orderID int64, token string) error
+	SetRedeemNonce(ctx context.Context, orderID int64, nonce string, ttl time.Duration) error
+	ConsumeRedeemNonce(ctx context.Context, orderID int64, nonce string) (bool, error)
 }
 
 type voucherOrderRepo struct {
 	q      *query.Query
+	rdb    *redis.Client
 	logger *slog.Logger
 }
 
@@ -29,9 +124,191 @@ func (r *voucherOrderRepo) HasUserPurchasedVoucher(ctx context.Context, voucherI
 	return count > 0, nil
 }
 
-func NewVoucherOrderRepo(db *gorm.DB, logger *slog.Logger) VoucherOrderRepo {
+func (r *voucherOrderRepo) GetVoucherOrderByID(ctx context.Context, orderID int64) (*model.TbVoucherOrder, error) {
+	return r.q.TbVoucherOrder.WithContext(ctx).Where(r.q.TbVoucherOrder.ID.Eq(orderID)).First()
+}
+
+// ListExistingOrderIDs 返回 orderIDs 中已落库的订单 ID
+func (r *voucherOrderRepo) ListExistingOrderIDs(ctx context.Context, orderIDs []int64) ([]int64, error) {
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+	o := r.q.TbVoucherOrder
+	var ids []int64
+	err := o.WithContext(ctx).Where(o.ID.In(orderIDs...)).Pluck(o.ID, &ids)
+	return ids, err
+}
+
+// CountActiveOrders 统计占用库存的订单数, 与 active 生成列一致: 已取消和已退款的订单已归还库存, 不计算在内,
+// 退款中的订单尚未归还库存, 仍计算在内
+func (r *voucherOrderRepo) CountActiveOrders(ctx context.Context, voucherID uint64) (int64, error) {
+	o := r.q.TbVoucherOrder
+	return o.WithContext(ctx).Where(
+		o.VoucherID.Eq(voucherID),
+		o.Status.In(OrderStatusUnpaid, OrderStatusPaid, OrderStatusUsed, OrderStatusRefunding),
+	).Count()
+}
+
+// UpdateOrderStatus 仅当订单仍处于 from 状态时, 更新状态、支付方式和各时间字段
+func (r *voucherOrderRepo) UpdateOrderStatus(ctx context.Context, order *model.TbVoucherOrder, from uint8) error {
+	return updateOrderStatus(ctx, r.q, order, from)
+}
+
+// UpdateOrderStatusAndReturnStock 在同一事务中更新订单状态并归还 MySQL 库存
+func (r *voucherOrderRepo) UpdateOrderStatusAndReturnStock(ctx context.Context, order *model.TbVoucherOrder, from uint8) error {
+	return r.q.Transaction(func(tx *query.Query) error {
+		if err := updateOrderStatus(ctx, tx, order, from); err != nil {
+			return err
+		}
+		_, err := tx.TbSeckillVoucher.WithContext(ctx).
+			Where(tx.TbSeckillVoucher.VoucherID.Eq(order.VoucherID)).
+			UpdateSimple(tx.TbSeckillVoucher.Stock.Add(1))
+		return err
+	})
+}
+
+func updateOrderStatus(ctx context.Context, q *query.Query, order *model.TbVoucherOrder, from uint8) error {
+	o := q.TbVoucherOrder
+	order.UpdateTime = time.Now()
+	// 只更新已设置的时间字段, 零值时间不能写入 timestamp 列
+	columns := []field.AssignExpr{
+		o.Status.Value(order.Status),
+		o.PayType.Value(order.PayType),
+		o.UpdateTime.Value(order.UpdateTime),
+	}
+	if !order.PayTime.IsZero() {
+		columns = append(columns, o.PayTime.Value(order.PayTime))
+	}
+	if !order.UseTime.IsZero() {
+		columns = append(columns, o.UseTime.Value(order.UseTime))
+	}
+	if !order.RefundTime.IsZero() {
+		columns = append(columns, o.RefundTime.Value(order.RefundTime))
+	}
+
+	info, err := o.WithContext(ctx).
+		Where(o.ID.Eq(order.ID), o.Status.Eq(from)).
+		UpdateSimple(columns...)
+	if err != nil {
+		return err
+	}
+	if info.RowsAffected == 0 {
+		return ErrOrderStatusChanged
+	}
+	return nil
+}
+
+// GetSeckillOrderStatus 状态 key 不存在时返回 redis.Nil
+func (r *voucherOrderRepo) GetSeckillOrderStatus(ctx context.Context, orderID int64) (*SeckillOrderStatus, error) {
+	values, err := r.rdb.HGetAll(ctx, getSeckillOrderStatusKey(orderID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, redis.Nil
+	}
+	userID, _ := strconv.ParseUint(values["userID"], 10, 64)
+	voucherID, _ := strconv.ParseUint(values["voucherID"], 10, 64)
+	shopID, _ := strconv.ParseUint(values["shop_id"], 10, 64)
+	payValue, _ := strconv.ParseUint(values["pay_value"], 10, 64)
+	actualValue, _ := strconv.ParseInt(values["actual_value"], 10, 64)
+	return &SeckillOrderStatus{
+		Status:      values["status"],
+		UserID:      userID,
+		VoucherID:   voucherID,
+		ShopID:      shopID,
+		Title:       values["title"],
+		PayValue:    payValue,
+		ActualValue: actualValue,
+	}, nil
+}
+
+// SetSeckillOrderStatus 仅更新已存在的状态 key, key 已过期时以 MySQL 为准
+func (r *voucherOrderRepo) SetSeckillOrderStatus(ctx context.Context, orderID int64, status string) error {
+	return setExistingStatusScript.Run(ctx, r.rdb, []string{getSeckillOrderStatusKey(orderID)}, status).Err()
+}
+
+func (r *voucherOrderRepo) AddUnpaidOrder(ctx context.Context, orderID int64, deadline time.Time) error {
+	return r.rdb.ZAdd(ctx, UnpaidOrderDelayKey, redis.Z{
+		Score:  float64(deadline.UnixMilli()),
+		Member: orderID,
+	}).Err()
+}
+
+func (r *voucherOrderRepo) RemoveUnpaidOrder(ctx context.Context, orderID int64) error {
+	return r.rdb.ZRem(ctx, UnpaidOrderDelayKey, orderID).Err()
+}
+
+// ClaimExpiredUnpaidOrders 取出支付截止时间早于 now 的订单, 订单在 lease 时长内不会被再次取出,
+// 调用方取消成功后需调用 RemoveUnpaidOrder 删除
+func (r *voucherOrderRepo) ClaimExpiredUnpaidOrders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]int64, error) {
+	members, err := claimExpiredScript.Run(ctx, r.rdb, []string{UnpaidOrderDelayKey},
+		now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			r.logger.Error("invalid order id in unpaid delay queue", "member", m)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ListExpiredUnpaidOrderIDs 按 ID 升序返回下单时间早于 createdBefore 且仍未支付的订单, 用于兜底扫描
+func (r *voucherOrderRepo) ListExpiredUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, afterID int64, limit int) ([]int64, error) {
+	o := r.q.TbVoucherOrder
+	var ids []int64
+	err := o.WithContext(ctx).Where(
+		o.Status.Eq(OrderStatusUnpaid),
+		o.CreateTime.Lt(createdBefore),
+		o.ID.Gt(afterID),
+	).Order(o.ID).Limit(limit).Pluck(o.ID, &ids)
+	return ids, err
+}
+
+// LockOrderPayment 获取订单的支付锁, 同一订单同时只能有一个支付请求扣款; token 为持有者标识
+func (r *voucherOrderRepo) LockOrderPayment(ctx context.Context, orderID int64, token string, ttl time.Duration) (bool, error) {
+	return r.rdb.SetNX(ctx, getOrderPayLockKey(orderID), token, ttl).Result()
+}
+
+func (r *voucherOrderRepo) UnlockOrderPayment(ctx context.Context, orderID int64, token string) error {
+	return releaseLockScript.Run(ctx, r.rdb, []string{getOrderPayLockKey(orderID)}, token).Err()
+}
+
+// SetRedeemNonce 保存订单最新核销码的 nonce, 重新生成核销码会使旧的核销码失效
+func (r *voucherOrderRepo) SetRedeemNonce(ctx context.Context, orderID int64, nonce string, ttl time.Duration) error {
+	return r.rdb.Set(ctx, getRedeemNonceKey(orderID), nonce, ttl).Err()
+}
+
+func (r *voucherOrderRepo) ConsumeRedeemNonce(ctx context.Context, orderID int64, nonce string) (bool, error) {
+	res, err := consumeRedeemNonceScript.Run(ctx, r.rdb, []string{getRedeemNonceKey(orderID)}, nonce).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+func getRedeemNonceKey(orderID int64) string {
+	return "order:redeem:nonce:" + strconv.FormatInt(orderID, 10)
+}
+
+func getOrderPayLockKey(orderID int64) string {
+	return "order:pay:lock:" + strconv.FormatInt(orderID, 10)
+}
+
+func getSeckillOrderStatusKey(orderID int64) string {
+	return "seckill:order:status:" + strconv.FormatInt(orderID, 10)
+}
+
+func NewVoucherOrderRepo(db *gorm.DB, rdb *redis.Client, logger *slog.Logger) VoucherOrderRepo {
 	return &voucherOrderRepo{
 		q:      query.Use(db),
+		rdb:    rdb,
 		logger: logger,
 	}
 }
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 取出的订单在租约内不会被再次取出, 租约到期前未删除则重新取出
+func TestClaimExpiredUnpaidOrders(t *testing.T) {
+	db, _ := newMockDB(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	repo := NewVoucherOrderRepo(db, rdb, slog.Default())
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, repo.AddUnpaidOrder(ctx, 1, now.Add(-time.Second)))
+	require.NoError(t, repo.AddUnpaidOrder(ctx, 2, now.Add(-time.Second)))
+	require.NoError(t, repo.AddUnpaidOrder(ctx, 3, now.Add(time.Hour)))
+
+	ids, err := repo.ClaimExpiredUnpaidOrders(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{1, 2}, ids)
+
+	// 取出后仍保留在队列中, 租约内不会被其他实例取到
+	members, err := rdb.ZCard(ctx, UnpaidOrderDelayKey).Result()
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, members)
+	ids, err = repo.ClaimExpiredUnpaidOrders(ctx, now.Add(30*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// 订单 1 取消成功后删除, 订单 2 租约到期后重新取出
+	require.NoError(t, repo.RemoveUnpaidOrder(ctx, 1))
+	ids, err = repo.ClaimExpiredUnpaidOrders(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+}
+
+func TestListExpiredUnpaidOrderIDs(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewVoucherOrderRepo(db, nil, slog.Default())
+	before := time.Now()
+
+	mock.ExpectQuery("SELECT `id` FROM `tb_voucher_order` WHERE `tb_voucher_order`.`status` = \\? AND `tb_voucher_order`.`create_time` < \\? AND `tb_voucher_order`.`id` > \\? ORDER BY `tb_voucher_order`.`id` LIMIT \\?").
+		WithArgs(OrderStatusUnpaid, before, int64(100), 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(101).AddRow(105))
+
+	ids, err := repo.ListExpiredUnpaidOrderIDs(context.Background(), before, 100, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{101, 105}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 退款中的订单尚未归还库存, 与 active 生成列一致计入有效订单
+func TestCountActiveOrders(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewVoucherOrderRepo(db, nil, slog.Default())
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `tb_voucher_order` WHERE `tb_voucher_order`.`voucher_id` = \\? AND `tb_voucher_order`.`status` IN \\(\\?,\\?,\\?,\\?\\)").
+		WithArgs(uint64(2), OrderStatusUnpaid, OrderStatusPaid, OrderStatusUsed, OrderStatusRefunding).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	count, err := repo.CountActiveOrders(context.Background(), 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 状态 key 已过期时不写入, 轮询以 MySQL 为准
+func TestSetSeckillOrderStatus(t *testing.T) {
+	db, _ := newMockDB(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	repo := NewVoucherOrderRepo(db, rdb, slog.Default())
+	ctx := context.Background()
+
+	mr.HSet(getSeckillOrderStatusKey(1), "status", SeckillOrderPending, "userID", "2", "voucherID", "3",
+		"shop_id", "4", "title", "50元代金券", "pay_value", "4750", "actual_value", "5000")
+	require.NoError(t, repo.SetSeckillOrderStatus(ctx, 1, SeckillOrderCreated))
+	status, err := repo.GetSeckillOrderStatus(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &SeckillOrderStatus{
+		Status: SeckillOrderCreated, UserID: 2, VoucherID: 3,
+		ShopID: 4, Title: "50元代金券", PayValue: 4750, ActualValue: 5000,
+	}, status)
+
+	require.NoError(t, repo.SetSeckillOrderStatus(ctx, 2, SeckillOrderFailed))
+	assert.False(t, mr.Exists(getSeckillOrderStatusKey(2)))
+}
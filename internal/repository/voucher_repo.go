@@ -5,19 +5,77 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/hmmm42/city-picks/dal/model"
 	"github.com/hmmm42/city-picks/dal/query"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 优惠券状态, 对应 tb_voucher.status
+const (
+	VoucherStatusOnline  uint8 = 1 // 上架
+	VoucherStatusOffline uint8 = 2 // 下架
+	VoucherStatusExpired uint8 = 3 // 过期
+)
+
+// VoucherTypeSeckill 秒杀券, 对应 tb_voucher.type
+const VoucherTypeSeckill uint8 = 1
+
+// 秒杀模式, 对应 tb_seckill_voucher.mode
+const (
+	SeckillModeFirstCome uint8 = 0 // 先到先得
+	SeckillModeLottery   uint8 = 1 // 预约抽签
+)
+
+var (
+	ErrStockInsufficient = errors.New("stock insufficient")
+	// ErrOrderExists 订单 ID 已存在, 说明同一条订单消息被重复消费
+	ErrOrderExists = errors.New("voucher order already exists")
+	// ErrUserOrderExists 用户已持有该券的有效订单
+	ErrUserOrderExists = errors.New("user already has an active order for this voucher")
+)
+
+var compareAndSetStockScript = redis.NewScript(`
+if(redis.call('get', KEYS[1]) == ARGV[1]) then
+    redis.call('set', KEYS[1], ARGV[2])
+    return 1
+end
+return 0
+`)
+
+// mysqlErrDuplicateEntry 唯一键冲突的 MySQL 错误码
+const mysqlErrDuplicateEntry = 1062
+
+func isDuplicateKeyErr(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
 type VoucherRepo interface {
 	CreateVoucher(ctx context.Context, voucher *model.TbVoucher) error
 	CreateSeckillVoucher(ctx context.Context, voucher *model.TbVoucher, seckillVoucher *model.TbSeckillVoucher) error
+	GetVoucherByID(ctx context.Context, voucherID uint64) (*model.TbVoucher, error)
 	GetSeckillVoucherByID(ctx context.Context, voucherID uint64) (*model.TbSeckillVoucher, error)
+	UpdateVoucher(ctx context.Context, voucher *model.TbVoucher) error
+	UpdateVoucherStatus(ctx context.Context, voucherID uint64, status uint8) error
+	UpdateSeckillWindow(ctx context.Context, voucherID uint64, begin, end time.Time) error
+	ListExpiredSeckillVoucherIDs(ctx context.Context, now time.Time) ([]uint64, error)
+	ListOnlineSeckillVoucherIDs(ctx context.Context) ([]uint64, error)
 	CreateVoucherOrderAndReduceStock(ctx context.Context, order *model.TbVoucherOrder) error
+	CreateVoucherOrdersAndReduceStock(ctx context.Context, orders []*model.TbVoucherOrder) error
+	AdjustSeckillStock(ctx context.Context, stockLog *model.TbSeckillStockLog) error
+	ListSeckillStockLogs(ctx context.Context, voucherID uint64) ([]*model.TbSeckillStockLog, error)
 	SetVoucherStockCache(ctx context.Context, voucher *model.TbSeckillVoucher) error
+	GetVoucherStockCache(ctx context.Context, voucherID uint64) (int64, error)
+	CompareAndSetVoucherStockCache(ctx context.Context, voucherID uint64, old, stock int64) (bool, error)
+	CountSeckillOrderUsers(ctx context.Context, voucherID uint64) (int64, error)
+	SetSeckillInfoCache(ctx context.Context, voucher *model.TbVoucher, seckillVoucher *model.TbSeckillVoucher) error
+	SetSeckillStatusCache(ctx context.Context, voucherID uint64, status uint8) error
+	DeleteSeckillCache(ctx context.Context, voucherID uint64) error
 	ExecScript(ctx context.Context, script string, keys []string, args ...any) (int64, error)
 }
 
@@ -43,42 +101,236 @@ func (r *voucherRepo) CreateSeckillVoucher(ctx context.Context, voucher *model.T
 	})
 }
 
+func (r *voucherRepo) GetVoucherByID(ctx context.Context, voucherID uint64) (*model.TbVoucher, error) {
+	return r.q.TbVoucher.WithContext(ctx).Where(r.q.TbVoucher.ID.Eq(voucherID)).First()
+}
+
 func (r *voucherRepo) GetSeckillVoucherByID(ctx context.Context, voucherID uint64) (*model.TbSeckillVoucher, error) {
 	return r.q.TbSeckillVoucher.WithContext(ctx).Where(r.q.TbSeckillVoucher.VoucherID.Eq(voucherID)).First()
 }
 
-func (r *voucherRepo) CreateVoucherOrderAndReduceStock(ctx context.Context, order *model.TbVoucherOrder) error {
+// UpdateVoucher 只更新 voucher 中的非零值字段
+func (r *voucherRepo) UpdateVoucher(ctx context.Context, voucher *model.TbVoucher) error {
+	v := r.q.TbVoucher
+	_, err := v.WithContext(ctx).Where(v.ID.Eq(voucher.ID)).Updates(voucher)
+	return err
+}
+
+// UpdateVoucherStatus 更新优惠券状态, 同一事务中写入状态变更事件
+func (r *voucherRepo) UpdateVoucherStatus(ctx context.Context, voucherID uint64, status uint8) error {
 	return r.q.Transaction(func(tx *query.Query) error {
-		info, err := tx.TbSeckillVoucher.WithContext(ctx).Where(
-			tx.TbSeckillVoucher.VoucherID.Eq(order.VoucherID),
-			tx.TbSeckillVoucher.Stock.Gt(0),
-		).UpdateSimple(tx.TbSeckillVoucher.Stock.Add(-1))
+		v := tx.TbVoucher
+		if _, err := v.WithContext(ctx).Where(v.ID.Eq(voucherID)).Update(v.Status, status); err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, tx, TopicVoucherStatusChanged, VoucherStatusChangedEvent{VoucherID: voucherID, Status: status})
+	})
+}
+
+func (r *voucherRepo) UpdateSeckillWindow(ctx context.Context, voucherID uint64, begin, end time.Time) error {
+	sv := r.q.TbSeckillVoucher
+	_, err := sv.WithContext(ctx).Where(sv.VoucherID.Eq(voucherID)).
+		UpdateSimple(sv.BeginTime.Value(begin), sv.EndTime.Value(end))
+	return err
+}
+
+// ListExpiredSeckillVoucherIDs 查询已过结束时间但尚未标记为过期的秒杀券
+func (r *voucherRepo) ListExpiredSeckillVoucherIDs(ctx context.Context, now time.Time) ([]uint64, error) {
+	v, sv := r.q.TbVoucher, r.q.TbSeckillVoucher
+	var ids []uint64
+	err := v.WithContext(ctx).
+		Join(sv, sv.VoucherID.EqCol(v.ID)).
+		Where(sv.EndTime.Lt(now), v.Status.Neq(VoucherStatusExpired)).
+		Pluck(v.ID, &ids)
+	return ids, err
+}
+
+func (r *voucherRepo) ListOnlineSeckillVoucherIDs(ctx context.Context) ([]uint64, error) {
+	v, sv := r.q.TbVoucher, r.q.TbSeckillVoucher
+	var ids []uint64
+	err := v.WithContext(ctx).
+		Join(sv, sv.VoucherID.EqCol(v.ID)).
+		Where(v.Status.Eq(VoucherStatusOnline)).
+		Pluck(v.ID, &ids)
+	return ids, err
+}
+
+// CreateVoucherOrderAndReduceStock 以订单 ID 幂等地创建订单并扣减库存
+// 先插入订单, 主键冲突时直接返回 ErrOrderExists, 保证重复消费不会二次扣减库存; 下单事件与订单在同一事务中写入发件箱
+func (r *voucherRepo) CreateVoucherOrderAndReduceStock(ctx context.Context, order *model.TbVoucherOrder) error {
+	err := r.q.Transaction(func(tx *query.Query) error {
+		o := tx.TbVoucherOrder
+		if err := o.WithContext(ctx).
+			Omit(o.PayTime, o.UseTime, o.RefundTime).
+			Create(order); err != nil {
+			return err
+		}
+
+		sv := tx.TbSeckillVoucher
+		info, err := sv.WithContext(ctx).Where(
+			sv.VoucherID.Eq(order.VoucherID),
+			sv.Stock.Gt(0),
+		).UpdateSimple(sv.Stock.Add(-1))
 		if err != nil {
 			return err
 		}
 		if info.RowsAffected == 0 {
-			return errors.New("row not found or stock insufficient")
+			return fmt.Errorf("voucher %d: %w", order.VoucherID, ErrStockInsufficient)
+		}
+		return addOutboxEvent(ctx, tx, TopicOrderCreated, orderCreatedEvent(order))
+	})
+	if !isDuplicateKeyErr(err) {
+		return err
+	}
+
+	// 唯一键冲突可能来自主键或 (user_id, voucher_id), 按订单 ID 区分
+	o := r.q.TbVoucherOrder
+	n, countErr := o.WithContext(ctx).Where(o.ID.Eq(order.ID)).Count()
+	if countErr != nil {
+		return errors.Join(err, countErr)
+	}
+	if n > 0 {
+		return ErrOrderExists
+	}
+	return fmt.Errorf("%w: %w", ErrUserOrderExists, err)
+}
+
+// CreateVoucherOrdersAndReduceStock 在同一事务中批量创建订单, 每张券的库存只扣减一次; 任一订单失败则全部回滚
+func (r *voucherRepo) CreateVoucherOrdersAndReduceStock(ctx context.Context, orders []*model.TbVoucherOrder) error {
+	counts := make(map[uint64]int64)
+	for _, order := range orders {
+		counts[order.VoucherID]++
+	}
+
+	return r.q.Transaction(func(tx *query.Query) error {
+		sv := tx.TbSeckillVoucher
+		for voucherID, n := range counts {
+			info, err := sv.WithContext(ctx).Where(
+				sv.VoucherID.Eq(voucherID),
+				sv.Stock.Gte(n),
+			).UpdateSimple(sv.Stock.Add(-n))
+			if err != nil {
+				return err
+			}
+			if info.RowsAffected == 0 {
+				return fmt.Errorf("voucher %d: %w", voucherID, ErrStockInsufficient)
+			}
 		}
 
-		o := r.q.TbVoucherOrder
-		return tx.TbVoucherOrder.WithContext(ctx).
+		o := tx.TbVoucherOrder
+		if err := o.WithContext(ctx).
 			Omit(o.PayTime, o.UseTime, o.RefundTime).
-			Create(order)
+			CreateInBatches(orders, len(orders)); err != nil {
+			return err
+		}
+
+		events := make([]*model.TbOutbox, 0, len(orders))
+		for _, order := range orders {
+			event, err := newOutboxEvent(TopicOrderCreated, orderCreatedEvent(order))
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return addOutboxEvents(ctx, tx, events...)
 	})
 }
 
+func orderCreatedEvent(order *model.TbVoucherOrder) OrderCreatedEvent {
+	return OrderCreatedEvent{OrderID: order.ID, UserID: order.UserID, VoucherID: order.VoucherID}
+}
+
+// AdjustSeckillStock 在同一事务中调整 MySQL 库存并写入审计日志, 调整后库存不能小于 0
+func (r *voucherRepo) AdjustSeckillStock(ctx context.Context, stockLog *model.TbSeckillStockLog) error {
+	return r.q.Transaction(func(tx *query.Query) error {
+		sv := tx.TbSeckillVoucher
+		seckillVoucher, err := sv.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(sv.VoucherID.Eq(stockLog.VoucherID)).
+			First()
+		if err != nil {
+			return err
+		}
+		if seckillVoucher.Stock+stockLog.Delta < 0 {
+			return ErrStockInsufficient
+		}
+
+		_, err = sv.WithContext(ctx).Where(sv.VoucherID.Eq(stockLog.VoucherID)).
+			UpdateSimple(sv.Stock.Add(stockLog.Delta))
+		if err != nil {
+			return err
+		}
+
+		stockLog.StockBefore = seckillVoucher.Stock
+		stockLog.StockAfter = seckillVoucher.Stock + stockLog.Delta
+		return tx.TbSeckillStockLog.WithContext(ctx).Create(stockLog)
+	})
+}
+
+func (r *voucherRepo) ListSeckillStockLogs(ctx context.Context, voucherID uint64) ([]*model.TbSeckillStockLog, error) {
+	l := r.q.TbSeckillStockLog
+	return l.WithContext(ctx).Where(l.VoucherID.Eq(voucherID)).Order(l.ID.Desc()).Find()
+}
+
 func getVoucherKey(voucherID uint64) string {
 	return fmt.Sprintf("seckill:stock:%d", voucherID)
 }
 
+func getVoucherOrderKey(voucherID uint64) string {
+	return fmt.Sprintf("seckill:order:%d", voucherID)
+}
+
+// getVoucherInfoKey 秒杀 Lua 脚本据此判断优惠券状态与时间窗口
+func getVoucherInfoKey(voucherID uint64) string {
+	return fmt.Sprintf("seckill:info:%d", voucherID)
+}
+
 func (r *voucherRepo) SetVoucherStockCache(ctx context.Context, voucher *model.TbSeckillVoucher) error {
 	return r.rdb.Set(ctx, getVoucherKey(voucher.VoucherID), voucher.Stock, 0).Err()
 }
 
+// SetSeckillInfoCache 写入秒杀状态与时间窗口, 以及秒杀脚本随订单事件发送的优惠券快照
+func (r *voucherRepo) SetSeckillInfoCache(ctx context.Context, voucher *model.TbVoucher, seckillVoucher *model.TbSeckillVoucher) error {
+	return r.rdb.HSet(ctx, getVoucherInfoKey(voucher.ID),
+		"status", voucher.Status,
+		"begin", seckillVoucher.BeginTime.Unix(),
+		"end", seckillVoucher.EndTime.Unix(),
+		"mode", seckillVoucher.Mode,
+		"shop_id", voucher.ShopID,
+		"title", voucher.Title,
+		"pay_value", voucher.PayValue,
+		"actual_value", voucher.ActualValue,
+	).Err()
+}
+
+func (r *voucherRepo) SetSeckillStatusCache(ctx context.Context, voucherID uint64, status uint8) error {
+	return r.rdb.HSet(ctx, getVoucherInfoKey(voucherID), "status", status).Err()
+}
+
+// DeleteSeckillCache 删除秒杀相关的库存、一人一单集合与状态缓存
+func (r *voucherRepo) DeleteSeckillCache(ctx context.Context, voucherID uint64) error {
+	return r.rdb.Del(ctx,
+		getVoucherKey(voucherID),
+		getVoucherOrderKey(voucherID),
+		getVoucherInfoKey(voucherID),
+	).Err()
+}
+
 func (r *voucherRepo) GetVoucherStockCache(ctx context.Context, voucherID uint64) (int64, error) {
 	return r.rdb.Get(ctx, getVoucherKey(voucherID)).Int64()
 }
 
+// CompareAndSetVoucherStockCache 仅当 Redis 库存仍为 old 时改为 stock, 避免覆盖并发的扣减
+func (r *voucherRepo) CompareAndSetVoucherStockCache(ctx context.Context, voucherID uint64, old, stock int64) (bool, error) {
+	n, err := compareAndSetStockScript.Run(ctx, r.rdb, []string{getVoucherKey(voucherID)}, old, stock).Int64()
+	return n == 1, err
+}
+
+// CountSeckillOrderUsers 一人一单集合中的用户数, 即 Redis 中已发放的订单数
+func (r *voucherRepo) CountSeckillOrderUsers(ctx context.Context, voucherID uint64) (int64, error) {
+	return r.rdb.SCard(ctx, getVoucherOrderKey(voucherID)).Result()
+}
+
 func (r *voucherRepo) ExecScript(ctx context.Context, script string, keys []string, args ...any) (int64, error) {
 	// 使用 Lua 脚本执行 Redis 命令
 	result, err := r.rdb.Eval(ctx, script, keys, args...).Result()
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mysqldriver "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupVoucherRepo(t *testing.T) (VoucherRepo, sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+	return NewVoucherRepo(db, nil, slog.Default()), mock
+}
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(mysqldriver.New(mysqldriver.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	return db, mock
+}
+
+func newTestOrder() *model.TbVoucherOrder {
+	return &model.TbVoucherOrder{ID: 1001, UserID: 1, VoucherID: 2, PayType: 1, Status: OrderStatusUnpaid}
+}
+
+func TestCreateVoucherOrderAndReduceStock(t *testing.T) {
+	repo, mock := setupVoucherRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_voucher_order`").WillReturnResult(sqlmock.NewResult(1001, 1))
+	mock.ExpectExec("UPDATE `tb_seckill_voucher` SET `stock`=").WillReturnResult(sqlmock.NewResult(0, 1))
+	// 下单事件与订单在同一事务中写入发件箱
+	mock.ExpectExec("INSERT INTO `tb_outbox`").
+		WithArgs(TopicOrderCreated, `{"order_id":1001,"user_id":1,"voucher_id":2}`, OutboxStatusPending, 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.CreateVoucherOrderAndReduceStock(context.Background(), newTestOrder()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 重复消费的订单消息主键冲突, 不能再次扣减库存
+func TestCreateVoucherOrderAndReduceStockReplayed(t *testing.T) {
+	repo, mock := setupVoucherRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_voucher_order`").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry '1001' for key 'PRIMARY'"})
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `tb_voucher_order`").
+		WithArgs(int64(1001)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	err := repo.CreateVoucherOrderAndReduceStock(context.Background(), newTestOrder())
+	assert.ErrorIs(t, err, ErrOrderExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateVoucherOrderAndReduceStockUserOrderExists(t *testing.T) {
+	repo, mock := setupVoucherRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_voucher_order`").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry '1-2-1' for key 'uk_user_voucher'"})
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `tb_voucher_order`").
+		WithArgs(int64(1001)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	err := repo.CreateVoucherOrderAndReduceStock(context.Background(), newTestOrder())
+	assert.ErrorIs(t, err, ErrUserOrderExists)
+	assert.NotErrorIs(t, err, ErrOrderExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateVoucherOrderAndReduceStockInsufficient(t *testing.T) {
+	repo, mock := setupVoucherRepo(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tb_voucher_order`").WillReturnResult(sqlmock.NewResult(1001, 1))
+	mock.ExpectExec("UPDATE `tb_seckill_voucher`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.CreateVoucherOrderAndReduceStock(context.Background(), newTestOrder())
+	assert.ErrorIs(t, err, ErrStockInsufficient)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
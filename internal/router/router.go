@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/hmmm42/city-picks/internal/handler"
+	"github.com/hmmm42/city-picks/internal/middleware"
 	"github.com/hmmm42/city-picks/pkg/code"
 )
 
@@ -12,6 +13,15 @@ func NewRouter(
 	userHandler *handler.LoginHandler,
 	shopHandler *handler.ShopService,
 	voucherHandler *handler.VoucherHandler,
+	lotteryHandler *handler.LotteryHandler,
+	orderHandler *handler.OrderHandler,
+	deadLetterHandler *handler.DeadLetterHandler,
+	stockReconcileHandler *handler.StockReconcileHandler,
+	blogHandler *handler.BlogHandler,
+	commentHandler *handler.CommentHandler,
+	moderationHandler *handler.ModerationHandler,
+	followHandler *handler.FollowHandler,
+	idempotency *middleware.Idempotency,
 ) *gin.Engine {
 	//r := gin.New()
 	//r.Use(gin.Recovery())
@@ -37,9 +47,63 @@ func NewRouter(
 		protected.POST("/shop/create", shopHandler.CreateShop)
 		protected.POST("/shop/update", shopHandler.UpdateShop)
 		protected.DELETE("/shop/:id", shopHandler.DeleteShop)
+	}
+
+	authed := r.Group("/")
+	authed.Use(middleware.JWT())
+	{
+		authed.POST("/voucher/create", idempotency.Handle(), voucherHandler.CreateVoucher)
+		authed.POST("/voucher/seckill", idempotency.Handle(), voucherHandler.SeckillVoucher)
+		authed.POST("/voucher/lottery/:id/register", lotteryHandler.Register)
+		authed.GET("/voucher/lottery/:id/result", lotteryHandler.GetResult)
+		authed.GET("/voucher/order/:id", voucherHandler.GetSeckillOrder)
+
+		authed.POST("/order/:id/pay", orderHandler.Pay)
+		authed.POST("/order/:id/redeem-token", orderHandler.IssueRedeemToken)
+		authed.POST("/order/:id/cancel", orderHandler.Cancel)
+		authed.POST("/order/:id/refund", orderHandler.Refund)
+
+		authed.POST("/merchant/order/redeem", orderHandler.Redeem)
+
+		authed.POST("/blog", blogHandler.CreateBlog)
+		authed.GET("/blog/hot", blogHandler.ListHotBlogs)
+		authed.GET("/blog/:id", blogHandler.GetBlog)
+		authed.DELETE("/blog/:id", blogHandler.DeleteBlog)
+		authed.PUT("/blog/like/:id", blogHandler.LikeBlog)
+		authed.GET("/blog/likes/:id", blogHandler.ListBlogLikers)
+
+		authed.POST("/blog/:id/comments", commentHandler.CreateComment)
+		authed.GET("/blog/:id/comments", commentHandler.ListComments)
+		authed.GET("/blog/comments/:id/replies", commentHandler.ListReplies)
+		authed.DELETE("/blog/comments/:id", commentHandler.DeleteComment)
+		authed.PUT("/blog/comments/:id/like", commentHandler.LikeComment)
+		authed.POST("/blog/comments/:id/report", moderationHandler.ReportComment)
+
+		authed.PUT("/follow/:id", followHandler.Follow)
+		authed.DELETE("/follow/:id", followHandler.Unfollow)
+		authed.GET("/follow/:id", followHandler.IsFollowing)
+		authed.GET("/follow/:id/followers", followHandler.ListFollowers)
+		authed.GET("/follow/:id/followees", followHandler.ListFollowees)
+		authed.GET("/follow/common/:id", followHandler.ListCommonFollows)
+	}
+
+	admin := r.Group("/admin")
+	admin.Use(middleware.JWT(), middleware.Admin())
+	{
+		admin.POST("/voucher/update", voucherHandler.UpdateVoucher)
+		admin.POST("/voucher/:id/offline", voucherHandler.TakeDownVoucher)
+		admin.POST("/voucher/:id/window", voucherHandler.UpdateSeckillWindow)
+		admin.POST("/voucher/stock", voucherHandler.AdjustSeckillStock)
+		admin.GET("/voucher/:id/stock/logs", voucherHandler.ListSeckillStockLogs)
+		admin.POST("/voucher/stock/reconcile", stockReconcileHandler.Reconcile)
+
+		admin.GET("/dlq", deadLetterHandler.List)
+		admin.POST("/dlq/replay", deadLetterHandler.Replay)
+		admin.POST("/dlq/discard", deadLetterHandler.Discard)
+		admin.POST("/dlq/reconcile", deadLetterHandler.Reconcile)
 
-		protected.POST("/voucher/create", voucherHandler.CreateVoucher)
-		protected.POST("/voucher/seckill", voucherHandler.SeckillVoucher)
+		admin.GET("/moderation/queue", moderationHandler.ListQueue)
+		admin.POST("/moderation/review", moderationHandler.Review)
 	}
 	return r
 }
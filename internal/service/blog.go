@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/moderation"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// blogImageSeparator tb_blog.images 中多张图片以逗号分隔
+	blogImageSeparator = ","
+	maxBlogImages      = 9
+	// 与 tb_blog 的列长度一致
+	maxBlogTitleLen   = 255
+	maxBlogImagesLen  = 2048
+	maxBlogContentLen = 2048
+
+	defaultBlogPageSize = 10
+	maxBlogPageSize     = 50
+	// topBlogLikers 点赞列表只展示最早点赞的几位用户
+	topBlogLikers = 5
+)
+
+var (
+	ErrInvalidBlog   = errors.New("invalid blog")
+	ErrNotBlogAuthor = errors.New("blog does not belong to the user")
+)
+
+// BlogCreateDTO 发布探店笔记, Images 为图片地址, 最多 9 张
+type BlogCreateDTO struct {
+	ShopID  uint64   `json:"shop_id" binding:"required"`
+	Title   string   `json:"title" binding:"required"`
+	Content string   `json:"content" binding:"required"`
+	Images  []string `json:"images"`
+}
+
+type BlogAuthorDTO struct {
+	ID       uint64 `json:"id"`
+	NickName string `json:"nick_name"`
+	Icon     string `json:"icon"`
+}
+
+// BlogDTO 探店笔记与作者信息, 图片拆分为列表返回; IsLiked 表示当前用户是否已点赞
+type BlogDTO struct {
+	*model.TbBlog
+	Images  []string       `json:"images"`
+	Author  *BlogAuthorDTO `json:"author"`
+	IsLiked bool           `json:"is_liked"`
+}
+
+// BlogLikeDTO 点赞或取消点赞后的状态
+type BlogLikeDTO struct {
+	IsLiked bool   `json:"is_liked"`
+	Liked   uint64 `json:"liked"`
+}
+
+// BlogService 探店笔记; 发布时命中审核词的笔记处于待审核状态, visibleOnly 为 true 时只有作者能看到非正常状态的笔记
+type BlogService interface {
+	CreateBlog(ctx context.Context, userID uint64, req *BlogCreateDTO) (*BlogDTO, error)
+	GetBlog(ctx context.Context, id, userID uint64, visibleOnly bool) (*BlogDTO, error)
+	DeleteBlog(ctx context.Context, id, userID uint64) error
+	ListHotBlogs(ctx context.Context, userID uint64, page, size int) ([]*BlogDTO, error)
+	LikeBlog(ctx context.Context, id, userID uint64) (*BlogLikeDTO, error)
+	ListBlogLikers(ctx context.Context, id, userID uint64, visibleOnly bool) ([]*BlogAuthorDTO, error)
+}
+
+type blogService struct {
+	blogRepo    repository.BlogRepo
+	commentRepo repository.CommentRepo
+	shopRepo    repository.ShopRepo
+	userRepo    repository.UserRepo
+	moderator   moderation.Moderator
+	logger      *slog.Logger
+}
+
+func (s *blogService) CreateBlog(ctx context.Context, userID uint64, req *BlogCreateDTO) (*BlogDTO, error) {
+	images, err := joinBlogImages(req.Images)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(req.Title) > maxBlogTitleLen {
+		return nil, fmt.Errorf("%w: title exceeds %d characters", ErrInvalidBlog, maxBlogTitleLen)
+	}
+	if utf8.RuneCountInString(req.Content) > maxBlogContentLen {
+		return nil, fmt.Errorf("%w: content exceeds %d characters", ErrInvalidBlog, maxBlogContentLen)
+	}
+	verdict, err := checkContent(ctx, s.moderator, s.logger, req.Title+"\n"+req.Content)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.shopRepo.GetShopByID(ctx, req.ShopID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: shop %d not found", ErrInvalidBlog, req.ShopID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shop %d: %w", req.ShopID, err)
+	}
+
+	blog := &model.TbBlog{
+		ShopID:  int64(req.ShopID),
+		UserID:  userID,
+		Title:   req.Title,
+		Images:  images,
+		Content: req.Content,
+		Status:  repository.BlogStatusNormal,
+	}
+	if verdict == moderation.VerdictReview {
+		blog.Status = repository.BlogStatusPending
+	}
+	if err = s.blogRepo.CreateBlog(ctx, blog); err != nil {
+		s.logger.Error("failed to create blog", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("failed to create blog: %w", err)
+	}
+	dtos, err := s.toBlogDTOs(ctx, userID, []*model.TbBlog{blog})
+	if err != nil {
+		return nil, err
+	}
+	return dtos[0], nil
+}
+
+func (s *blogService) GetBlog(ctx context.Context, id, userID uint64, visibleOnly bool) (*BlogDTO, error) {
+	blog, err := getVisibleBlog(ctx, s.blogRepo, id, userID, visibleOnly)
+	if err != nil {
+		return nil, err
+	}
+	dtos, err := s.toBlogDTOs(ctx, userID, []*model.TbBlog{blog})
+	if err != nil {
+		return nil, err
+	}
+	return dtos[0], nil
+}
+
+// DeleteBlog 只能删除自己发布的笔记
+func (s *blogService) DeleteBlog(ctx context.Context, id, userID uint64) error {
+	ok, commentIDs, err := s.blogRepo.DeleteBlog(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete blog %d: %w", id, err)
+	}
+	if !ok {
+		if _, err = s.blogRepo.GetBlogByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrNotBlogAuthor
+	}
+
+	if err = s.blogRepo.DeleteBlogLikeCache(ctx, id); err != nil {
+		s.logger.Warn("failed to delete blog like cache", "err", err, "blog_id", id)
+	}
+	if len(commentIDs) == 0 {
+		return nil
+	}
+	if err = s.commentRepo.DeleteCommentLikeCache(ctx, commentIDs...); err != nil {
+		s.logger.Warn("failed to delete comment like cache", "err", err, "comment_ids", commentIDs)
+	}
+	return nil
+}
+
+// ListHotBlogs 按点赞数分页查询热门笔记, page 从 1 开始
+func (s *blogService) ListHotBlogs(ctx context.Context, userID uint64, page, size int) ([]*BlogDTO, error) {
+	offset, limit := pageOf(page, size)
+	blogs, err := s.blogRepo.ListHotBlogs(ctx, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hot blogs: %w", err)
+	}
+	return s.toBlogDTOs(ctx, userID, blogs)
+}
+
+// LikeBlog 点赞, 已点赞时取消; 先在 Redis 中切换点赞状态, 再更新 MySQL 中的点赞数, 失败时撤销 Redis 中的切换
+func (s *blogService) LikeBlog(ctx context.Context, id, userID uint64) (*BlogLikeDTO, error) {
+	blog, err := s.blogRepo.GetBlogByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if blog.Status != repository.BlogStatusNormal {
+		return nil, fmt.Errorf("%w: blog %d is not visible", ErrInvalidBlog, id)
+	}
+
+	now := time.Now()
+	liked, err := s.blogRepo.ToggleBlogLikeCache(ctx, id, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to toggle blog like: %w", err)
+	}
+	delta := 1
+	if !liked {
+		delta = -1
+	}
+	if err = s.blogRepo.IncrBlogLiked(ctx, id, delta); err != nil {
+		if _, undoErr := s.blogRepo.ToggleBlogLikeCache(ctx, id, userID, now); undoErr != nil {
+			s.logger.Error("failed to undo blog like toggle", "err", undoErr, "blog_id", id, "user_id", userID)
+		}
+		return nil, fmt.Errorf("failed to update liked count of blog %d: %w", id, err)
+	}
+
+	count := blog.Liked + 1
+	if !liked {
+		count = max(blog.Liked, 1) - 1
+	}
+	return &BlogLikeDTO{IsLiked: liked, Liked: count}, nil
+}
+
+// ListBlogLikers 按点赞时间返回最早点赞的几位用户
+func (s *blogService) ListBlogLikers(ctx context.Context, id, userID uint64, visibleOnly bool) ([]*BlogAuthorDTO, error) {
+	if _, err := getVisibleBlog(ctx, s.blogRepo, id, userID, visibleOnly); err != nil {
+		return nil, err
+	}
+
+	userIDs, err := s.blogRepo.ListBlogLikersCache(ctx, id, topBlogLikers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list likers of blog %d: %w", id, err)
+	}
+	users, err := getBlogAuthors(ctx, s.userRepo, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	likers := make([]*BlogAuthorDTO, 0, len(userIDs))
+	for _, likerID := range userIDs {
+		if u, ok := users[likerID]; ok {
+			likers = append(likers, u)
+		}
+	}
+	return likers, nil
+}
+
+// toBlogDTOs 批量查询作者信息与当前用户的点赞状态, 作者不存在时 Author 为 nil; userID 为 0 时不查询点赞状态
+func (s *blogService) toBlogDTOs(ctx context.Context, userID uint64, blogs []*model.TbBlog) ([]*BlogDTO, error) {
+	dtos := make([]*BlogDTO, 0, len(blogs))
+	if len(blogs) == 0 {
+		return dtos, nil
+	}
+
+	authorIDs := make([]uint64, 0, len(blogs))
+	blogIDs := make([]uint64, 0, len(blogs))
+	for _, blog := range blogs {
+		authorIDs = append(authorIDs, blog.UserID)
+		blogIDs = append(blogIDs, blog.ID)
+	}
+	authors, err := getBlogAuthors(ctx, s.userRepo, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	liked := make(map[uint64]bool)
+	if userID != 0 {
+		liked = getLikedStatus(ctx, s.logger, s.blogRepo.GetBlogLikedCache, userID, blogIDs)
+	}
+
+	for _, blog := range blogs {
+		dtos = append(dtos, &BlogDTO{
+			TbBlog:  blog,
+			Images:  splitBlogImages(blog.Images),
+			Author:  authors[blog.UserID],
+			IsLiked: liked[blog.ID],
+		})
+	}
+	return dtos, nil
+}
+
+// getLikedStatus 查询用户对各笔记或评论的点赞状态; 点赞状态只影响展示, Redis 故障时按未点赞返回
+func getLikedStatus(ctx context.Context, logger *slog.Logger, get func(context.Context, uint64, ...uint64) (map[uint64]bool, error), userID uint64, ids []uint64) map[uint64]bool {
+	liked, err := get(ctx, userID, ids...)
+	if err != nil {
+		logger.Error("failed to get liked status", "err", err, "user_id", userID, "ids", ids)
+		return make(map[uint64]bool)
+	}
+	return liked
+}
+
+// getVisibleBlog 查询笔记, visibleOnly 为 true 时非正常状态的笔记只有作者能看到, 其他用户按不存在处理
+func getVisibleBlog(ctx context.Context, blogRepo repository.BlogRepo, id, userID uint64, visibleOnly bool) (*model.TbBlog, error) {
+	blog, err := blogRepo.GetBlogByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if visibleOnly && blog.Status != repository.BlogStatusNormal && blog.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return blog, nil
+}
+
+// getBlogAuthors 批量查询用户的昵称与头像
+func getBlogAuthors(ctx context.Context, userRepo repository.UserRepo, ids []uint64) (map[uint64]*BlogAuthorDTO, error) {
+	users := make(map[uint64]*BlogAuthorDTO, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+	list, err := userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	for _, u := range list {
+		users[u.ID] = &BlogAuthorDTO{ID: u.ID, NickName: u.NickName, Icon: u.Icon}
+	}
+	return users, nil
+}
+
+// pageOf 将从 1 开始的页码转换为偏移量, size 不合法时使用默认值
+func pageOf(page, size int) (offset, limit int) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = defaultBlogPageSize
+	}
+	size = min(size, maxBlogPageSize)
+	return (page - 1) * size, size
+}
+
+// joinBlogImages 校验图片列表并拼接为 tb_blog.images 的格式
+func joinBlogImages(images []string) (string, error) {
+	if len(images) > maxBlogImages {
+		return "", fmt.Errorf("%w: at most %d images", ErrInvalidBlog, maxBlogImages)
+	}
+	for _, image := range images {
+		if strings.TrimSpace(image) == "" || strings.Contains(image, blogImageSeparator) {
+			return "", fmt.Errorf("%w: invalid image %q", ErrInvalidBlog, image)
+		}
+	}
+	joined := strings.Join(images, blogImageSeparator)
+	if utf8.RuneCountInString(joined) > maxBlogImagesLen {
+		return "", fmt.Errorf("%w: images exceed %d characters", ErrInvalidBlog, maxBlogImagesLen)
+	}
+	return joined, nil
+}
+
+func splitBlogImages(images string) []string {
+	if images == "" {
+		return []string{}
+	}
+	return strings.Split(images, blogImageSeparator)
+}
+
+func NewBlogService(blogRepo repository.BlogRepo, commentRepo repository.CommentRepo, shopRepo repository.ShopRepo, userRepo repository.UserRepo, moderator moderation.Moderator, logger *slog.Logger) BlogService {
+	return &blogService{
+		blogRepo:    blogRepo,
+		commentRepo: commentRepo,
+		shopRepo:    shopRepo,
+		userRepo:    userRepo,
+		moderator:   moderator,
+		logger:      logger,
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/moderation"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxCommentLen 与 tb_blog_comments.content 的列长度一致
+const maxCommentLen = 255
+
+var (
+	ErrInvalidComment   = errors.New("invalid comment")
+	ErrNotCommentAuthor = errors.New("comment does not belong to the user or the blog author")
+)
+
+// CommentCreateDTO 发表评论, AnswerID 为回复的评论, 为 0 时发表一级评论
+type CommentCreateDTO struct {
+	AnswerID uint64 `json:"answer_id"`
+	Content  string `json:"content" binding:"required"`
+}
+
+// CommentDTO 评论与作者信息; 回复的不是一级评论时 ReplyTo 为被回复的用户, ReplyCount 只对一级评论统计
+type CommentDTO struct {
+	*model.TbBlogComment
+	Author     *BlogAuthorDTO `json:"author"`
+	ReplyTo    *BlogAuthorDTO `json:"reply_to,omitempty"`
+	IsLiked    bool           `json:"is_liked"`
+	ReplyCount int64          `json:"reply_count"`
+}
+
+type CommentLikeDTO struct {
+	IsLiked bool   `json:"is_liked"`
+	Liked   uint64 `json:"liked"`
+}
+
+// CommentService 两级评论: 一级评论的 parent_id 为 0, 回复的 parent_id 为所属的一级评论, answer_id 为被回复的评论;
+// 发布时命中审核词的评论与被举报的评论一样等待审核; visibleOnly 为 true 时笔记不可见按不存在处理,
+// 不返回其他用户被举报和禁止查看的评论, 与笔记一样作者仍能看到自己的评论
+type CommentService interface {
+	CreateComment(ctx context.Context, blogID, userID uint64, req *CommentCreateDTO) (*CommentDTO, error)
+	ListComments(ctx context.Context, blogID, userID uint64, visibleOnly bool, page, size int) ([]*CommentDTO, error)
+	ListReplies(ctx context.Context, commentID, userID uint64, visibleOnly bool, page, size int) ([]*CommentDTO, error)
+	DeleteComment(ctx context.Context, commentID, userID uint64) error
+	LikeComment(ctx context.Context, commentID, userID uint64) (*CommentLikeDTO, error)
+}
+
+type commentService struct {
+	commentRepo repository.CommentRepo
+	blogRepo    repository.BlogRepo
+	userRepo    repository.UserRepo
+	moderator   moderation.Moderator
+	logger      *slog.Logger
+}
+
+func (s *commentService) CreateComment(ctx context.Context, blogID, userID uint64, req *CommentCreateDTO) (*CommentDTO, error) {
+	if strings.TrimSpace(req.Content) == "" || utf8.RuneCountInString(req.Content) > maxCommentLen {
+		return nil, fmt.Errorf("%w: content must be 1 to %d characters", ErrInvalidComment, maxCommentLen)
+	}
+	verdict, err := checkContent(ctx, s.moderator, s.logger, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &model.TbBlogComment{
+		UserID:   userID,
+		BlogID:   blogID,
+		AnswerID: req.AnswerID,
+		Content:  req.Content,
+		Status:   repository.CommentStatusNormal,
+	}
+	if verdict == moderation.VerdictReview {
+		comment.Status = repository.CommentStatusReported
+	}
+	if req.AnswerID != 0 {
+		answer, err := s.commentRepo.GetCommentByID(ctx, req.AnswerID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: comment %d not found", ErrInvalidComment, req.AnswerID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comment %d: %w", req.AnswerID, err)
+		}
+		if answer.BlogID != blogID || answer.Status != repository.CommentStatusNormal {
+			return nil, fmt.Errorf("%w: cannot reply to comment %d", ErrInvalidComment, req.AnswerID)
+		}
+		// 回复的回复仍挂在同一条一级评论下
+		comment.ParentID = answer.ParentID
+		if comment.ParentID == 0 {
+			comment.ParentID = answer.ID
+		}
+	}
+
+	if err := s.commentRepo.CreateComment(ctx, comment); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		s.logger.Error("failed to create comment", "err", err, "blog_id", blogID, "user_id", userID)
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	dtos, err := s.toCommentDTOs(ctx, userID, []*model.TbBlogComment{comment}, false)
+	if err != nil {
+		return nil, err
+	}
+	return dtos[0], nil
+}
+
+// ListComments 分页查询笔记的一级评论, 附带各评论的回复数
+func (s *commentService) ListComments(ctx context.Context, blogID, userID uint64, visibleOnly bool, page, size int) ([]*CommentDTO, error) {
+	if _, err := getVisibleBlog(ctx, s.blogRepo, blogID, userID, visibleOnly); err != nil {
+		return nil, err
+	}
+
+	offset, limit := pageOf(page, size)
+	comments, err := s.commentRepo.ListTopComments(ctx, blogID, userID, visibleOnly, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments of blog %d: %w", blogID, err)
+	}
+	dtos, err := s.toCommentDTOs(ctx, userID, comments, visibleOnly)
+	if err != nil || len(dtos) == 0 {
+		return dtos, err
+	}
+
+	ids := make([]uint64, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	counts, err := s.commentRepo.CountReplies(ctx, ids, userID, visibleOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count replies: %w", err)
+	}
+	for _, dto := range dtos {
+		dto.ReplyCount = counts[dto.ID]
+	}
+	return dtos, nil
+}
+
+// ListReplies 分页查询一级评论下的回复, 一级评论不可见时按不存在处理
+func (s *commentService) ListReplies(ctx context.Context, commentID, userID uint64, visibleOnly bool, page, size int) ([]*CommentDTO, error) {
+	parent, err := s.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.ParentID != 0 {
+		return nil, fmt.Errorf("%w: comment %d is a reply", ErrInvalidComment, commentID)
+	}
+	if visibleOnly && parent.Status != repository.CommentStatusNormal && parent.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	offset, limit := pageOf(page, size)
+	replies, err := s.commentRepo.ListReplies(ctx, commentID, userID, visibleOnly, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replies of comment %d: %w", commentID, err)
+	}
+	return s.toCommentDTOs(ctx, userID, replies, visibleOnly)
+}
+
+// DeleteComment 评论作者或笔记作者可以删除评论, 删除一级评论时一并删除其下的回复
+func (s *commentService) DeleteComment(ctx context.Context, commentID, userID uint64) error {
+	comment, err := s.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID {
+		blog, err := s.blogRepo.GetBlogByID(ctx, comment.BlogID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get blog %d: %w", comment.BlogID, err)
+		}
+		if blog == nil || blog.UserID != userID {
+			return ErrNotCommentAuthor
+		}
+	}
+
+	ids, err := s.commentRepo.DeleteComment(ctx, comment)
+	if err != nil {
+		s.logger.Error("failed to delete comment", "err", err, "comment_id", commentID)
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err = s.commentRepo.DeleteCommentLikeCache(ctx, ids...); err != nil {
+		s.logger.Warn("failed to delete comment like cache", "err", err, "comment_ids", ids)
+	}
+	return nil
+}
+
+// LikeComment 点赞评论, 已点赞时取消; 与笔记点赞相同, 先切换 Redis 中的状态再更新 MySQL 中的点赞数
+func (s *commentService) LikeComment(ctx context.Context, commentID, userID uint64) (*CommentLikeDTO, error) {
+	comment, err := s.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status != repository.CommentStatusNormal {
+		return nil, fmt.Errorf("%w: comment %d is not visible", ErrInvalidComment, commentID)
+	}
+
+	now := time.Now()
+	liked, err := s.commentRepo.ToggleCommentLikeCache(ctx, commentID, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to toggle comment like: %w", err)
+	}
+	delta := 1
+	if !liked {
+		delta = -1
+	}
+	if err = s.commentRepo.IncrCommentLiked(ctx, commentID, delta); err != nil {
+		if _, undoErr := s.commentRepo.ToggleCommentLikeCache(ctx, commentID, userID, now); undoErr != nil {
+			s.logger.Error("failed to undo comment like toggle", "err", undoErr, "comment_id", commentID, "user_id", userID)
+		}
+		return nil, fmt.Errorf("failed to update liked count of comment %d: %w", commentID, err)
+	}
+
+	count := comment.Liked + 1
+	if !liked {
+		count = max(comment.Liked, 1) - 1
+	}
+	return &CommentLikeDTO{IsLiked: liked, Liked: count}, nil
+}
+
+// toCommentDTOs 查询作者、被回复的用户与当前用户的点赞状态; 被回复的评论不可见时不返回 ReplyTo
+func (s *commentService) toCommentDTOs(ctx context.Context, userID uint64, comments []*model.TbBlogComment, visibleOnly bool) ([]*CommentDTO, error) {
+	dtos := make([]*CommentDTO, 0, len(comments))
+	if len(comments) == 0 {
+		return dtos, nil
+	}
+
+	userIDs := make([]uint64, 0, len(comments))
+	commentIDs := make([]uint64, 0, len(comments))
+	var answerIDs []uint64
+	for _, c := range comments {
+		userIDs = append(userIDs, c.UserID)
+		commentIDs = append(commentIDs, c.ID)
+		if c.AnswerID != 0 && c.AnswerID != c.ParentID {
+			answerIDs = append(answerIDs, c.AnswerID)
+		}
+	}
+
+	answerUsers := make(map[uint64]uint64) // 被回复的评论 ID -> 用户 ID
+	if len(answerIDs) > 0 {
+		answers, err := s.commentRepo.GetCommentsByIDs(ctx, answerIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get answered comments: %w", err)
+		}
+		for _, a := range answers {
+			if visibleOnly && a.Status != repository.CommentStatusNormal && a.UserID != userID {
+				continue
+			}
+			answerUsers[a.ID] = a.UserID
+			userIDs = append(userIDs, a.UserID)
+		}
+	}
+
+	users, err := getBlogAuthors(ctx, s.userRepo, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	liked := getLikedStatus(ctx, s.logger, s.commentRepo.GetCommentLikedCache, userID, commentIDs)
+
+	for _, c := range comments {
+		dto := &CommentDTO{
+			TbBlogComment: c,
+			Author:        users[c.UserID],
+			IsLiked:       liked[c.ID],
+		}
+		if answerUserID, ok := answerUsers[c.AnswerID]; ok {
+			dto.ReplyTo = users[answerUserID]
+		}
+		dtos = append(dtos, dto)
+	}
+	return dtos, nil
+}
+
+func NewCommentService(commentRepo repository.CommentRepo, blogRepo repository.BlogRepo, userRepo repository.UserRepo, moderator moderation.Moderator, logger *slog.Logger) CommentService {
+	return &commentService{
+		commentRepo: commentRepo,
+		blogRepo:    blogRepo,
+		userRepo:    userRepo,
+		moderator:   moderator,
+		logger:      logger,
+	}
+}
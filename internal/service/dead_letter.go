@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/event"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeadLetterPageSize = 100
+	// 死信补偿标记的有效期, 期间重复核对同一订单不会再次归还库存
+	deadLetterCompensatedTTL = 7 * 24 * time.Hour
+)
+
+var ErrNoDeadLetterSelected = errors.New("either ids or all must be specified")
+
+// errSkipDeadLetter 跳过当前死信, 不从死信队列中删除
+var errSkipDeadLetter = errors.New("skip dead letter")
+
+// DeadLetterDTO 死信队列中的一条订单消息
+type DeadLetterDTO struct {
+	ID         string `json:"id"`
+	OriginalID string `json:"original_id"`
+	Consumer   string `json:"consumer"`
+	Error      string `json:"error"`
+	FailedAt   string `json:"failed_at"`
+	OrderID    string `json:"order_id"`
+	UserID     string `json:"user_id"`
+	VoucherID  string `json:"voucher_id"`
+	RetryCount string `json:"retry_count"`
+	Event      string `json:"event,omitempty"`
+}
+
+// DeadLetterPage NextCursor 为空表示没有更多消息
+type DeadLetterPage struct {
+	Entries    []*DeadLetterDTO `json:"entries"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+// DeadLetterRequest 指定要处理的死信 ID, All 为 true 时处理整个死信队列
+type DeadLetterRequest struct {
+	IDs []string `json:"ids"`
+	All bool     `json:"all"`
+}
+
+// ReconcileResult Created 为订单已存在、直接移除的死信数, Compensated 为已归还 Redis 库存的死信数,
+// InFlight 为已重新投递、仍在订单流中处理而保留的死信数
+type ReconcileResult struct {
+	Created     int `json:"created"`
+	Compensated int `json:"compensated"`
+	Invalid     int `json:"invalid"`
+	InFlight    int `json:"in_flight"`
+}
+
+type DeadLetterService interface {
+	List(ctx context.Context, cursor string, count int64) (*DeadLetterPage, error)
+	Replay(ctx context.Context, req *DeadLetterRequest) (int, error)
+	Discard(ctx context.Context, req *DeadLetterRequest) (int, error)
+	Reconcile(ctx context.Context, req *DeadLetterRequest) (*ReconcileResult, error)
+}
+
+type deadLetterService struct {
+	mq               repository.MessageQueue
+	voucherRepo      repository.VoucherRepo
+	voucherOrderRepo repository.VoucherOrderRepo
+	logger           *slog.Logger
+}
+
+// List 从 cursor 之后开始列出死信, cursor 为空时从头开始
+func (s *deadLetterService) List(ctx context.Context, cursor string, count int64) (*DeadLetterPage, error) {
+	if count <= 0 {
+		count = defaultDeadLetterPageSize
+	}
+	start := "-"
+	if cursor != "" {
+		start = "(" + cursor
+	}
+	msgs, err := s.mq.RangeMessages(ctx, repository.DeadLetterStreamKey, start, "+", count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	page := &DeadLetterPage{Entries: make([]*DeadLetterDTO, 0, len(msgs))}
+	for _, msg := range msgs {
+		page.Entries = append(page.Entries, toDeadLetterDTO(msg))
+	}
+	if int64(len(msgs)) == count {
+		page.NextCursor = msgs[len(msgs)-1].ID
+	}
+	return page, nil
+}
+
+// Replay 将死信重新投递到 stream:orders, 重置重试次数
+// 已由 Reconcile 归还库存的订单不再投递, 否则订单落库时不会再扣减 Redis 库存, 导致超卖
+func (s *deadLetterService) Replay(ctx context.Context, req *DeadLetterRequest) (int, error) {
+	return s.each(ctx, req, func(msg repository.Message) error {
+		// 格式错误的死信重新投递也无法处理, 保留在死信队列中
+		order, err := event.DecodeOrder(msg.Values)
+		if err != nil {
+			s.logger.Warn("skip replaying malformed dead letter", "id", msg.ID, "err", err)
+			return errSkipDeadLetter
+		}
+		compensated, err := s.voucherRepo.ExecScript(ctx, isDeadOrderCompensated, []string{strconv.FormatInt(order.OrderID, 10)})
+		if err != nil {
+			return err
+		}
+		if compensated == 1 {
+			s.logger.Warn("skip replaying compensated dead letter", "id", msg.ID, "order_id", order.OrderID)
+			return errSkipDeadLetter
+		}
+		// 旧版本的消息按当前版本重新编码
+		values, err := event.EncodeOrder(order)
+		if err != nil {
+			return err
+		}
+		if _, err = s.mq.AddOrderToStream(ctx, values); err != nil {
+			return err
+		}
+		if err = s.voucherOrderRepo.SetSeckillOrderStatus(ctx, order.OrderID, repository.SeckillOrderPending); err != nil {
+			s.logger.Error("failed to reset seckill order status", "err", err, "order_id", order.OrderID)
+		}
+		return nil
+	})
+}
+
+// Discard 直接删除死信, 不做任何补偿
+func (s *deadLetterService) Discard(ctx context.Context, req *DeadLetterRequest) (int, error) {
+	return s.each(ctx, req, func(repository.Message) error { return nil })
+}
+
+// Reconcile 逐条核对死信: 已重新投递且仍在订单流中的保留; 订单已落库则直接移除; 否则订单不会再创建,
+// 归还 Redis 库存并将用户移出 seckill:order 集合, 订单状态标记为失败.
+// 因用户已持有有效订单而失败的死信只归还库存, 用户仍占用一人一单名额.
+// 补偿前按订单 ID 写入补偿标记, 删除死信失败或重复核对时不会重复归还库存
+func (s *deadLetterService) Reconcile(ctx context.Context, req *DeadLetterRequest) (*ReconcileResult, error) {
+	inFlight, err := s.inFlightOrderIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReconcileResult{}
+	_, err = s.each(ctx, req, func(msg repository.Message) error {
+		order, err := event.DecodeOrder(msg.Values)
+		if err != nil {
+			s.logger.Warn("discard invalid dead letter", "id", msg.ID, "values", msg.Values, "err", err)
+			result.Invalid++
+			return nil
+		}
+		orderID, userID, voucherID := order.OrderID, order.UserID, order.VoucherID
+		if inFlight[orderID] {
+			s.logger.Info("skip dead letter replayed and still in flight", "id", msg.ID, "order_id", orderID)
+			result.InFlight++
+			return errSkipDeadLetter
+		}
+
+		_, err = s.voucherOrderRepo.GetVoucherOrderByID(ctx, orderID)
+		if err == nil {
+			result.Created++
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		releaseUser := 1
+		if strings.Contains(msg.Values["error"], repository.ErrUserOrderExists.Error()) {
+			releaseUser = 0
+		}
+		keys := []string{strconv.FormatUint(voucherID, 10), strconv.FormatUint(userID, 10), strconv.FormatInt(orderID, 10)}
+		compensated, err := s.voucherRepo.ExecScript(ctx, compensateDeadOrder, keys, int64(deadLetterCompensatedTTL.Seconds()), releaseUser)
+		if err != nil {
+			return err
+		}
+		if err = s.voucherOrderRepo.SetSeckillOrderStatus(ctx, orderID, repository.SeckillOrderFailed); err != nil {
+			s.logger.Error("failed to mark seckill order failed", "err", err, "order_id", orderID)
+		}
+		if compensated == 0 {
+			s.logger.Info("dead order already compensated", "order_id", orderID, "voucher_id", voucherID, "user_id", userID)
+		} else {
+			s.logger.Info("compensated dead order", "order_id", orderID, "voucher_id", voucherID, "user_id", userID)
+		}
+		result.Compensated++
+		return nil
+	})
+	return result, err
+}
+
+// inFlightOrderIDs 订单流中尚未确认或等待重试的订单, 包括重新投递后还未处理完的死信订单
+func (s *deadLetterService) inFlightOrderIDs(ctx context.Context) (map[int64]bool, error) {
+	msgs, err := s.mq.InFlightMessages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list in-flight messages: %w", err)
+	}
+	ids := make(map[int64]bool, len(msgs))
+	for _, msg := range msgs {
+		if order, err := event.DecodeOrder(msg.Values); err == nil {
+			ids[order.OrderID] = true
+		}
+	}
+	return ids, nil
+}
+
+// each 对选中的每条死信执行 fn, 成功后从死信队列中删除, fn 返回 errSkipDeadLetter 时保留, 返回处理成功的数量
+func (s *deadLetterService) each(ctx context.Context, req *DeadLetterRequest, fn func(msg repository.Message) error) (int, error) {
+	if !req.All && len(req.IDs) == 0 {
+		return 0, ErrNoDeadLetterSelected
+	}
+
+	handled := 0
+	handle := func(msgs []repository.Message) error {
+		for _, msg := range msgs {
+			err := fn(msg)
+			if errors.Is(err, errSkipDeadLetter) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to handle dead letter %s: %w", msg.ID, err)
+			}
+			if _, err := s.mq.DeleteMessages(ctx, repository.DeadLetterStreamKey, msg.ID); err != nil {
+				return fmt.Errorf("failed to delete dead letter %s: %w", msg.ID, err)
+			}
+			handled++
+		}
+		return nil
+	}
+
+	if !req.All {
+		for _, id := range req.IDs {
+			msgs, err := s.mq.RangeMessages(ctx, repository.DeadLetterStreamKey, id, id, 1)
+			if err != nil {
+				return handled, err
+			}
+			if err = handle(msgs); err != nil {
+				return handled, err
+			}
+		}
+		return handled, nil
+	}
+
+	start := "-"
+	for {
+		msgs, err := s.mq.RangeMessages(ctx, repository.DeadLetterStreamKey, start, "+", defaultDeadLetterPageSize)
+		if err != nil {
+			return handled, err
+		}
+		if err = handle(msgs); err != nil {
+			return handled, err
+		}
+		if len(msgs) < defaultDeadLetterPageSize {
+			return handled, nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// toDeadLetterDTO 格式错误的死信不填写订单信息, 原始内容放在 Event 中
+func toDeadLetterDTO(msg repository.Message) *DeadLetterDTO {
+	dto := &DeadLetterDTO{
+		ID:         msg.ID,
+		OriginalID: msg.Values["original_id"],
+		Consumer:   msg.Values["consumer"],
+		Error:      msg.Values["error"],
+		FailedAt:   msg.Values["failed_at"],
+		RetryCount: msg.Values["retry_count"],
+	}
+	order, err := event.DecodeOrder(msg.Values)
+	if err != nil {
+		dto.Event = msg.Values[event.FieldOrder]
+		return dto
+	}
+	dto.OrderID = strconv.FormatInt(order.OrderID, 10)
+	dto.UserID = strconv.FormatUint(order.UserID, 10)
+	dto.VoucherID = strconv.FormatUint(order.VoucherID, 10)
+	return dto
+}
+
+func NewDeadLetterService(mq repository.MessageQueue, voucherRepo repository.VoucherRepo, voucherOrderRepo repository.VoucherOrderRepo, logger *slog.Logger) DeadLetterService {
+	return &deadLetterService{
+		mq:               mq,
+		voucherRepo:      voucherRepo,
+		voucherOrderRepo: voucherOrderRepo,
+		logger:           logger,
+	}
+}
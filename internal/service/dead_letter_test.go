@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/event"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeDeadLetterQueue 内存中的死信队列和订单流
+type fakeDeadLetterQueue struct {
+	repository.MessageQueue
+	deadLetters []repository.Message
+	orders      []repository.Message
+	deleteErr   error
+}
+
+func (q *fakeDeadLetterQueue) RangeMessages(ctx context.Context, streamKey, start, end string, count int64) ([]repository.Message, error) {
+	if start != end {
+		if start != "-" {
+			return nil, nil
+		}
+		return slices.Clone(q.deadLetters), nil
+	}
+	for _, msg := range q.deadLetters {
+		if msg.ID == start {
+			return []repository.Message{msg}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *fakeDeadLetterQueue) DeleteMessages(ctx context.Context, streamKey string, msgIDs ...string) (int64, error) {
+	if q.deleteErr != nil {
+		return 0, q.deleteErr
+	}
+	q.deadLetters = slices.DeleteFunc(q.deadLetters, func(msg repository.Message) bool {
+		return slices.Contains(msgIDs, msg.ID)
+	})
+	return int64(len(msgIDs)), nil
+}
+
+func (q *fakeDeadLetterQueue) AddOrderToStream(ctx context.Context, values map[string]any) (string, error) {
+	msg := repository.Message{ID: "1-0", Values: make(map[string]string)}
+	for k, v := range values {
+		msg.Values[k] = v.(string)
+	}
+	q.orders = append(q.orders, msg)
+	return msg.ID, nil
+}
+
+func (q *fakeDeadLetterQueue) InFlightMessages(ctx context.Context) ([]repository.Message, error) {
+	return q.orders, nil
+}
+
+// redisScriptRepo 在 miniredis 上执行 Lua 脚本
+type redisScriptRepo struct {
+	repository.VoucherRepo
+	rdb *redis.Client
+}
+
+func (r *redisScriptRepo) ExecScript(ctx context.Context, script string, keys []string, args ...any) (int64, error) {
+	return r.rdb.Eval(ctx, script, keys, args...).Int64()
+}
+
+type fakeDeadOrderRepo struct {
+	repository.VoucherOrderRepo
+	created  map[int64]bool
+	statuses map[int64]string
+}
+
+func (r *fakeDeadOrderRepo) GetVoucherOrderByID(ctx context.Context, orderID int64) (*model.TbVoucherOrder, error) {
+	if !r.created[orderID] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.TbVoucherOrder{ID: orderID}, nil
+}
+
+func (r *fakeDeadOrderRepo) SetSeckillOrderStatus(ctx context.Context, orderID int64, status string) error {
+	r.statuses[orderID] = status
+	return nil
+}
+
+func deadLetter(t *testing.T, id string, orderID int64, userID, voucherID uint64) repository.Message {
+	values, err := event.EncodeOrder(&event.Order{OrderID: orderID, UserID: userID, VoucherID: voucherID})
+	require.NoError(t, err)
+	return repository.Message{ID: id, Values: map[string]string{event.FieldOrder: values[event.FieldOrder].(string)}}
+}
+
+func newDeadLetterFixture(t *testing.T) (*deadLetterService, *fakeDeadLetterQueue, *fakeDeadOrderRepo, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	queue := &fakeDeadLetterQueue{deadLetters: []repository.Message{
+		deadLetter(t, "1-1", 101, 1, 7),
+		deadLetter(t, "1-2", 102, 2, 7),
+	}}
+	orderRepo := &fakeDeadOrderRepo{created: make(map[int64]bool), statuses: make(map[int64]string)}
+	svc := &deadLetterService{
+		mq:               queue,
+		voucherRepo:      &redisScriptRepo{rdb: rdb},
+		voucherOrderRepo: orderRepo,
+		logger:           slog.Default(),
+	}
+	require.NoError(t, mr.Set("seckill:stock:7", "3"))
+	_, err := mr.SAdd("seckill:order:7", "1", "2")
+	require.NoError(t, err)
+	return svc, queue, orderRepo, mr
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	svc, queue, orderRepo, _ := newDeadLetterFixture(t)
+
+	n, err := svc.Replay(context.Background(), &DeadLetterRequest{IDs: []string{"1-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, queue.orders, 1)
+	order, err := event.DecodeOrder(queue.orders[0].Values)
+	require.NoError(t, err)
+	assert.Equal(t, int64(101), order.OrderID)
+	assert.Equal(t, repository.SeckillOrderPending, orderRepo.statuses[101])
+	require.Len(t, queue.deadLetters, 1)
+	assert.Equal(t, "1-2", queue.deadLetters[0].ID)
+}
+
+func TestDiscardDeadLetters(t *testing.T) {
+	svc, queue, _, mr := newDeadLetterFixture(t)
+
+	n, err := svc.Discard(context.Background(), &DeadLetterRequest{All: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Empty(t, queue.deadLetters)
+	// 不做任何补偿
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "3", stock)
+}
+
+func TestReconcileDeadLetters(t *testing.T) {
+	svc, queue, orderRepo, mr := newDeadLetterFixture(t)
+	orderRepo.created[101] = true
+
+	result, err := svc.Reconcile(context.Background(), &DeadLetterRequest{All: true})
+	require.NoError(t, err)
+	assert.Equal(t, &ReconcileResult{Created: 1, Compensated: 1}, result)
+	assert.Empty(t, queue.deadLetters)
+
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "4", stock)
+	members, _ := mr.Members("seckill:order:7")
+	assert.Equal(t, []string{"1"}, members)
+	assert.Equal(t, repository.SeckillOrderFailed, orderRepo.statuses[102])
+}
+
+// 删除死信失败后再次核对, 不会重复归还库存
+func TestReconcileDeadLettersCompensatesOnce(t *testing.T) {
+	svc, queue, _, mr := newDeadLetterFixture(t)
+	queue.deleteErr = errors.New("redis down")
+
+	_, err := svc.Reconcile(context.Background(), &DeadLetterRequest{IDs: []string{"1-2"}})
+	require.Error(t, err)
+	queue.deleteErr = nil
+	result, err := svc.Reconcile(context.Background(), &DeadLetterRequest{IDs: []string{"1-2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Compensated)
+
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "4", stock)
+	assert.Len(t, queue.deadLetters, 1)
+}
+
+// 已重新投递、仍在订单流中处理的订单不能补偿, 死信保留
+func TestReconcileSkipsReplayedInFlight(t *testing.T) {
+	svc, queue, _, mr := newDeadLetterFixture(t)
+	queue.orders = []repository.Message{orderMessage(t, 102, 7)}
+
+	result, err := svc.Reconcile(context.Background(), &DeadLetterRequest{IDs: []string{"1-2"}})
+	require.NoError(t, err)
+	assert.Equal(t, &ReconcileResult{InFlight: 1}, result)
+	assert.Len(t, queue.deadLetters, 2)
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "3", stock)
+}
+
+// 已归还库存的死信不能重新投递, 否则订单落库后库存没有再扣减
+func TestReplaySkipsCompensatedDeadLetter(t *testing.T) {
+	svc, queue, _, mr := newDeadLetterFixture(t)
+	_, err := svc.Reconcile(context.Background(), &DeadLetterRequest{IDs: []string{"1-2"}})
+	require.NoError(t, err)
+	queue.deadLetters = append(queue.deadLetters, deadLetter(t, "1-3", 102, 2, 7))
+
+	n, err := svc.Replay(context.Background(), &DeadLetterRequest{IDs: []string{"1-3"}})
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, queue.orders)
+	assert.Len(t, queue.deadLetters, 2)
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "4", stock)
+}
+
+// 用户已持有有效订单导致的死信只归还库存, 不释放一人一单名额
+func TestReconcileKeepsUserOfDuplicateOrder(t *testing.T) {
+	svc, queue, _, mr := newDeadLetterFixture(t)
+	queue.deadLetters[1].Values["error"] = "failed to create order: " + repository.ErrUserOrderExists.Error() + ": duplicate entry"
+
+	result, err := svc.Reconcile(context.Background(), &DeadLetterRequest{IDs: []string{"1-2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Compensated)
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "4", stock)
+	members, _ := mr.Members("seckill:order:7")
+	assert.Equal(t, []string{"1", "2"}, members)
+}
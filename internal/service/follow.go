@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/hmmm42/city-picks/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidFollow = errors.New("invalid follow")
+
+type FollowStatusDTO struct {
+	IsFollowing bool `json:"is_following"`
+}
+
+// FollowService 关注关系以 MySQL 为准, Redis 中的关注集合只用于求共同关注;
+// 关注与取消关注都是幂等的, 更新 Redis 失败时删除关注集合, 下次使用时重新加载
+type FollowService interface {
+	Follow(ctx context.Context, userID, followUserID uint64) error
+	Unfollow(ctx context.Context, userID, followUserID uint64) error
+	IsFollowing(ctx context.Context, userID, followUserID uint64) (*FollowStatusDTO, error)
+	ListFollowers(ctx context.Context, userID uint64, page, size int) ([]*BlogAuthorDTO, error)
+	ListFollowees(ctx context.Context, userID uint64, page, size int) ([]*BlogAuthorDTO, error)
+	ListCommonFollows(ctx context.Context, userID, otherID uint64) ([]*BlogAuthorDTO, error)
+}
+
+type followService struct {
+	followRepo repository.FollowRepo
+	userRepo   repository.UserRepo
+	logger     *slog.Logger
+}
+
+func (s *followService) Follow(ctx context.Context, userID, followUserID uint64) error {
+	if userID == followUserID {
+		return fmt.Errorf("%w: cannot follow yourself", ErrInvalidFollow)
+	}
+	users, err := s.userRepo.FindByIDs(ctx, []uint64{followUserID})
+	if err != nil {
+		return fmt.Errorf("failed to get user %d: %w", followUserID, err)
+	}
+	if len(users) == 0 {
+		return fmt.Errorf("user %d: %w", followUserID, gorm.ErrRecordNotFound)
+	}
+
+	err = s.followRepo.Follow(ctx, userID, followUserID)
+	if errors.Is(err, repository.ErrAlreadyFollowing) {
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("failed to follow user", "err", err, "user_id", userID, "follow_user_id", followUserID)
+		return fmt.Errorf("failed to follow user %d: %w", followUserID, err)
+	}
+	if err = s.followRepo.AddFollowCache(ctx, userID, followUserID); err != nil {
+		s.dropFollowsCache(ctx, userID, err)
+	}
+	return nil
+}
+
+func (s *followService) Unfollow(ctx context.Context, userID, followUserID uint64) error {
+	ok, err := s.followRepo.Unfollow(ctx, userID, followUserID)
+	if err != nil {
+		s.logger.Error("failed to unfollow user", "err", err, "user_id", userID, "follow_user_id", followUserID)
+		return fmt.Errorf("failed to unfollow user %d: %w", followUserID, err)
+	}
+	if !ok {
+		return nil
+	}
+	if err = s.followRepo.RemoveFollowCache(ctx, userID, followUserID); err != nil {
+		s.dropFollowsCache(ctx, userID, err)
+	}
+	return nil
+}
+
+// dropFollowsCache 关注集合与 MySQL 不一致时删除, 删除也失败时只能等待缓存过期
+func (s *followService) dropFollowsCache(ctx context.Context, userID uint64, cause error) {
+	s.logger.Warn("failed to update follows cache", "err", cause, "user_id", userID)
+	if err := s.followRepo.DeleteFollowsCache(ctx, userID); err != nil {
+		s.logger.Error("failed to delete stale follows cache", "err", err, "user_id", userID)
+	}
+}
+
+func (s *followService) IsFollowing(ctx context.Context, userID, followUserID uint64) (*FollowStatusDTO, error) {
+	ok, err := s.followRepo.IsFollowing(ctx, userID, followUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check follow status: %w", err)
+	}
+	return &FollowStatusDTO{IsFollowing: ok}, nil
+}
+
+// ListFollowers 分页查询粉丝, 最近关注的在前
+func (s *followService) ListFollowers(ctx context.Context, userID uint64, page, size int) ([]*BlogAuthorDTO, error) {
+	offset, limit := pageOf(page, size)
+	ids, err := s.followRepo.ListFollowerIDs(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list followers of user %d: %w", userID, err)
+	}
+	return s.toUserDTOs(ctx, ids)
+}
+
+// ListFollowees 分页查询关注的用户, 最近关注的在前
+func (s *followService) ListFollowees(ctx context.Context, userID uint64, page, size int) ([]*BlogAuthorDTO, error) {
+	offset, limit := pageOf(page, size)
+	ids, err := s.followRepo.ListFolloweeIDs(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list followees of user %d: %w", userID, err)
+	}
+	return s.toUserDTOs(ctx, ids)
+}
+
+// ListCommonFollows 当前用户与 otherID 共同关注的用户, 按用户 ID 排序
+func (s *followService) ListCommonFollows(ctx context.Context, userID, otherID uint64) ([]*BlogAuthorDTO, error) {
+	for _, id := range []uint64{userID, otherID} {
+		if err := s.followRepo.LoadFollowsCache(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to load follows of user %d: %w", id, err)
+		}
+	}
+	ids, err := s.followRepo.CommonFollowsCache(ctx, userID, otherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get common follows: %w", err)
+	}
+	slices.Sort(ids)
+	return s.toUserDTOs(ctx, ids)
+}
+
+// toUserDTOs 按 ids 的顺序返回用户信息, 跳过已不存在的用户
+func (s *followService) toUserDTOs(ctx context.Context, ids []uint64) ([]*BlogAuthorDTO, error) {
+	users, err := getBlogAuthors(ctx, s.userRepo, ids)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*BlogAuthorDTO, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := users[id]; ok {
+			dtos = append(dtos, u)
+		}
+	}
+	return dtos, nil
+}
+
+func NewFollowService(followRepo repository.FollowRepo, userRepo repository.UserRepo, logger *slog.Logger) FollowService {
+	return &followService{
+		followRepo: followRepo,
+		userRepo:   userRepo,
+		logger:     logger,
+	}
+}
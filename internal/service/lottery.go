@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/event"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/sonyflake"
+	"gorm.io/gorm"
+)
+
+// 每次 Lua 调用发放的中签订单数, 避免单个脚本阻塞 Redis 过久
+const lotteryIssueBatch = 500
+
+var (
+	ErrLotteryNotAvailable = errors.New("lottery registration is not available for this voucher")
+	ErrLotteryRegistered   = errors.New("user has already registered for this lottery")
+)
+
+// LotteryResult 用户的抽签结果
+type LotteryResult struct {
+	Drawn   bool  `json:"drawn"` // 是否已开奖
+	Won     bool  `json:"won"`
+	OrderID int64 `json:"order_id,omitempty"`
+}
+
+type LotteryService interface {
+	Register(ctx context.Context, voucherID, userID uint64) error
+	DrawPending(ctx context.Context) (int, error)
+	Draw(ctx context.Context, voucherID uint64) error
+	GetResult(ctx context.Context, voucherID, userID uint64) (*LotteryResult, error)
+}
+
+type lotteryService struct {
+	lotteryRepo repository.LotteryRepo
+	voucherRepo repository.VoucherRepo
+	sf          *sonyflake.Sonyflake
+	logger      *slog.Logger
+}
+
+func (s *lotteryService) Register(ctx context.Context, voucherID, userID uint64) error {
+	keys := []string{
+		strconv.FormatUint(voucherID, 10),
+		strconv.FormatUint(userID, 10),
+	}
+	res, err := s.voucherRepo.ExecScript(ctx, registerLottery, keys, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to register lottery: %w", err)
+	}
+
+	switch res {
+	case 0:
+		return nil
+	case 2:
+		return ErrLotteryRegistered
+	case 3:
+		return ErrLotteryNotAvailable
+	default:
+		return fmt.Errorf("unexpected result from lottery register script: %d", res)
+	}
+}
+
+// DrawPending 为所有已到开始时间的抽签券开奖, 返回开奖的数量
+func (s *lotteryService) DrawPending(ctx context.Context) (int, error) {
+	ids, err := s.lotteryRepo.ListPendingDrawVoucherIDs(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending lottery vouchers: %w", err)
+	}
+
+	drawn := 0
+	for _, id := range ids {
+		if err = s.Draw(ctx, id); err != nil {
+			s.logger.Error("failed to draw lottery", "err", err, "voucher_id", id)
+			continue
+		}
+		drawn++
+	}
+	return drawn, nil
+}
+
+// Draw 随机抽取不超过库存数量的中签用户, 并通过 stream:orders 为其创建订单
+// 抽签记录与预约名单先写入 MySQL, 主键保证多实例下每张券只抽签一次, 由种子和名单可以复现抽签结果;
+// 中签订单分批发放, 每批发放后记录进度, 中途失败时下次从未发放的批次继续
+func (s *lotteryService) Draw(ctx context.Context, voucherID uint64) error {
+	record, participants, err := s.loadDraw(ctx, voucherID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		record, participants, err = s.createDraw(ctx, voucherID)
+	}
+	if err != nil {
+		return err
+	}
+
+	winners := drawWinners(participants, int64(record.Winners), record.Seed)
+	keys := []string{strconv.FormatUint(voucherID, 10)}
+	var shortfall int64
+	for start := int(record.Issued); start < len(winners); start += lotteryIssueBatch {
+		chunk := winners[start:min(start+lotteryIssueBatch, len(winners))]
+		args := make([]any, 0, len(chunk)*2+2)
+		args = append(args, int64(repository.SeckillOrderStatusTTL.Seconds()), event.OrderVersion)
+		for _, userID := range chunk {
+			orderID, err := s.sf.NextID()
+			if err != nil {
+				return fmt.Errorf("failed to generate unique ID: %w", err)
+			}
+			args = append(args, userID, orderID)
+		}
+		// 脚本跳过已中签的用户, 进度未能记录时重新发放同一批不会重复下单
+		missed, err := s.voucherRepo.ExecScript(ctx, issueLotteryOrders, keys, args...)
+		if err != nil {
+			return fmt.Errorf("failed to issue lottery orders: %w", err)
+		}
+		shortfall += missed
+		if err = s.lotteryRepo.UpdateDrawIssued(ctx, voucherID, uint64(start+len(chunk))); err != nil {
+			return fmt.Errorf("failed to save lottery issue progress: %w", err)
+		}
+	}
+
+	if shortfall > 0 {
+		// 库存在抽签后被调低, 超出库存的中签用户没有发放订单
+		s.logger.Warn("lottery stock insufficient for winners", "voucher_id", voucherID, "shortfall", shortfall)
+	}
+	s.logger.Info("lottery drawn",
+		"voucher_id", voucherID,
+		"seed", record.Seed,
+		"participants", len(participants),
+		"winners", len(winners),
+	)
+	return nil
+}
+
+// createDraw 抽签并保存抽签记录与预约名单; 已被其他实例抽签时读取其记录
+func (s *lotteryService) createDraw(ctx context.Context, voucherID uint64) (*model.TbSeckillLottery, []uint64, error) {
+	participants, err := s.lotteryRepo.GetParticipants(ctx, voucherID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get lottery participants: %w", err)
+	}
+	slices.Sort(participants)
+
+	stock, err := s.voucherRepo.GetVoucherStockCache(ctx, voucherID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stock of voucher %d: %w", voucherID, err)
+	}
+
+	seed := time.Now().UnixNano()
+	record := &model.TbSeckillLottery{
+		VoucherID:          voucherID,
+		Seed:               seed,
+		Participants:       uint64(len(participants)),
+		ParticipantsDigest: participantsDigest(participants),
+		Winners:            uint64(len(drawWinners(participants, stock, seed))),
+		DrawTime:           time.Now(),
+	}
+	err = s.lotteryRepo.CreateDrawRecord(ctx, record, participants)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		s.logger.Info("lottery already drawn by another instance", "voucher_id", voucherID)
+		return s.loadDraw(ctx, voucherID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create lottery record: %w", err)
+	}
+	return record, participants, nil
+}
+
+// loadDraw 读取抽签记录与保存的预约名单, 名单与记录的摘要不一致时拒绝继续发放
+func (s *lotteryService) loadDraw(ctx context.Context, voucherID uint64) (*model.TbSeckillLottery, []uint64, error) {
+	record, err := s.lotteryRepo.GetDrawRecord(ctx, voucherID)
+	if err != nil {
+		return nil, nil, err
+	}
+	participants, err := s.lotteryRepo.ListDrawParticipants(ctx, voucherID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list lottery participants: %w", err)
+	}
+	if participantsDigest(participants) != record.ParticipantsDigest {
+		return nil, nil, fmt.Errorf("participants of lottery %d do not match the recorded digest", voucherID)
+	}
+	return record, participants, nil
+}
+
+// GetResult 中签订单全部发放后才返回开奖结果
+func (s *lotteryService) GetResult(ctx context.Context, voucherID, userID uint64) (*LotteryResult, error) {
+	record, err := s.lotteryRepo.GetDrawRecord(ctx, voucherID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &LotteryResult{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lottery record: %w", err)
+	}
+	if record.Issued < record.Winners {
+		return &LotteryResult{}, nil
+	}
+
+	orderID, err := s.lotteryRepo.GetWinnerOrderID(ctx, voucherID, userID)
+	if errors.Is(err, redis.Nil) {
+		return &LotteryResult{Drawn: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lottery winner: %w", err)
+	}
+	return &LotteryResult{Drawn: true, Won: true, OrderID: orderID}, nil
+}
+
+// drawWinners 用 seed 打乱按用户 ID 升序排列的预约名单, 取前 n 名中签; 相同的输入总是得到相同的结果
+func drawWinners(participants []uint64, n int64, seed int64) []uint64 {
+	shuffled := slices.Clone(participants)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled[:max(0, min(n, int64(len(shuffled))))]
+}
+
+func participantsDigest(participants []uint64) string {
+	h := sha256.New()
+	for _, userID := range participants {
+		h.Write(strconv.AppendUint(nil, userID, 10))
+		h.Write([]byte{','})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func NewLotteryService(lotteryRepo repository.LotteryRepo, voucherRepo repository.VoucherRepo, idGen *sonyflake.Sonyflake, logger *slog.Logger) LotteryService {
+	return &lotteryService{
+		lotteryRepo: lotteryRepo,
+		voucherRepo: voucherRepo,
+		sf:          idGen,
+		logger:      logger,
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/sonyflake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 相同的名单、数量和种子总是得到相同的中签名单
+func TestDrawWinnersDeterministic(t *testing.T) {
+	participants := make([]uint64, 100)
+	for i := range participants {
+		participants[i] = uint64(i + 1)
+	}
+
+	winners := drawWinners(participants, 10, 42)
+	require.Len(t, winners, 10)
+	assert.Equal(t, winners, drawWinners(participants, 10, 42))
+	assert.NotEqual(t, winners, drawWinners(participants, 10, 43))
+	// 中签名单是打乱后的前缀, 数量越多越包含数量少时的名单
+	assert.Equal(t, winners, drawWinners(participants, 20, 42)[:10])
+	// 不修改传入的名单
+	assert.Equal(t, uint64(1), participants[0])
+
+	assert.Len(t, drawWinners(participants, 200, 42), 100)
+	assert.Empty(t, drawWinners(participants, -1, 42))
+}
+
+type fakeLotteryRepo struct {
+	repository.LotteryRepo
+	record       *model.TbSeckillLottery
+	participants []uint64
+}
+
+func (r *fakeLotteryRepo) GetDrawRecord(ctx context.Context, voucherID uint64) (*model.TbSeckillLottery, error) {
+	return r.record, nil
+}
+
+func (r *fakeLotteryRepo) ListDrawParticipants(ctx context.Context, voucherID uint64) ([]uint64, error) {
+	return r.participants, nil
+}
+
+func (r *fakeLotteryRepo) UpdateDrawIssued(ctx context.Context, voucherID, issued uint64) error {
+	r.record.Issued = issued
+	return nil
+}
+
+// fakeScriptRepo 记录执行的脚本参数
+type fakeScriptRepo struct {
+	repository.VoucherRepo
+	calls [][]any
+}
+
+func (r *fakeScriptRepo) ExecScript(ctx context.Context, script string, keys []string, args ...any) (int64, error) {
+	r.calls = append(r.calls, args)
+	return 0, nil
+}
+
+// 上次只发放了第一批中签订单时, 从第二批继续发放
+func TestDrawResumesIssue(t *testing.T) {
+	participants := make([]uint64, 1000)
+	for i := range participants {
+		participants[i] = uint64(i + 1)
+	}
+	record := &model.TbSeckillLottery{
+		VoucherID:          7,
+		Seed:               42,
+		Participants:       1000,
+		ParticipantsDigest: participantsDigest(participants),
+		Winners:            600,
+		Issued:             lotteryIssueBatch,
+	}
+	lotteryRepo := &fakeLotteryRepo{record: record, participants: participants}
+	voucherRepo := &fakeScriptRepo{}
+	sf, err := sonyflake.New(sonyflake.Settings{MachineID: func() (uint16, error) { return 1, nil }})
+	require.NoError(t, err)
+	svc := NewLotteryService(lotteryRepo, voucherRepo, sf, slog.Default())
+
+	require.NoError(t, svc.Draw(context.Background(), 7))
+	require.Len(t, voucherRepo.calls, 1)
+	args := voucherRepo.calls[0]
+	winners := drawWinners(participants, 600, 42)
+	require.Len(t, args, 2+2*(600-lotteryIssueBatch))
+	assert.Equal(t, winners[lotteryIssueBatch], args[2])
+	assert.Equal(t, uint64(600), record.Issued)
+
+	// 全部发放后不再执行脚本
+	require.NoError(t, svc.Draw(context.Background(), 7))
+	assert.Len(t, voucherRepo.calls, 1)
+}
+
+// 保存的名单与摘要不一致时拒绝发放
+func TestDrawRejectsTamperedParticipants(t *testing.T) {
+	record := &model.TbSeckillLottery{VoucherID: 7, Seed: 42, ParticipantsDigest: participantsDigest([]uint64{1, 2, 3}), Winners: 1}
+	lotteryRepo := &fakeLotteryRepo{record: record, participants: []uint64{1, 2, 4}}
+	voucherRepo := &fakeScriptRepo{}
+	svc := NewLotteryService(lotteryRepo, voucherRepo, nil, slog.Default())
+
+	assert.Error(t, svc.Draw(context.Background(), 7))
+	assert.Empty(t, voucherRepo.calls)
+}
+
+// 发放数量不超过剩余库存, 库存不会被扣成负数, 超出库存的中签用户不记录一人一单
+func TestIssueLotteryOrdersCappedByStock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	repo := &redisScriptRepo{rdb: rdb}
+	ctx := context.Background()
+	require.NoError(t, mr.Set("seckill:stock:7", "2"))
+
+	issue := func(pairs ...any) int64 {
+		args := append([]any{int64(repository.SeckillOrderStatusTTL.Seconds()), 1}, pairs...)
+		shortfall, err := repo.ExecScript(ctx, issueLotteryOrders, []string{"7"}, args...)
+		require.NoError(t, err)
+		return shortfall
+	}
+	assert.Equal(t, int64(1), issue(1, 101, 2, 102, 3, 103))
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "0", stock)
+	members, _ := mr.Members("seckill:order:7")
+	assert.Equal(t, []string{"1", "2"}, members)
+
+	// 重复发放同一批时已中签的用户被跳过, 其余用户仍因库存不足不发放
+	assert.Equal(t, int64(2), issue(1, 101, 3, 103, 4, 104))
+	stock, _ = mr.Get("seckill:stock:7")
+	assert.Equal(t, "0", stock)
+	assert.False(t, mr.Exists("seckill:order:status:103"))
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/moderation"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"gorm.io/gorm"
+)
+
+// 审核队列中的内容类型
+const (
+	ModerationKindBlog    = "blog"
+	ModerationKindComment = "comment"
+)
+
+// 审核操作
+const (
+	ModerationActionApprove = "approve"
+	ModerationActionHide    = "hide"
+)
+
+var (
+	ErrContentRejected  = errors.New("content rejected by moderation")
+	ErrInvalidReview    = errors.New("invalid review request")
+	ErrNotPendingReview = errors.New("content is not waiting for review")
+)
+
+// ModerationReviewDTO 管理员审核待审核的笔记或被举报的评论
+type ModerationReviewDTO struct {
+	Kind   string `json:"kind" binding:"required"`
+	ID     uint64 `json:"id" binding:"required"`
+	Action string `json:"action" binding:"required"`
+}
+
+// ModerationQueueDTO 审核队列, 按 Kind 只填写 Blogs 或 Comments
+type ModerationQueueDTO struct {
+	Blogs    []*BlogDTO    `json:"blogs,omitempty"`
+	Comments []*CommentDTO `json:"comments,omitempty"`
+}
+
+// ModerationService 内容审核: 发布时命中审核词的笔记处于待审核状态, 用户举报的评论处于被举报状态, 由管理员通过或禁止查看
+type ModerationService interface {
+	ReportComment(ctx context.Context, commentID, userID uint64) error
+	ListQueue(ctx context.Context, kind string, page, size int) (*ModerationQueueDTO, error)
+	Review(ctx context.Context, req *ModerationReviewDTO) error
+}
+
+type moderationService struct {
+	blogRepo    repository.BlogRepo
+	commentRepo repository.CommentRepo
+	userRepo    repository.UserRepo
+	logger      *slog.Logger
+}
+
+// ReportComment 每个用户对同一评论只记录一次举报; 正常状态且未经管理员审核通过的评论进入审核队列,
+// 已被举报、禁止查看或审核通过的评论只记录举报
+func (s *moderationService) ReportComment(ctx context.Context, commentID, userID uint64) error {
+	if _, err := s.commentRepo.GetCommentByID(ctx, commentID); err != nil {
+		return err
+	}
+	queued, err := s.commentRepo.ReportComment(ctx, commentID, userID)
+	if errors.Is(err, repository.ErrAlreadyReported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to report comment %d: %w", commentID, err)
+	}
+	s.logger.Info("comment reported", "comment_id", commentID, "user_id", userID, "queued", queued)
+	return nil
+}
+
+// ListQueue 按提交顺序分页查询待审核的笔记或被举报的评论
+func (s *moderationService) ListQueue(ctx context.Context, kind string, page, size int) (*ModerationQueueDTO, error) {
+	offset, limit := pageOf(page, size)
+	switch kind {
+	case ModerationKindBlog:
+		blogs, err := s.blogRepo.ListBlogsByStatus(ctx, repository.BlogStatusPending, offset, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pending blogs: %w", err)
+		}
+		dtos, err := s.toBlogDTOs(ctx, blogs)
+		return &ModerationQueueDTO{Blogs: dtos}, err
+	case ModerationKindComment:
+		comments, err := s.commentRepo.ListCommentsByStatus(ctx, repository.CommentStatusReported, offset, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list reported comments: %w", err)
+		}
+		dtos, err := s.toCommentDTOs(ctx, comments)
+		return &ModerationQueueDTO{Comments: dtos}, err
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidReview, kind)
+	}
+}
+
+// Review 通过时恢复为正常状态, 否则禁止查看; 只能审核队列中的内容, 审核通过的评论不会再因举报进入队列
+func (s *moderationService) Review(ctx context.Context, req *ModerationReviewDTO) error {
+	if req.Action != ModerationActionApprove && req.Action != ModerationActionHide {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidReview, req.Action)
+	}
+	approve := req.Action == ModerationActionApprove
+
+	var ok bool
+	var err error
+	switch req.Kind {
+	case ModerationKindBlog:
+		to := repository.BlogStatusHidden
+		if approve {
+			to = repository.BlogStatusNormal
+		}
+		if ok, err = s.blogRepo.UpdateBlogStatus(ctx, req.ID, repository.BlogStatusPending, to); err == nil && !ok {
+			_, err = s.blogRepo.GetBlogByID(ctx, req.ID)
+		}
+	case ModerationKindComment:
+		if approve {
+			ok, err = s.commentRepo.ApproveComment(ctx, req.ID)
+		} else {
+			ok, err = s.commentRepo.UpdateCommentStatus(ctx, req.ID, repository.CommentStatusReported, repository.CommentStatusHidden)
+		}
+		if err == nil && !ok {
+			_, err = s.commentRepo.GetCommentByID(ctx, req.ID)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidReview, req.Kind)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to review %s %d: %w", req.Kind, req.ID, err)
+	}
+	if !ok {
+		return ErrNotPendingReview
+	}
+	s.logger.Info("content reviewed", "kind", req.Kind, "id", req.ID, "action", req.Action)
+	return nil
+}
+
+// toBlogDTOs 审核队列只需要作者信息, 不查询点赞状态
+func (s *moderationService) toBlogDTOs(ctx context.Context, blogs []*model.TbBlog) ([]*BlogDTO, error) {
+	ids := make([]uint64, 0, len(blogs))
+	for _, b := range blogs {
+		ids = append(ids, b.UserID)
+	}
+	authors, err := getBlogAuthors(ctx, s.userRepo, ids)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*BlogDTO, 0, len(blogs))
+	for _, b := range blogs {
+		dtos = append(dtos, &BlogDTO{TbBlog: b, Images: splitBlogImages(b.Images), Author: authors[b.UserID]})
+	}
+	return dtos, nil
+}
+
+func (s *moderationService) toCommentDTOs(ctx context.Context, comments []*model.TbBlogComment) ([]*CommentDTO, error) {
+	ids := make([]uint64, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.UserID)
+	}
+	authors, err := getBlogAuthors(ctx, s.userRepo, ids)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*CommentDTO, 0, len(comments))
+	for _, c := range comments {
+		dtos = append(dtos, &CommentDTO{TbBlogComment: c, Author: authors[c.UserID]})
+	}
+	return dtos, nil
+}
+
+// checkContent 审核发布的内容, 命中拒绝词时返回 ErrContentRejected; 审核服务出错时按需要人工审核处理
+func checkContent(ctx context.Context, moderator moderation.Moderator, logger *slog.Logger, text string) (moderation.Verdict, error) {
+	res, err := moderator.Check(ctx, text)
+	if err != nil {
+		logger.Error("failed to moderate content, send to review queue", "err", err)
+		return moderation.VerdictReview, nil
+	}
+	if res.Verdict != moderation.VerdictPass {
+		logger.Info("content hit moderation words", "verdict", res.Verdict, "hits", res.Hits)
+	}
+	if res.Verdict == moderation.VerdictBlock {
+		return res.Verdict, ErrContentRejected
+	}
+	return res.Verdict, nil
+}
+
+func NewModerationService(blogRepo repository.BlogRepo, commentRepo repository.CommentRepo, userRepo repository.UserRepo, logger *slog.Logger) ModerationService {
+	return &moderationService{
+		blogRepo:    blogRepo,
+		commentRepo: commentRepo,
+		userRepo:    userRepo,
+		logger:      logger,
+	}
+}
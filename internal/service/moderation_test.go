@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeCommentRepo 内存中的评论与举报记录, 状态修改与 commentRepo 的条件更新一致
+type fakeCommentRepo struct {
+	repository.CommentRepo
+	comments map[uint64]*model.TbBlogComment
+	reports  map[[2]uint64]bool
+}
+
+func newFakeCommentRepo(comments ...*model.TbBlogComment) *fakeCommentRepo {
+	r := &fakeCommentRepo{comments: make(map[uint64]*model.TbBlogComment), reports: make(map[[2]uint64]bool)}
+	for _, c := range comments {
+		r.comments[c.ID] = c
+	}
+	return r
+}
+
+func (r *fakeCommentRepo) GetCommentByID(ctx context.Context, id uint64) (*model.TbBlogComment, error) {
+	c, ok := r.comments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (r *fakeCommentRepo) ListCommentsByStatus(ctx context.Context, status uint8, offset, limit int) ([]*model.TbBlogComment, error) {
+	var list []*model.TbBlogComment
+	for _, c := range r.comments {
+		if c.Status == status {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (r *fakeCommentRepo) UpdateCommentStatus(ctx context.Context, id uint64, from, to uint8) (bool, error) {
+	c, ok := r.comments[id]
+	if !ok || c.Status != from {
+		return false, nil
+	}
+	c.Status = to
+	return true, nil
+}
+
+func (r *fakeCommentRepo) ReportComment(ctx context.Context, id, userID uint64) (bool, error) {
+	if r.reports[[2]uint64{id, userID}] {
+		return false, repository.ErrAlreadyReported
+	}
+	r.reports[[2]uint64{id, userID}] = true
+	c, ok := r.comments[id]
+	if !ok || c.Status != repository.CommentStatusNormal || c.Reviewed != 0 {
+		return false, nil
+	}
+	c.Status = repository.CommentStatusReported
+	return true, nil
+}
+
+func (r *fakeCommentRepo) ApproveComment(ctx context.Context, id uint64) (bool, error) {
+	c, ok := r.comments[id]
+	if !ok || c.Status != repository.CommentStatusReported {
+		return false, nil
+	}
+	c.Status, c.Reviewed = repository.CommentStatusNormal, 1
+	return true, nil
+}
+
+func (r *fakeCommentRepo) ListTopComments(ctx context.Context, blogID, viewerID uint64, visibleOnly bool, offset, limit int) ([]*model.TbBlogComment, error) {
+	return nil, nil
+}
+
+type fakeModerationBlogRepo struct {
+	repository.BlogRepo
+	blogs map[uint64]*model.TbBlog
+}
+
+func (r *fakeModerationBlogRepo) GetBlogByID(ctx context.Context, id uint64) (*model.TbBlog, error) {
+	b, ok := r.blogs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return b, nil
+}
+
+func (r *fakeModerationBlogRepo) UpdateBlogStatus(ctx context.Context, id uint64, from, to uint8) (bool, error) {
+	b, ok := r.blogs[id]
+	if !ok || b.Status != from {
+		return false, nil
+	}
+	b.Status = to
+	return true, nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepo
+}
+
+func (r *fakeUserRepo) FindByIDs(ctx context.Context, ids []uint64) ([]*model.TbUser, error) {
+	users := make([]*model.TbUser, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, &model.TbUser{ID: id})
+	}
+	return users, nil
+}
+
+func newTestModerationService(comments *fakeCommentRepo, blogs ...*model.TbBlog) *moderationService {
+	blogRepo := &fakeModerationBlogRepo{blogs: make(map[uint64]*model.TbBlog)}
+	for _, b := range blogs {
+		blogRepo.blogs[b.ID] = b
+	}
+	return &moderationService{blogRepo: blogRepo, commentRepo: comments, userRepo: &fakeUserRepo{}, logger: slog.Default()}
+}
+
+// 每个用户只记录一次举报, 审核通过的评论再被举报也不会回到审核队列
+func TestReportCommentAfterApprove(t *testing.T) {
+	ctx := context.Background()
+	comments := newFakeCommentRepo(&model.TbBlogComment{ID: 10, UserID: 1, Status: repository.CommentStatusNormal})
+	svc := newTestModerationService(comments)
+
+	require.NoError(t, svc.ReportComment(ctx, 10, 2))
+	require.NoError(t, svc.ReportComment(ctx, 10, 2))
+	assert.Len(t, comments.reports, 1)
+	assert.Equal(t, repository.CommentStatusReported, comments.comments[10].Status)
+
+	queue, err := svc.ListQueue(ctx, ModerationKindComment, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, queue.Comments, 1)
+	assert.Equal(t, uint64(1), queue.Comments[0].Author.ID)
+
+	require.NoError(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindComment, ID: 10, Action: ModerationActionApprove}))
+	require.NoError(t, svc.ReportComment(ctx, 10, 3))
+	assert.Len(t, comments.reports, 2)
+	assert.Equal(t, repository.CommentStatusNormal, comments.comments[10].Status)
+	assert.ErrorIs(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindComment, ID: 10, Action: ModerationActionHide}), ErrNotPendingReview)
+}
+
+func TestReportCommentNotFound(t *testing.T) {
+	svc := newTestModerationService(newFakeCommentRepo())
+	assert.ErrorIs(t, svc.ReportComment(context.Background(), 10, 2), gorm.ErrRecordNotFound)
+}
+
+func TestReviewHide(t *testing.T) {
+	ctx := context.Background()
+	comments := newFakeCommentRepo(&model.TbBlogComment{ID: 10, UserID: 1, Status: repository.CommentStatusReported})
+	svc := newTestModerationService(comments, &model.TbBlog{ID: 4, UserID: 1, Status: repository.BlogStatusPending})
+
+	require.NoError(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindComment, ID: 10, Action: ModerationActionHide}))
+	assert.Equal(t, repository.CommentStatusHidden, comments.comments[10].Status)
+	require.NoError(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindBlog, ID: 4, Action: ModerationActionHide}))
+	assert.ErrorIs(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindBlog, ID: 4, Action: ModerationActionApprove}), ErrNotPendingReview)
+	assert.ErrorIs(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindBlog, ID: 5, Action: ModerationActionApprove}), gorm.ErrRecordNotFound)
+}
+
+func TestReviewInvalid(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestModerationService(newFakeCommentRepo())
+
+	assert.ErrorIs(t, svc.Review(ctx, &ModerationReviewDTO{Kind: "user", ID: 1, Action: ModerationActionHide}), ErrInvalidReview)
+	assert.ErrorIs(t, svc.Review(ctx, &ModerationReviewDTO{Kind: ModerationKindBlog, ID: 1, Action: "delete"}), ErrInvalidReview)
+	_, err := svc.ListQueue(ctx, "user", 1, 10)
+	assert.ErrorIs(t, err, ErrInvalidReview)
+}
+
+// 待审核笔记的评论与点赞用户只有作者和管理员能看到
+func TestListCommentsOfPendingBlog(t *testing.T) {
+	ctx := context.Background()
+	blogRepo := &fakeModerationBlogRepo{blogs: map[uint64]*model.TbBlog{4: {ID: 4, UserID: 1, Status: repository.BlogStatusPending}}}
+	comments := &commentService{commentRepo: newFakeCommentRepo(), blogRepo: blogRepo, logger: slog.Default()}
+	blogs := &blogService{blogRepo: blogRepo, logger: slog.Default()}
+
+	_, err := comments.ListComments(ctx, 4, 2, true, 1, 10)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = blogs.ListBlogLikers(ctx, 4, 2, true)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = comments.ListComments(ctx, 4, 1, true, 1, 10)
+	assert.NoError(t, err)
+	_, err = comments.ListComments(ctx, 4, 2, false, 1, 10)
+	assert.NoError(t, err)
+}
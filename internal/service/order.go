@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/payment"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidOrderTransition = errors.New("order status does not allow this operation")
+	ErrNotShopOwner           = errors.New("order does not belong to the merchant's shops")
+	ErrPaymentFailed          = errors.New("payment failed")
+	ErrRefundFailed           = errors.New("refund failed")
+	ErrOrderPaying            = errors.New("order is being paid by another request")
+	ErrOrderRefunding         = errors.New("order is being refunded by another request")
+)
+
+// orderPayLockTTL 订单支付锁的有效期, 需要大于支付渠道的超时时间; 支付和退款共用同一把锁
+const orderPayLockTTL = time.Minute
+
+// orderTransitions 订单状态机, key 为当前状态, value 为允许转移到的状态
+//
+//	未支付 -> 已支付 -> 已核销
+//	   |        |
+//	   v        v
+//	已取消    退款中 -> 已退款
+var orderTransitions = map[uint8][]uint8{
+	repository.OrderStatusUnpaid:    {repository.OrderStatusPaid, repository.OrderStatusCancelled},
+	repository.OrderStatusPaid:      {repository.OrderStatusUsed, repository.OrderStatusRefunding},
+	repository.OrderStatusRefunding: {repository.OrderStatusRefunded},
+}
+
+func canTransitOrder(from, to uint8) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PayOrderDTO 支付订单, PayType 1:余额 2:支付宝 3:微信
+type PayOrderDTO struct {
+	PayType uint8 `json:"pay_type" binding:"required"`
+}
+
+// RedeemTokenDTO 核销码, 由用户出示给商户扫码核销
+type RedeemTokenDTO struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RedeemDTO 商户核销请求
+type RedeemDTO struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type OrderService interface {
+	Pay(ctx context.Context, orderID int64, userID uint64, req *PayOrderDTO) (*model.TbVoucherOrder, error)
+	IssueRedeemToken(ctx context.Context, orderID int64, userID uint64) (*RedeemTokenDTO, error)
+	Redeem(ctx context.Context, req *RedeemDTO, merchantID uint64) (*model.TbVoucherOrder, error)
+	Cancel(ctx context.Context, orderID int64, userID uint64) (*model.TbVoucherOrder, error)
+	Refund(ctx context.Context, orderID int64, userID uint64) (*model.TbVoucherOrder, error)
+	CancelExpired(ctx context.Context, orderID int64) error
+}
+
+type orderService struct {
+	voucherOrderRepo repository.VoucherOrderRepo
+	voucherRepo      repository.VoucherRepo
+	shopRepo         repository.ShopRepo
+	gateway          payment.Gateway
+	logger           *slog.Logger
+}
+
+// Pay 持有订单的支付锁时扣款, 并发的支付请求直接返回 ErrOrderPaying;
+// 扣款后订单状态已被修改(例如超时取消)时退回扣款
+func (s *orderService) Pay(ctx context.Context, orderID int64, userID uint64, req *PayOrderDTO) (*model.TbVoucherOrder, error) {
+	if !payment.ValidPayType(req.PayType) {
+		return nil, payment.ErrUnsupportedPayType
+	}
+	unlock, err := s.lockOrderPayment(ctx, orderID, ErrOrderPaying)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// 获取锁之后再查询订单, 已被其他请求支付的订单不会重复扣款
+	order, err := s.getOrder(ctx, orderID, userID, repository.OrderStatusPaid)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按下单时的快照扣款, 不受商家之后修改价格的影响
+	result, err := s.gateway.Pay(ctx, &payment.PayRequest{
+		OrderID: order.ID,
+		UserID:  userID,
+		Amount:  order.PayValue,
+		PayType: req.PayType,
+	})
+	if err != nil {
+		s.logger.Error("failed to pay order", "err", err, "order_id", orderID)
+		return nil, fmt.Errorf("%w: %w", ErrPaymentFailed, err)
+	}
+
+	from := order.Status
+	order.Status = repository.OrderStatusPaid
+	order.PayType = req.PayType
+	order.PayTime = time.Now()
+	if err = s.voucherOrderRepo.UpdateOrderStatus(ctx, order, from); err != nil {
+		s.logger.Error("order paid but failed to update status, refunding", "err", err, "order_id", orderID, "trade_no", result.TradeNo)
+		refundErr := s.gateway.Refund(ctx, &payment.RefundRequest{
+			RefundNo: "revert-" + result.TradeNo,
+			OrderID:  order.ID,
+			UserID:   userID,
+			Amount:   order.PayValue,
+			PayType:  req.PayType,
+		})
+		if refundErr != nil {
+			// 已扣款且退款失败, 需要人工对账
+			s.logger.Error("failed to refund payment of unpaid order", "err", refundErr, "order_id", orderID, "trade_no", result.TradeNo)
+		}
+		return nil, err
+	}
+	s.logger.Info("order paid", "order_id", orderID, "trade_no", result.TradeNo)
+	s.removeUnpaidOrder(ctx, orderID)
+	return order, nil
+}
+
+// IssueRedeemToken 为已支付的订单生成短期有效的一次性核销码, 重新生成会使旧的核销码失效
+func (s *orderService) IssueRedeemToken(ctx context.Context, orderID int64, userID uint64) (*RedeemTokenDTO, error) {
+	ttl, secret, err := redeemTokenOptions()
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.getOrder(ctx, orderID, userID, repository.OrderStatusUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate redeem nonce: %w", err)
+	}
+	expiresAt := time.Now().Add(ttl)
+	if err = s.voucherOrderRepo.SetRedeemNonce(ctx, order.ID, nonce, ttl); err != nil {
+		return nil, fmt.Errorf("failed to save redeem nonce: %w", err)
+	}
+
+	token := signRedeemToken(&redeemClaims{
+		OrderID:   order.ID,
+		Nonce:     nonce,
+		ExpiresAt: expiresAt.Unix(),
+	}, secret)
+	return &RedeemTokenDTO{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// Redeem 商户扫码核销: 校验核销码, 确认订单属于商户的店铺后, 消费核销码并将订单标记为已核销
+func (s *orderService) Redeem(ctx context.Context, req *RedeemDTO, merchantID uint64) (*model.TbVoucherOrder, error) {
+	_, secret, err := redeemTokenOptions()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := parseRedeemToken(req.Token, secret, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.voucherOrderRepo.GetVoucherOrderByID(ctx, claims.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransitOrder(order.Status, repository.OrderStatusUsed) {
+		return nil, ErrInvalidOrderTransition
+	}
+	if err = fillOrderSnapshots(ctx, s.voucherRepo, order); err != nil {
+		return nil, err
+	}
+
+	shop, err := s.shopRepo.GetShopByID(ctx, order.ShopID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shop %d: %w", order.ShopID, err)
+	}
+	if shop.OwnerID == 0 || shop.OwnerID != merchantID {
+		return nil, ErrNotShopOwner
+	}
+
+	// 先消费 nonce 再更新状态, 同一核销码并发提交时只有一个请求能通过
+	ok, err := s.voucherOrderRepo.ConsumeRedeemNonce(ctx, order.ID, claims.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume redeem nonce: %w", err)
+	}
+	if !ok {
+		return nil, ErrInvalidRedeemToken
+	}
+
+	from := order.Status
+	order.Status = repository.OrderStatusUsed
+	order.UseTime = time.Now()
+	if err = s.voucherOrderRepo.UpdateOrderStatus(ctx, order, from); err != nil {
+		return nil, err
+	}
+	s.logger.Info("order redeemed", "order_id", order.ID, "shop_id", shop.ID, "merchant_id", merchantID)
+	return order, nil
+}
+
+// Cancel 取消未支付的订单并归还库存
+func (s *orderService) Cancel(ctx context.Context, orderID int64, userID uint64) (*model.TbVoucherOrder, error) {
+	order, err := s.getOrder(ctx, orderID, userID, repository.OrderStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.cancel(ctx, order); err != nil {
+		return nil, err
+	}
+	s.removeUnpaidOrder(ctx, orderID)
+	return order, nil
+}
+
+// CancelExpired 支付超时后由系统取消订单, 订单已不是未支付状态时忽略
+func (s *orderService) CancelExpired(ctx context.Context, orderID int64) error {
+	order, err := s.voucherOrderRepo.GetVoucherOrderByID(ctx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if order.Status != repository.OrderStatusUnpaid {
+		return nil
+	}
+
+	err = s.cancel(ctx, order)
+	if errors.Is(err, repository.ErrOrderStatusChanged) {
+		return nil // 用户恰好在此时支付或取消
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.Info("unpaid order cancelled", "order_id", orderID, "voucher_id", order.VoucherID)
+	return nil
+}
+
+// cancel 取消订单并归还 MySQL 与 Redis 库存
+func (s *orderService) cancel(ctx context.Context, order *model.TbVoucherOrder) error {
+	from := order.Status
+	order.Status = repository.OrderStatusCancelled
+	if err := s.voucherOrderRepo.UpdateOrderStatusAndReturnStock(ctx, order, from); err != nil {
+		return err
+	}
+	s.returnStockCache(ctx, order)
+	return nil
+}
+
+// Refund 已支付未核销的订单先进入退款中, 支付渠道退款成功后变为已退款并归还库存
+// 渠道退款失败时订单停留在退款中, 再次调用 Refund 会重试; 持有订单的支付锁时调用渠道, 并发的重试直接返回 ErrOrderRefunding,
+// 同一订单的退款单号不变, 锁过期后的重复请求也能由渠道去重
+func (s *orderService) Refund(ctx context.Context, orderID int64, userID uint64) (*model.TbVoucherOrder, error) {
+	unlock, err := s.lockOrderPayment(ctx, orderID, ErrOrderRefunding)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	order, err := s.getOrder(ctx, orderID, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != repository.OrderStatusRefunding {
+		if !canTransitOrder(order.Status, repository.OrderStatusRefunding) {
+			return nil, ErrInvalidOrderTransition
+		}
+		from := order.Status
+		order.Status = repository.OrderStatusRefunding
+		if err = s.voucherOrderRepo.UpdateOrderStatus(ctx, order, from); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.gateway.Refund(ctx, &payment.RefundRequest{
+		RefundNo: fmt.Sprintf("refund-%d", order.ID),
+		OrderID:  order.ID,
+		UserID:   userID,
+		Amount:   order.PayValue,
+		PayType:  order.PayType,
+	})
+	if err != nil {
+		s.logger.Error("failed to refund order", "err", err, "order_id", orderID)
+		return nil, fmt.Errorf("%w: %w", ErrRefundFailed, err)
+	}
+
+	order.Status = repository.OrderStatusRefunded
+	order.RefundTime = time.Now()
+	if err = s.voucherOrderRepo.UpdateOrderStatusAndReturnStock(ctx, order, repository.OrderStatusRefunding); err != nil {
+		s.logger.Error("order refunded but failed to update status", "err", err, "order_id", orderID)
+		return nil, err
+	}
+	s.returnStockCache(ctx, order)
+	return order, nil
+}
+
+// lockOrderPayment 获取订单的支付锁, 返回释放锁的函数; 锁已被其他请求持有时返回 busyErr
+func (s *orderService) lockOrderPayment(ctx context.Context, orderID int64, busyErr error) (func(), error) {
+	token, err := newNonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	locked, err := s.voucherOrderRepo.LockOrderPayment(ctx, orderID, token, orderPayLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock order %d for payment: %w", orderID, err)
+	}
+	if !locked {
+		return nil, busyErr
+	}
+	return func() {
+		if err := s.voucherOrderRepo.UnlockOrderPayment(ctx, orderID, token); err != nil {
+			s.logger.Warn("failed to release order payment lock", "err", err, "order_id", orderID)
+		}
+	}, nil
+}
+
+// getOrder 查询用户自己的订单, 并校验能否转移到 to 状态; to 为 0 时不校验
+func (s *orderService) getOrder(ctx context.Context, orderID int64, userID uint64, to uint8) (*model.TbVoucherOrder, error) {
+	order, err := s.voucherOrderRepo.GetVoucherOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if to != 0 && !canTransitOrder(order.Status, to) {
+		return nil, ErrInvalidOrderTransition
+	}
+	if err = fillOrderSnapshots(ctx, s.voucherRepo, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// hasOrderSnapshot 增加快照字段之前的订单, 以及没有快照的旧版本订单消息, 快照字段均为零值
+func hasOrderSnapshot(order *model.TbVoucherOrder) bool {
+	return order.ShopID != 0 || order.Title != "" || order.PayValue != 0
+}
+
+// fillOrderSnapshots 没有快照的订单按优惠券当前的信息补全, 同一张券只查询一次
+func fillOrderSnapshots(ctx context.Context, voucherRepo repository.VoucherRepo, orders ...*model.TbVoucherOrder) error {
+	vouchers := make(map[uint64]*model.TbVoucher)
+	for _, order := range orders {
+		if hasOrderSnapshot(order) {
+			continue
+		}
+		voucher, ok := vouchers[order.VoucherID]
+		if !ok {
+			var err error
+			if voucher, err = voucherRepo.GetVoucherByID(ctx, order.VoucherID); err != nil {
+				return fmt.Errorf("failed to get voucher %d: %w", order.VoucherID, err)
+			}
+			vouchers[order.VoucherID] = voucher
+		}
+		order.ShopID = voucher.ShopID
+		order.Title = voucher.Title
+		order.PayValue = voucher.PayValue
+		order.ActualValue = voucher.ActualValue
+	}
+	return nil
+}
+
+// returnStockCache 归还 Redis 库存, 失败时仅记录日志, 由 MySQL 库存为准
+func (s *orderService) returnStockCache(ctx context.Context, order *model.TbVoucherOrder) {
+	keys := []string{
+		strconv.FormatUint(order.VoucherID, 10),
+		strconv.FormatUint(order.UserID, 10),
+	}
+	if _, err := s.voucherRepo.ExecScript(ctx, returnSeckillStock, keys); err != nil {
+		s.logger.Error("failed to return seckill stock cache", "err", err, "order_id", order.ID)
+	}
+}
+
+// removeUnpaidOrder 订单已支付或取消, 从延时队列中移除; 失败时由超时任务检查状态后忽略
+func (s *orderService) removeUnpaidOrder(ctx context.Context, orderID int64) {
+	if err := s.voucherOrderRepo.RemoveUnpaidOrder(ctx, orderID); err != nil {
+		s.logger.Error("failed to remove order from unpaid delay queue", "err", err, "order_id", orderID)
+	}
+}
+
+func NewOrderService(voucherOrderRepo repository.VoucherOrderRepo, voucherRepo repository.VoucherRepo, shopRepo repository.ShopRepo, gateway payment.Gateway, logger *slog.Logger) OrderService {
+	return &orderService{
+		voucherOrderRepo: voucherOrderRepo,
+		voucherRepo:      voucherRepo,
+		shopRepo:         shopRepo,
+		gateway:          gateway,
+		logger:           logger,
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/payment"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrderRepo 内存中的订单与支付锁, 未用到的方法由嵌入的接口提供(调用时 panic)
+type fakeOrderRepo struct {
+	repository.VoucherOrderRepo
+	mu     sync.Mutex
+	orders map[int64]*model.TbVoucherOrder
+	locks  map[int64]string
+	nonces map[int64]string
+}
+
+func newFakeOrderRepo(orders ...*model.TbVoucherOrder) *fakeOrderRepo {
+	r := &fakeOrderRepo{orders: make(map[int64]*model.TbVoucherOrder), locks: make(map[int64]string), nonces: make(map[int64]string)}
+	for _, o := range orders {
+		r.orders[o.ID] = o
+	}
+	return r
+}
+
+func (r *fakeOrderRepo) GetVoucherOrderByID(ctx context.Context, orderID int64) (*model.TbVoucherOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := *r.orders[orderID]
+	return &o, nil
+}
+
+func (r *fakeOrderRepo) UpdateOrderStatus(ctx context.Context, order *model.TbVoucherOrder, from uint8) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.orders[order.ID].Status != from {
+		return repository.ErrOrderStatusChanged
+	}
+	o := *order
+	r.orders[order.ID] = &o
+	return nil
+}
+
+func (r *fakeOrderRepo) UpdateOrderStatusAndReturnStock(ctx context.Context, order *model.TbVoucherOrder, from uint8) error {
+	return r.UpdateOrderStatus(ctx, order, from)
+}
+
+func (r *fakeOrderRepo) setStatus(orderID int64, status uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[orderID].Status = status
+}
+
+func (r *fakeOrderRepo) LockOrderPayment(ctx context.Context, orderID int64, token string, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.locks[orderID]; ok {
+		return false, nil
+	}
+	r.locks[orderID] = token
+	return true, nil
+}
+
+func (r *fakeOrderRepo) UnlockOrderPayment(ctx context.Context, orderID int64, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.locks[orderID] == token {
+		delete(r.locks, orderID)
+	}
+	return nil
+}
+
+func (r *fakeOrderRepo) SetRedeemNonce(ctx context.Context, orderID int64, nonce string, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nonces[orderID] = nonce
+	return nil
+}
+
+func (r *fakeOrderRepo) ConsumeRedeemNonce(ctx context.Context, orderID int64, nonce string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nonces[orderID] != nonce {
+		return false, nil
+	}
+	delete(r.nonces, orderID)
+	return true, nil
+}
+
+func (r *fakeOrderRepo) RemoveUnpaidOrder(ctx context.Context, orderID int64) error {
+	return nil
+}
+
+// fakeGateway 记录扣款与退款次数, onPay 在扣款时调用
+type fakeGateway struct {
+	mu        sync.Mutex
+	pays      int
+	refunds   int
+	refundNos []string
+	onPay     func()
+}
+
+func (g *fakeGateway) Pay(ctx context.Context, req *payment.PayRequest) (*payment.PayResult, error) {
+	if g.onPay != nil {
+		g.onPay()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pays++
+	return &payment.PayResult{TradeNo: "t"}, nil
+}
+
+func (g *fakeGateway) Refund(ctx context.Context, req *payment.RefundRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refunds++
+	g.refundNos = append(g.refundNos, req.RefundNo)
+	return nil
+}
+
+func newUnpaidOrder() *model.TbVoucherOrder {
+	return &model.TbVoucherOrder{ID: 1, UserID: 2, VoucherID: 3, ShopID: 4, PayValue: 800, Status: repository.OrderStatusUnpaid}
+}
+
+func TestCanTransitOrder(t *testing.T) {
+	tests := []struct {
+		from, to uint8
+		want     bool
+	}{
+		{repository.OrderStatusUnpaid, repository.OrderStatusPaid, true},
+		{repository.OrderStatusUnpaid, repository.OrderStatusCancelled, true},
+		{repository.OrderStatusUnpaid, repository.OrderStatusUsed, false},
+		{repository.OrderStatusPaid, repository.OrderStatusUsed, true},
+		{repository.OrderStatusPaid, repository.OrderStatusRefunding, true},
+		{repository.OrderStatusPaid, repository.OrderStatusCancelled, false},
+		{repository.OrderStatusRefunding, repository.OrderStatusRefunded, true},
+		{repository.OrderStatusUsed, repository.OrderStatusRefunding, false},
+		{repository.OrderStatusCancelled, repository.OrderStatusPaid, false},
+		{repository.OrderStatusRefunded, repository.OrderStatusPaid, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, canTransitOrder(tt.from, tt.to), "%d -> %d", tt.from, tt.to)
+	}
+}
+
+// 扣款进行中的并发支付请求直接失败, 只扣款一次
+func TestPayConcurrent(t *testing.T) {
+	repo := newFakeOrderRepo(newUnpaidOrder())
+	entered, release := make(chan struct{}), make(chan struct{})
+	gateway := &fakeGateway{onPay: func() {
+		close(entered)
+		<-release
+	}}
+	svc := NewOrderService(repo, nil, nil, gateway, slog.Default())
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		_, err := svc.Pay(ctx, 1, 2, &PayOrderDTO{PayType: payment.PayTypeBalance})
+		done <- err
+	}()
+	<-entered
+	_, err := svc.Pay(ctx, 1, 2, &PayOrderDTO{PayType: payment.PayTypeBalance})
+	assert.ErrorIs(t, err, ErrOrderPaying)
+	close(release)
+	require.NoError(t, <-done)
+
+	// 支付完成后再次支付不会扣款
+	_, err = svc.Pay(ctx, 1, 2, &PayOrderDTO{PayType: payment.PayTypeBalance})
+	assert.ErrorIs(t, err, ErrInvalidOrderTransition)
+	assert.Equal(t, 1, gateway.pays)
+	assert.Equal(t, 0, gateway.refunds)
+}
+
+// 扣款期间订单被超时取消, 状态更新失败后退回扣款
+func TestPayRefundsWhenOrderCancelled(t *testing.T) {
+	repo := newFakeOrderRepo(newUnpaidOrder())
+	gateway := &fakeGateway{}
+	gateway.onPay = func() { repo.setStatus(1, repository.OrderStatusCancelled) }
+	svc := NewOrderService(repo, nil, nil, gateway, slog.Default())
+
+	_, err := svc.Pay(context.Background(), 1, 2, &PayOrderDTO{PayType: payment.PayTypeBalance})
+	assert.ErrorIs(t, err, repository.ErrOrderStatusChanged)
+	assert.Equal(t, 1, gateway.pays)
+	assert.Equal(t, 1, gateway.refunds)
+	assert.Empty(t, repo.locks)
+}
+
+type noopScriptRepo struct {
+	repository.VoucherRepo
+}
+
+func (r *noopScriptRepo) ExecScript(ctx context.Context, script string, keys []string, args ...any) (int64, error) {
+	return 1, nil
+}
+
+// 退款与支付共用订单锁, 并发的退款重试不会重复调用渠道; 重试使用相同的退款单号
+func TestRefundRetryHoldsOrderLock(t *testing.T) {
+	order := newUnpaidOrder()
+	order.Status = repository.OrderStatusRefunding
+	repo := newFakeOrderRepo(order)
+	gateway := &fakeGateway{}
+	svc := NewOrderService(repo, &noopScriptRepo{}, nil, gateway, slog.Default())
+	ctx := context.Background()
+
+	repo.locks[1] = "other"
+	_, err := svc.Refund(ctx, 1, 2)
+	assert.ErrorIs(t, err, ErrOrderRefunding)
+	assert.Equal(t, 0, gateway.refunds)
+
+	delete(repo.locks, 1)
+	refunded, err := svc.Refund(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, repository.OrderStatusRefunded, refunded.Status)
+	assert.Equal(t, []string{"refund-1"}, gateway.refundNos)
+	assert.Empty(t, repo.locks)
+
+	_, err = svc.Refund(ctx, 1, 2)
+	assert.ErrorIs(t, err, ErrInvalidOrderTransition)
+	assert.Equal(t, 1, gateway.refunds)
+}
+
+type fakeShopRepo struct {
+	repository.ShopRepo
+	ownerID uint64
+}
+
+func (r *fakeShopRepo) GetShopByID(ctx context.Context, id uint64) (*model.TbShop, error) {
+	return &model.TbShop{ID: id, OwnerID: r.ownerID}, nil
+}
+
+func setRedeemOptions(t *testing.T, opts *config.OrderSetting) {
+	old := config.OrderOptions
+	t.Cleanup(func() { config.OrderOptions = old })
+	config.OrderOptions = opts
+}
+
+func newPaidOrderService(t *testing.T) (OrderService, *fakeOrderRepo) {
+	order := newUnpaidOrder()
+	order.Status = repository.OrderStatusPaid
+	repo := newFakeOrderRepo(order)
+	return NewOrderService(repo, nil, &fakeShopRepo{ownerID: 9}, &fakeGateway{}, slog.Default()), repo
+}
+
+func TestRedeemToken(t *testing.T) {
+	setRedeemOptions(t, &config.OrderSetting{RedeemSecret: "redeem-secret"})
+	svc, repo := newPaidOrderService(t)
+	ctx := context.Background()
+
+	token, err := svc.IssueRedeemToken(ctx, 1, 2)
+	require.NoError(t, err)
+	// 未配置有效期时使用默认值
+	assert.WithinDuration(t, time.Now().Add(defaultRedeemTokenTTL), token.ExpiresAt, time.Second)
+
+	// 只有订单所属店铺的商户可以核销
+	_, err = svc.Redeem(ctx, &RedeemDTO{Token: token.Token}, 8)
+	assert.ErrorIs(t, err, ErrNotShopOwner)
+	order, err := svc.Redeem(ctx, &RedeemDTO{Token: token.Token}, 9)
+	require.NoError(t, err)
+	assert.Equal(t, repository.OrderStatusUsed, order.Status)
+
+	// 同一核销码不能重复使用
+	repo.setStatus(1, repository.OrderStatusPaid)
+	_, err = svc.Redeem(ctx, &RedeemDTO{Token: token.Token}, 9)
+	assert.ErrorIs(t, err, ErrInvalidRedeemToken)
+}
+
+// 重新生成核销码后旧的核销码失效
+func TestRedeemTokenReissued(t *testing.T) {
+	setRedeemOptions(t, &config.OrderSetting{RedeemSecret: "redeem-secret", RedeemTokenTTL: time.Minute})
+	svc, _ := newPaidOrderService(t)
+	ctx := context.Background()
+
+	old, err := svc.IssueRedeemToken(ctx, 1, 2)
+	require.NoError(t, err)
+	_, err = svc.IssueRedeemToken(ctx, 1, 2)
+	require.NoError(t, err)
+	_, err = svc.Redeem(ctx, &RedeemDTO{Token: old.Token}, 9)
+	assert.ErrorIs(t, err, ErrInvalidRedeemToken)
+}
+
+func TestParseRedeemToken(t *testing.T) {
+	now := time.Now()
+	token := signRedeemToken(&redeemClaims{OrderID: 1, Nonce: "n", ExpiresAt: now.Add(time.Minute).Unix()}, "redeem-secret")
+
+	claims, err := parseRedeemToken(token, "redeem-secret", now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.OrderID)
+
+	_, err = parseRedeemToken(token, "redeem-secret", now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrInvalidRedeemToken, "expired")
+	_, err = parseRedeemToken(token, "other-secret", now)
+	assert.ErrorIs(t, err, ErrInvalidRedeemToken, "wrong secret")
+}
+
+// 未配置签名密钥时不签发核销码, 也不会退回使用 JWT 密钥
+func TestRedeemTokenWithoutSecret(t *testing.T) {
+	setRedeemOptions(t, nil)
+	svc, _ := newPaidOrderService(t)
+
+	_, err := svc.IssueRedeemToken(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, ErrRedeemSecretMissing)
+	_, err = svc.Redeem(context.Background(), &RedeemDTO{Token: "x.y"}, 9)
+	assert.ErrorIs(t, err, ErrRedeemSecretMissing)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+)
+
+var (
+	ErrInvalidRedeemToken  = errors.New("redeem token is invalid, expired or already used")
+	ErrRedeemSecretMissing = errors.New("redeem token secret is not configured")
+)
+
+// defaultRedeemTokenTTL 未配置 RedeemTokenTTL 时核销码的有效期
+const defaultRedeemTokenTTL = 2 * time.Minute
+
+// redeemClaims 核销码内容, Nonce 同时保存在 Redis 中, 核销时删除以防重放
+type redeemClaims struct {
+	OrderID   int64
+	Nonce     string
+	ExpiresAt int64
+}
+
+// redeemTokenOptions 返回核销码的有效期和签名密钥, 未配置密钥时不能签发和核销
+func redeemTokenOptions() (time.Duration, string, error) {
+	opts := config.OrderOptions
+	if opts == nil || opts.RedeemSecret == "" {
+		return 0, "", ErrRedeemSecretMissing
+	}
+	ttl := opts.RedeemTokenTTL
+	if ttl <= 0 {
+		ttl = defaultRedeemTokenTTL
+	}
+	return ttl, opts.RedeemSecret, nil
+}
+
+// newNonce 随机生成的 32 位十六进制串, 用作核销码的 nonce 和锁的持有者标识
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// signRedeemToken 生成核销码: base64(orderID.nonce.expiresAt).base64(hmac-sha256)
+func signRedeemToken(claims *redeemClaims, secret string) string {
+	payload := fmt.Sprintf("%d.%s.%d", claims.OrderID, claims.Nonce, claims.ExpiresAt)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseRedeemToken 校验签名和有效期, 不检查 nonce 是否已被使用
+func parseRedeemToken(token, secret string, now time.Time) (*redeemClaims, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidRedeemToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidRedeemToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return nil, ErrInvalidRedeemToken
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidRedeemToken
+	}
+
+	var claims redeemClaims
+	if _, err = fmt.Sscanf(strings.ReplaceAll(string(payload), ".", " "), "%d %s %d",
+		&claims.OrderID, &claims.Nonce, &claims.ExpiresAt); err != nil {
+		return nil, ErrInvalidRedeemToken
+	}
+	if now.Unix() > claims.ExpiresAt {
+		return nil, ErrInvalidRedeemToken
+	}
+	return &claims, nil
+}
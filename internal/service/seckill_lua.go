@@ -10,17 +10,40 @@ local voucherID = KEYS[1]
 local userID = KEYS[2]
 -- 1.3.订单id
 local orderID = KEYS[3]
+-- 1.4.当前时间(unix 秒)
+local now = tonumber(ARGV[1])
+-- 1.5.订单状态有效期(秒)
+local statusTTL = tonumber(ARGV[2])
+-- 1.6.订单事件版本
+local eventVersion = tonumber(ARGV[3])
 
 -- 2.数据key
 -- 2.1.库存key  ..lua的字符串拼接
 local stockKey = 'seckill:stock:' .. voucherID
 -- 2.2.订单key
 local orderKey = 'seckill:order:' .. voucherID
+-- 2.3.状态key, 保存上下架状态与秒杀时间窗口
+local infoKey = 'seckill:info:' .. voucherID
+-- 2.4.订单创建状态key, 供客户端轮询
+local statusKey = 'seckill:order:status:' .. orderID
 
 -- 3.脚本业务
+-- 3.0.判断优惠券是否在售: 已下架/已过期/抽签券, 或不在秒杀时间窗口内, 返回3
+local info = redis.call('hmget', infoKey, 'status', 'begin', 'end', 'mode', 'shop_id', 'title', 'pay_value', 'actual_value')
+if(info[1] and info[1] ~= '1') then
+    return 3
+end
+-- 预约抽签券不能直接秒杀
+if(info[4] and info[4] ~= '0') then
+    return 3
+end
+if(info[2] and now < tonumber(info[2])) or (info[3] and now > tonumber(info[3])) then
+    return 3
+end
 -- 3.1.判断库存是否充足 get stockKey  tonumber()将字符串转换为数字
-if(tonumber(redis.call('get', stockKey)) <= 0) then
-    -- 3.2.库存不足,返回1
+local stock = tonumber(redis.call('get', stockKey))
+if(stock == nil or stock <= 0) then
+    -- 3.2.库存不足或库存已被清理,返回1
     return 1
 end
 -- 3.2.判断用户是否下单 SISMEMBER:判断set集合中是否存在某个元素
@@ -32,7 +55,149 @@ end
   redis.call('incrby', stockKey, -1)
 -- 3.5.下单(保存用户) sadd orderKey userID
   redis.call('sadd', orderKey, userID)
--- 3.6.发送消息到队列中， XADD stream:orders * k1 v1 k2 v2 ...
-	redis.call('xadd', 'stream:orders', '*', 'userID', userID, 'voucherID', voucherID, 'orderID', orderID)
+-- 3.6.发送订单事件到队列中, ID 保持字符串编码, 附带下单时的优惠券快照, XADD stream:orders * event {...}
+  local event = {v = eventVersion, order_id = orderID, user_id = userID, voucher_id = voucherID}
+  if(info[5] and info[6] and info[7] and info[8]) then
+      event.snapshot = {shop_id = info[5], title = info[6], pay_value = info[7], actual_value = info[8]}
+  end
+  redis.call('xadd', 'stream:orders', '*', 'event', cjson.encode(event))
+-- 3.7.记录订单状态为 pending 及优惠券快照, 消费者落库后更新
+  redis.call('hset', statusKey, 'status', 'pending', 'userID', userID, 'voucherID', voucherID)
+  if(event.snapshot) then
+      redis.call('hset', statusKey, 'shop_id', info[5], 'title', info[6], 'pay_value', info[7], 'actual_value', info[8])
+  end
+  redis.call('expire', statusKey, statusTTL)
 return 0
 `
+
+// adjustStock 原子地调整 Redis 中的秒杀库存
+// Redis 库存已扣除所有已发放(包括尚未落库)的订单, 因此调整后不小于 0 即保证不低于已发放数量
+const adjustStock = `
+local stockKey = 'seckill:stock:' .. KEYS[1]
+local delta = tonumber(ARGV[1])
+
+local stock = tonumber(redis.call('get', stockKey))
+-- 库存不存在(未创建或已过期清理),返回-1
+if(stock == nil) then
+    return -1
+end
+-- 调整后库存小于0,返回-2
+if(stock + delta < 0) then
+    return -2
+end
+return redis.call('incrby', stockKey, delta)
+`
+
+// registerLottery 预约抽签: 仅限上架中的抽签券, 且必须在开始时间之前预约
+const registerLottery = `
+local voucherID = KEYS[1]
+local userID = KEYS[2]
+local now = tonumber(ARGV[1])
+
+local infoKey = 'seckill:info:' .. voucherID
+local registerKey = 'seckill:lottery:register:' .. voucherID
+
+local info = redis.call('hmget', infoKey, 'status', 'begin', 'mode')
+-- 不是抽签券、已下架或已开始抽签,返回3
+if(info[1] ~= '1' or info[3] ~= '1' or not info[2] or now >= tonumber(info[2])) then
+    return 3
+end
+-- 重复预约,返回2
+if(redis.call('sadd', registerKey, userID) == 0) then
+    return 2
+end
+return 0
+`
+
+// issueLotteryOrders 为中签用户发放订单, 与先到先得模式一样扣减库存、记录一人一单并发送到 stream:orders
+// 已发放过订单的中签用户会被跳过, 同一批可以重复执行; 发放数量不超过当前库存, 返回库存不足未发放的中签用户数
+// ARGV[1] 为订单状态有效期(秒), ARGV[2] 为订单事件版本, 之后依次为 userID1, orderID1, userID2, orderID2 ...
+const issueLotteryOrders = `
+local voucherID = KEYS[1]
+local statusTTL = tonumber(ARGV[1])
+local eventVersion = tonumber(ARGV[2])
+local stockKey = 'seckill:stock:' .. voucherID
+local orderKey = 'seckill:order:' .. voucherID
+local winnerKey = 'seckill:lottery:winner:' .. voucherID
+local infoKey = 'seckill:info:' .. voucherID
+
+-- 下单时的优惠券快照, 状态缓存中没有快照时由消费者按当前优惠券补全
+local snapshot = nil
+local info = redis.call('hmget', infoKey, 'shop_id', 'title', 'pay_value', 'actual_value')
+if(info[1] and info[2] and info[3] and info[4]) then
+    snapshot = {shop_id = info[1], title = info[2], pay_value = info[3], actual_value = info[4]}
+end
+
+-- 重复抽签或并发调整库存后剩余库存可能少于中签人数, 库存不存在时视为 0
+local stock = tonumber(redis.call('get', stockKey)) or 0
+local issued = 0
+local shortfall = 0
+for i = 3, #ARGV, 2 do
+    local userID = ARGV[i]
+    local orderID = ARGV[i + 1]
+    if(redis.call('hexists', winnerKey, userID) == 0 and redis.call('sismember', orderKey, userID) == 0) then
+        if(issued >= stock) then
+            shortfall = shortfall + 1
+        else
+            redis.call('sadd', orderKey, userID)
+            redis.call('hset', winnerKey, userID, orderID)
+            local event = {v = eventVersion, order_id = orderID, user_id = userID, voucher_id = voucherID, snapshot = snapshot}
+            redis.call('xadd', 'stream:orders', '*', 'event', cjson.encode(event))
+            local statusKey = 'seckill:order:status:' .. orderID
+            redis.call('hset', statusKey, 'status', 'pending', 'userID', userID, 'voucherID', voucherID)
+            if(snapshot) then
+                redis.call('hset', statusKey, 'shop_id', info[1], 'title', info[2], 'pay_value', info[3], 'actual_value', info[4])
+            end
+            redis.call('expire', statusKey, statusTTL)
+            issued = issued + 1
+        end
+    end
+end
+if(issued > 0) then
+    redis.call('incrby', stockKey, -issued)
+end
+return shortfall
+`
+
+// returnSeckillStock 订单取消或退款后归还 Redis 库存, 并移出一人一单集合, 允许用户重新购买
+// 库存 key 不存在(券已过期清理)时不再恢复库存
+const returnSeckillStock = `
+local voucherID = KEYS[1]
+local userID = KEYS[2]
+local stockKey = 'seckill:stock:' .. voucherID
+local orderKey = 'seckill:order:' .. voucherID
+
+if(redis.call('exists', stockKey) == 1) then
+    redis.call('incrby', stockKey, 1)
+end
+redis.call('srem', orderKey, userID)
+return 0
+`
+
+// compensateDeadOrder 死信中的订单不会再创建时归还 Redis 库存, ARGV[2] 为 1 时同时移出一人一单集合;
+// 先按订单 ID 写入补偿标记, 同一订单只补偿一次, 已补偿过返回 0, 否则返回 1
+// KEYS: 优惠券 ID, 用户 ID, 订单 ID; ARGV: 补偿标记的有效期(秒), 是否释放用户的一人一单名额
+const compensateDeadOrder = `
+local voucherID = KEYS[1]
+local userID = KEYS[2]
+local markKey = 'seckill:compensated:' .. KEYS[3]
+local stockKey = 'seckill:stock:' .. voucherID
+local orderKey = 'seckill:order:' .. voucherID
+
+if(not redis.call('set', markKey, 1, 'nx', 'ex', tonumber(ARGV[1]))) then
+    return 0
+end
+if(redis.call('exists', stockKey) == 1) then
+    redis.call('incrby', stockKey, 1)
+end
+if(ARGV[2] == '1') then
+    redis.call('srem', orderKey, userID)
+end
+return 1
+`
+
+// isDeadOrderCompensated 死信中的订单已由 compensateDeadOrder 归还库存时返回 1
+// KEYS: 订单 ID
+const isDeadOrderCompensated = `
+return redis.call('exists', 'seckill:compensated:' .. KEYS[1])
+`
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/event"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// reconcileRecheckDelay 扣减 Redis 库存与订单落库之间存在时间差, 发现不一致时间隔一段时间再核对一次
+const reconcileRecheckDelay = 500 * time.Millisecond
+
+// StockDrift 一张秒杀券的库存核对结果, 已扣减 Redis 库存但尚未落库的订单记为 InFlight 和 DeadLetters;
+// 一致时 CacheStock + InFlight + DeadLetters == DBStock, Holders == ActiveOrders + InFlight + DeadLetters
+type StockDrift struct {
+	VoucherID    uint64 `json:"voucher_id"`
+	CacheStock   int64  `json:"cache_stock"`
+	DBStock      int64  `json:"db_stock"`
+	InFlight     int64  `json:"in_flight"`     // 订单流中未确认和等待重试的订单
+	DeadLetters  int64  `json:"dead_letters"`  // 死信队列中的订单
+	Holders      int64  `json:"holders"`       // 一人一单集合中的用户数
+	ActiveOrders int64  `json:"active_orders"` // MySQL 中占用库存的订单数
+	StockDiff    int64  `json:"stock_diff"`
+	OrderDiff    int64  `json:"order_diff"`
+	RepairTo     int64  `json:"repair_to"` // 以 MySQL 为准 Redis 库存应为的值, 小于 0 时无法修复
+	Repaired     bool   `json:"repaired"`
+}
+
+type StockReconcileReport struct {
+	Checked  int           `json:"checked"`
+	Drifts   []*StockDrift `json:"drifts"`
+	Repaired int           `json:"repaired"`
+}
+
+type StockReconcileService interface {
+	Reconcile(ctx context.Context, repair bool) (*StockReconcileReport, error)
+}
+
+type stockReconcileService struct {
+	queues           []repository.MessageQueue // 第一个为订单消息队列, 之后为待转发消息所在的队列
+	voucherRepo      repository.VoucherRepo
+	voucherOrderRepo repository.VoucherOrderRepo
+	logger           *slog.Logger
+}
+
+// Reconcile 核对所有上架中的秒杀券, 两次核对结果相同才认为不一致;
+// repair 为 true 时以 MySQL 为准修复 Redis 库存, 否则只记录将要执行的修复
+func (s *stockReconcileService) Reconcile(ctx context.Context, repair bool) (*StockReconcileReport, error) {
+	ids, err := s.voucherRepo.ListOnlineSeckillVoucherIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list online seckill vouchers: %w", err)
+	}
+	drifts, err := s.check(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(drifts) > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(reconcileRecheckDelay):
+		}
+		driftIDs := make([]uint64, 0, len(drifts))
+		for _, d := range drifts {
+			driftIDs = append(driftIDs, d.VoucherID)
+		}
+		again, err := s.check(ctx, driftIDs)
+		if err != nil {
+			return nil, err
+		}
+		drifts = slices.DeleteFunc(again, func(d *StockDrift) bool {
+			i := slices.IndexFunc(drifts, func(prev *StockDrift) bool { return prev.VoucherID == d.VoucherID })
+			return drifts[i].StockDiff != d.StockDiff || drifts[i].OrderDiff != d.OrderDiff
+		})
+	}
+
+	report := &StockReconcileReport{Checked: len(ids), Drifts: drifts}
+	for _, d := range drifts {
+		s.logger.Warn("seckill stock drift detected",
+			"voucher_id", d.VoucherID,
+			"cache_stock", d.CacheStock,
+			"db_stock", d.DBStock,
+			"in_flight", d.InFlight,
+			"dead_letters", d.DeadLetters,
+			"holders", d.Holders,
+			"active_orders", d.ActiveOrders,
+			"stock_diff", d.StockDiff,
+			"order_diff", d.OrderDiff,
+			"repair_to", d.RepairTo,
+			"dry_run", !repair,
+		)
+		if !repair || d.StockDiff == 0 {
+			continue
+		}
+		if d.RepairTo < 0 {
+			s.logger.Error("cannot repair seckill stock cache below zero", "voucher_id", d.VoucherID, "repair_to", d.RepairTo)
+			continue
+		}
+		ok, err := s.voucherRepo.CompareAndSetVoucherStockCache(ctx, d.VoucherID, d.CacheStock, d.RepairTo)
+		if err != nil {
+			return report, fmt.Errorf("failed to repair stock cache of voucher %d: %w", d.VoucherID, err)
+		}
+		if !ok {
+			s.logger.Warn("seckill stock cache changed during reconcile, skip repair", "voucher_id", d.VoucherID)
+			continue
+		}
+		d.Repaired = true
+		report.Repaired++
+		s.logger.Info("seckill stock cache repaired", "voucher_id", d.VoucherID, "from", d.CacheStock, "to", d.RepairTo)
+	}
+
+	s.logger.Info("seckill stock reconciled", "checked", report.Checked, "drifts", len(report.Drifts), "repaired", report.Repaired)
+	return report, nil
+}
+
+// check 核对指定的秒杀券, 返回不一致的结果; 没有库存缓存(未预热或已清理)的券跳过
+func (s *stockReconcileService) check(ctx context.Context, ids []uint64) ([]*StockDrift, error) {
+	inFlight, deadLetters, err := s.unappliedOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []*StockDrift
+	for _, id := range ids {
+		d := &StockDrift{VoucherID: id, InFlight: inFlight[id], DeadLetters: deadLetters[id]}
+		d.CacheStock, err = s.voucherRepo.GetVoucherStockCache(ctx, id)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock cache of voucher %d: %w", id, err)
+		}
+		if d.Holders, err = s.voucherRepo.CountSeckillOrderUsers(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to count order users of voucher %d: %w", id, err)
+		}
+		seckillVoucher, err := s.voucherRepo.GetSeckillVoucherByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get seckill voucher %d: %w", id, err)
+		}
+		d.DBStock = seckillVoucher.Stock
+		if d.ActiveOrders, err = s.voucherOrderRepo.CountActiveOrders(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to count orders of voucher %d: %w", id, err)
+		}
+
+		unapplied := d.InFlight + d.DeadLetters
+		d.StockDiff = d.CacheStock + unapplied - d.DBStock
+		d.OrderDiff = d.Holders - d.ActiveOrders - unapplied
+		d.RepairTo = d.DBStock - unapplied
+		if d.StockDiff != 0 || d.OrderDiff != 0 {
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts, nil
+}
+
+// unappliedOrders 按优惠券统计队列和死信中尚未落库的订单数, 同一订单只计一次, 在死信中的记为死信
+func (s *stockReconcileService) unappliedOrders(ctx context.Context) (inFlight, deadLetters map[uint64]int64, err error) {
+	vouchers := make(map[int64]uint64) // 订单 ID -> 优惠券 ID
+	dead := make(map[int64]bool)
+	add := func(msgs []repository.Message, isDead bool) {
+		for _, msg := range msgs {
+			order, err := event.DecodeOrder(msg.Values)
+			if err != nil {
+				continue
+			}
+			vouchers[order.OrderID] = order.VoucherID
+			dead[order.OrderID] = dead[order.OrderID] || isDead
+		}
+	}
+
+	for _, mq := range s.queues {
+		msgs, err := mq.InFlightMessages(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list in-flight messages: %w", err)
+		}
+		add(msgs, false)
+	}
+	for start := "-"; ; {
+		msgs, err := s.queues[0].RangeMessages(ctx, repository.DeadLetterStreamKey, start, "+", defaultDeadLetterPageSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list dead letters: %w", err)
+		}
+		add(msgs, true)
+		if len(msgs) < defaultDeadLetterPageSize {
+			break
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+
+	// 消费者落库后才确认消息, 已落库的订单不再占用差额
+	orderIDs := make([]int64, 0, len(vouchers))
+	for orderID := range vouchers {
+		orderIDs = append(orderIDs, orderID)
+	}
+	for chunk := range slices.Chunk(orderIDs, defaultDeadLetterPageSize) {
+		existing, err := s.voucherOrderRepo.ListExistingOrderIDs(ctx, chunk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check orders: %w", err)
+		}
+		for _, orderID := range existing {
+			delete(vouchers, orderID)
+		}
+	}
+
+	inFlight, deadLetters = make(map[uint64]int64), make(map[uint64]int64)
+	for orderID, voucherID := range vouchers {
+		if dead[orderID] {
+			deadLetters[voucherID]++
+		} else {
+			inFlight[voucherID]++
+		}
+	}
+	return inFlight, deadLetters, nil
+}
+
+func NewStockReconcileService(mq repository.MessageQueue, rdb *redis.Client, voucherRepo repository.VoucherRepo, voucherOrderRepo repository.VoucherOrderRepo, logger *slog.Logger) StockReconcileService {
+	queues := []repository.MessageQueue{mq}
+	if opts := config.MQOptions; opts != nil && opts.Broker != "" && opts.Broker != repository.BrokerRedis {
+		// 秒杀脚本写入 Redis 的订单消息可能还未转发到消息中间件
+		queues = append(queues, repository.NewRedisMessageQueue(rdb))
+	}
+	return &stockReconcileService{
+		queues:           queues,
+		voucherRepo:      voucherRepo,
+		voucherOrderRepo: voucherOrderRepo,
+		logger:           logger,
+	}
+}
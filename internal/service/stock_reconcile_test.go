@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/event"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReconcileQueue struct {
+	repository.MessageQueue
+	inFlight    []repository.Message
+	deadLetters []repository.Message
+}
+
+func (q *fakeReconcileQueue) InFlightMessages(ctx context.Context) ([]repository.Message, error) {
+	return q.inFlight, nil
+}
+
+func (q *fakeReconcileQueue) RangeMessages(ctx context.Context, streamKey, start, end string, count int64) ([]repository.Message, error) {
+	if start != "-" {
+		return nil, nil
+	}
+	return q.deadLetters, nil
+}
+
+// fakeStockRepo cache 中保存每次核对读到的 Redis 库存, 读完最后一个值后一直返回该值
+type fakeStockRepo struct {
+	repository.VoucherRepo
+	cache   map[uint64][]int64
+	dbStock map[uint64]int64
+	holders map[uint64]int64
+	repairs map[uint64]int64
+}
+
+func (r *fakeStockRepo) ListOnlineSeckillVoucherIDs(ctx context.Context) ([]uint64, error) {
+	return []uint64{1, 2}, nil
+}
+
+func (r *fakeStockRepo) GetVoucherStockCache(ctx context.Context, voucherID uint64) (int64, error) {
+	values := r.cache[voucherID]
+	if len(values) > 1 {
+		r.cache[voucherID] = values[1:]
+	}
+	return values[0], nil
+}
+
+func (r *fakeStockRepo) CompareAndSetVoucherStockCache(ctx context.Context, voucherID uint64, old, stock int64) (bool, error) {
+	if r.cache[voucherID][0] != old {
+		return false, nil
+	}
+	r.cache[voucherID] = []int64{stock}
+	r.repairs[voucherID] = stock
+	return true, nil
+}
+
+func (r *fakeStockRepo) CountSeckillOrderUsers(ctx context.Context, voucherID uint64) (int64, error) {
+	return r.holders[voucherID], nil
+}
+
+func (r *fakeStockRepo) GetSeckillVoucherByID(ctx context.Context, voucherID uint64) (*model.TbSeckillVoucher, error) {
+	return &model.TbSeckillVoucher{VoucherID: voucherID, Stock: r.dbStock[voucherID]}, nil
+}
+
+type fakeActiveOrderRepo struct {
+	repository.VoucherOrderRepo
+	active   map[uint64]int64
+	existing []int64
+}
+
+func (r *fakeActiveOrderRepo) CountActiveOrders(ctx context.Context, voucherID uint64) (int64, error) {
+	return r.active[voucherID], nil
+}
+
+func (r *fakeActiveOrderRepo) ListExistingOrderIDs(ctx context.Context, orderIDs []int64) ([]int64, error) {
+	return r.existing, nil
+}
+
+func orderMessage(t *testing.T, orderID int64, voucherID uint64) repository.Message {
+	values, err := event.EncodeOrder(&event.Order{OrderID: orderID, UserID: uint64(orderID), VoucherID: voucherID})
+	require.NoError(t, err)
+	return repository.Message{ID: "0-1", Values: map[string]string{event.FieldOrder: values[event.FieldOrder].(string)}}
+}
+
+// 券 1: 一个订单在队列中未落库, 一个在死信中, 一个已落库但未确认, 库存一致;
+// 券 2: Redis 库存比 MySQL 少 3
+func newReconcileFixture(t *testing.T, voucher2Cache ...int64) (*stockReconcileService, *fakeStockRepo) {
+	queue := &fakeReconcileQueue{
+		inFlight:    []repository.Message{orderMessage(t, 101, 1), orderMessage(t, 102, 1)},
+		deadLetters: []repository.Message{orderMessage(t, 103, 1)},
+	}
+	stockRepo := &fakeStockRepo{
+		cache:   map[uint64][]int64{1: {6}, 2: voucher2Cache},
+		dbStock: map[uint64]int64{1: 8, 2: 8},
+		holders: map[uint64]int64{1: 5, 2: 2},
+		repairs: make(map[uint64]int64),
+	}
+	orderRepo := &fakeActiveOrderRepo{
+		active:   map[uint64]int64{1: 3, 2: 2},
+		existing: []int64{102},
+	}
+	return &stockReconcileService{
+		queues:           []repository.MessageQueue{queue},
+		voucherRepo:      stockRepo,
+		voucherOrderRepo: orderRepo,
+		logger:           slog.Default(),
+	}, stockRepo
+}
+
+func TestReconcileDetectsDrift(t *testing.T) {
+	svc, stockRepo := newReconcileFixture(t, 5)
+
+	report, err := svc.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	require.Len(t, report.Drifts, 1)
+	d := report.Drifts[0]
+	assert.Equal(t, uint64(2), d.VoucherID)
+	assert.Equal(t, int64(-3), d.StockDiff)
+	assert.Equal(t, int64(0), d.OrderDiff)
+	assert.Equal(t, int64(8), d.RepairTo)
+
+	// 只报告不修复
+	assert.False(t, d.Repaired)
+	assert.Zero(t, report.Repaired)
+	assert.Empty(t, stockRepo.repairs)
+}
+
+func TestReconcileRepairsDrift(t *testing.T) {
+	svc, stockRepo := newReconcileFixture(t, 5)
+
+	report, err := svc.Reconcile(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, report.Drifts, 1)
+	assert.True(t, report.Drifts[0].Repaired)
+	assert.Equal(t, 1, report.Repaired)
+	assert.Equal(t, map[uint64]int64{2: 8}, stockRepo.repairs)
+}
+
+// 第二次核对时已一致的差异是扣减与落库之间的时间差, 不报告也不修复
+func TestReconcileIgnoresTransientDrift(t *testing.T) {
+	svc, stockRepo := newReconcileFixture(t, 5, 8)
+
+	report, err := svc.Reconcile(context.Background(), true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Drifts)
+	assert.Empty(t, stockRepo.repairs)
+}
@@ -2,16 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/config"
+	"github.com/hmmm42/city-picks/internal/event"
 	"github.com/hmmm42/city-picks/internal/repository"
 	"github.com/hmmm42/city-picks/pkg/json_time"
-	sf "github.com/hmmm42/city-picks/pkg/sonyflake"
+	"github.com/redis/go-redis/v9"
 	"github.com/sony/sonyflake"
+	"gorm.io/gorm"
 )
 
 // VoucherDTO defines the request structure for creating a voucher.
@@ -24,14 +29,90 @@ type VoucherDTO struct {
 	ActualValue int64                `json:"actual_value"`
 	Type        uint8                `json:"type"`  //优惠卷类型
 	Stock       int64                `json:"stock"` //库存
+	Mode        uint8                `json:"mode"`  //秒杀模式, 0:先到先得, 1:预约抽签
 	BeginTime   json_time.CustomTime `json:"begin_time"`
 	EndTime     json_time.CustomTime `json:"end_time"`
 }
 
+// VoucherUpdateDTO 修改优惠券文案与使用规则, 空字段表示不修改
+type VoucherUpdateDTO struct {
+	ID       uint64 `json:"id" binding:"required"`
+	Title    string `json:"title"`
+	SubTitle string `json:"subTitle"`
+	Rules    string `json:"rules"`
+}
+
+// StockAdjustDTO 调整秒杀库存, Delta 为正表示补货, 为负表示扣减
+type StockAdjustDTO struct {
+	VoucherID uint64 `json:"voucher_id" binding:"required"`
+	Delta     int64  `json:"delta" binding:"required"`
+	Reason    string `json:"reason"`
+}
+
+// SeckillOrderDTO 秒杀订单状态: 落库前为 pending 或 failed, 落库后为订单的实际状态, 见 orderStatusNames
+type SeckillOrderDTO struct {
+	OrderID   int64  `json:"order_id"`
+	VoucherID uint64 `json:"voucher_id"`
+	Status    string `json:"status"`
+	// 下单时的优惠券快照
+	ShopID      uint64 `json:"shop_id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	PayValue    uint64 `json:"pay_value,omitempty"`
+	ActualValue int64  `json:"actual_value,omitempty"`
+}
+
+// orderStatusNames 已落库订单返回给轮询方的状态, 对应 tb_voucher_order.status
+var orderStatusNames = map[uint8]string{
+	repository.OrderStatusUnpaid:    "unpaid",
+	repository.OrderStatusPaid:      "paid",
+	repository.OrderStatusUsed:      "used",
+	repository.OrderStatusCancelled: "cancelled",
+	repository.OrderStatusRefunding: "refunding",
+	repository.OrderStatusRefunded:  "refunded",
+}
+
+const (
+	seckillUserLimitPrefix    = "ratelimit:seckill:user:"
+	seckillIPLimitPrefix      = "ratelimit:seckill:ip:"
+	seckillVoucherLimitPrefix = "ratelimit:seckill:voucher:"
+	// 本地售罄标记的有效期, 过期后重新访问 Redis, 使其他实例的补货能够生效
+	soldOutFlagTTL = 3 * time.Second
+)
+
+// RateLimitError 秒杀请求被限流, RetryAfter 为建议的重试等待时间
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many seckill requests, retry after %v", e.RetryAfter)
+}
+
+var (
+	ErrSeckillSoldOut     = errors.New("seckill voucher not found or out of stock")
+	ErrNotSeckillVoucher  = errors.New("voucher is not a seckill voucher")
+	ErrVoucherExpired     = errors.New("voucher has expired")
+	ErrInvalidTimeWindow  = errors.New("begin_time must be before end_time")
+	ErrInvalidSeckillMode = errors.New("seckill mode must be 0 (first come) or 1 (lottery)")
+	ErrStockCacheMissing  = errors.New("seckill stock cache not found")
+	ErrStockBelowIssued   = errors.New("stock cannot be reduced below zero or below issued orders")
+	// ErrStockCacheInconsistent MySQL 更新失败且 Redis 库存回滚失败, 需要执行库存核对修复
+	ErrStockCacheInconsistent = errors.New("seckill stock cache is inconsistent with database, reconcile required")
+)
+
 type VoucherService interface {
 	CreateVoucher(ctx context.Context, req *VoucherDTO) error
-	SeckillVoucher(ctx context.Context, voucherID, userID uint64) (int64, error)
+	UpdateVoucher(ctx context.Context, req *VoucherUpdateDTO) error
+	TakeDownVoucher(ctx context.Context, voucherID uint64) error
+	UpdateSeckillWindow(ctx context.Context, voucherID uint64, begin, end time.Time) error
+	ExpireVouchers(ctx context.Context) (int, error)
+	AdjustSeckillStock(ctx context.Context, req *StockAdjustDTO, operatorID uint64) (*model.TbSeckillStockLog, error)
+	ListSeckillStockLogs(ctx context.Context, voucherID uint64) ([]*model.TbSeckillStockLog, error)
+	SeckillVoucher(ctx context.Context, voucherID, userID uint64, clientIP string) (int64, error)
 	CreateVoucherOrderDB(ctx context.Context, order *model.TbVoucherOrder) error
+	CreateVoucherOrdersDB(ctx context.Context, orders []*model.TbVoucherOrder) error
+	MarkSeckillOrderFailed(ctx context.Context, orderID int64) error
+	GetSeckillOrder(ctx context.Context, orderID int64, userID uint64) (*SeckillOrderDTO, error)
 }
 
 type voucherService struct {
@@ -39,6 +120,8 @@ type voucherService struct {
 	voucherOrderRepo repository.VoucherOrderRepo
 	sf               *sonyflake.Sonyflake    // 用于唯一ID
 	mq               repository.MessageQueue // 用于消息队列
+	limiter          repository.RateLimiter
+	soldOut          sync.Map // voucherID -> 售罄时间, 售罄后短时间内直接拒绝请求, 不再访问 Redis
 	logger           *slog.Logger
 }
 
@@ -51,14 +134,19 @@ func (s *voucherService) CreateVoucher(ctx context.Context, req *VoucherDTO) err
 		PayValue:    req.PayValue,
 		ActualValue: req.ActualValue,
 		Type:        req.Type,
+		Status:      repository.VoucherStatusOnline,
 	}
 
 	var seckillVoucher *model.TbSeckillVoucher
-	if req.Type == 1 { // 特价券
+	if req.Type == repository.VoucherTypeSeckill { // 特价券
+		if req.Mode != repository.SeckillModeFirstCome && req.Mode != repository.SeckillModeLottery {
+			return ErrInvalidSeckillMode
+		}
 		seckillVoucher = &model.TbSeckillVoucher{
 			Stock:     req.Stock,
 			BeginTime: time.Time(req.BeginTime),
 			EndTime:   time.Time(req.EndTime),
+			Mode:      req.Mode,
 		}
 	}
 
@@ -70,7 +158,189 @@ func (s *voucherService) CreateVoucher(ctx context.Context, req *VoucherDTO) err
 		slog.Error("failed to create seckill voucher", "err", err)
 		return fmt.Errorf("failed to create seckill voucher: %w", err)
 	}
-	return s.voucherRepo.SetVoucherStockCache(ctx, seckillVoucher)
+	if err = s.voucherRepo.SetVoucherStockCache(ctx, seckillVoucher); err != nil {
+		return err
+	}
+	return s.voucherRepo.SetSeckillInfoCache(ctx, voucher, seckillVoucher)
+}
+
+func (s *voucherService) UpdateVoucher(ctx context.Context, req *VoucherUpdateDTO) error {
+	current, err := s.voucherRepo.GetVoucherByID(ctx, req.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get voucher %d: %w", req.ID, err)
+	}
+
+	voucher := &model.TbVoucher{
+		ID:       req.ID,
+		Title:    req.Title,
+		SubTitle: req.SubTitle,
+		Rules:    req.Rules,
+	}
+	if err = s.voucherRepo.UpdateVoucher(ctx, voucher); err != nil {
+		s.logger.Error("failed to update voucher", "err", err, "voucher_id", req.ID)
+		return fmt.Errorf("failed to update voucher: %w", err)
+	}
+
+	// 秒杀脚本从状态缓存中读取订单快照, 标题修改后同步, 已下的订单仍保留原标题; 已过期的券缓存已清理
+	if req.Title == "" || current.Type != repository.VoucherTypeSeckill || current.Status == repository.VoucherStatusExpired {
+		return nil
+	}
+	seckillVoucher, err := s.voucherRepo.GetSeckillVoucherByID(ctx, req.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get seckill voucher %d: %w", req.ID, err)
+	}
+	current.Title = req.Title
+	return s.voucherRepo.SetSeckillInfoCache(ctx, current, seckillVoucher)
+}
+
+// TakeDownVoucher 下架优惠券, 秒杀券同时在 Redis 中标记为下架以拦截秒杀请求
+func (s *voucherService) TakeDownVoucher(ctx context.Context, voucherID uint64) error {
+	voucher, err := s.voucherRepo.GetVoucherByID(ctx, voucherID)
+	if err != nil {
+		return fmt.Errorf("failed to get voucher %d: %w", voucherID, err)
+	}
+	if voucher.Status == repository.VoucherStatusExpired {
+		return ErrVoucherExpired
+	}
+
+	if err = s.voucherRepo.UpdateVoucherStatus(ctx, voucherID, repository.VoucherStatusOffline); err != nil {
+		s.logger.Error("failed to take down voucher", "err", err, "voucher_id", voucherID)
+		return fmt.Errorf("failed to take down voucher: %w", err)
+	}
+	if voucher.Type != repository.VoucherTypeSeckill {
+		return nil
+	}
+	if err = s.voucherRepo.SetSeckillStatusCache(ctx, voucherID, repository.VoucherStatusOffline); err != nil {
+		s.logger.Error("failed to set seckill status cache", "err", err, "voucher_id", voucherID)
+		return fmt.Errorf("failed to block seckill: %w", err)
+	}
+	return nil
+}
+
+// UpdateSeckillWindow 延长或缩短秒杀时间窗口
+func (s *voucherService) UpdateSeckillWindow(ctx context.Context, voucherID uint64, begin, end time.Time) error {
+	if !begin.Before(end) {
+		return ErrInvalidTimeWindow
+	}
+
+	voucher, err := s.voucherRepo.GetVoucherByID(ctx, voucherID)
+	if err != nil {
+		return fmt.Errorf("failed to get voucher %d: %w", voucherID, err)
+	}
+	if voucher.Type != repository.VoucherTypeSeckill {
+		return ErrNotSeckillVoucher
+	}
+	if voucher.Status == repository.VoucherStatusExpired {
+		return ErrVoucherExpired
+	}
+	seckillVoucher, err := s.voucherRepo.GetSeckillVoucherByID(ctx, voucherID)
+	if err != nil {
+		return fmt.Errorf("failed to get seckill voucher %d: %w", voucherID, err)
+	}
+
+	if err = s.voucherRepo.UpdateSeckillWindow(ctx, voucherID, begin, end); err != nil {
+		s.logger.Error("failed to update seckill window", "err", err, "voucher_id", voucherID)
+		return fmt.Errorf("failed to update seckill window: %w", err)
+	}
+	seckillVoucher.BeginTime, seckillVoucher.EndTime = begin, end
+	return s.voucherRepo.SetSeckillInfoCache(ctx, voucher, seckillVoucher)
+}
+
+// ExpireVouchers 将已过结束时间的秒杀券标记为过期并清理秒杀缓存, 返回处理的数量
+func (s *voucherService) ExpireVouchers(ctx context.Context) (int, error) {
+	ids, err := s.voucherRepo.ListExpiredSeckillVoucherIDs(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired vouchers: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		// 先清理缓存再更新状态, 状态更新失败时下一轮仍会重新处理
+		if err = s.voucherRepo.DeleteSeckillCache(ctx, id); err != nil {
+			s.logger.Error("failed to delete seckill cache", "err", err, "voucher_id", id)
+			continue
+		}
+		if err = s.voucherRepo.UpdateVoucherStatus(ctx, id, repository.VoucherStatusExpired); err != nil {
+			s.logger.Error("failed to mark voucher expired", "err", err, "voucher_id", id)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// AdjustSeckillStock 调整正在进行的秒杀活动的库存
+// 先通过 Lua 脚本原子地调整 Redis 库存并校验下限, 再在事务中更新 MySQL 并记录审计日志, MySQL 失败时回滚 Redis;
+// 回滚也失败时(例如增加的库存已被抢购)返回 ErrStockCacheInconsistent
+func (s *voucherService) AdjustSeckillStock(ctx context.Context, req *StockAdjustDTO, operatorID uint64) (*model.TbSeckillStockLog, error) {
+	voucher, err := s.voucherRepo.GetVoucherByID(ctx, req.VoucherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voucher %d: %w", req.VoucherID, err)
+	}
+	if voucher.Type != repository.VoucherTypeSeckill {
+		return nil, ErrNotSeckillVoucher
+	}
+	if voucher.Status == repository.VoucherStatusExpired {
+		return nil, ErrVoucherExpired
+	}
+
+	keys := []string{strconv.FormatUint(req.VoucherID, 10)}
+	res, err := s.adjustStockCache(ctx, keys, req.Delta)
+	if err != nil {
+		return nil, err
+	}
+
+	stockLog := &model.TbSeckillStockLog{
+		VoucherID:  req.VoucherID,
+		OperatorID: operatorID,
+		Delta:      req.Delta,
+		Reason:     req.Reason,
+	}
+	if err = s.voucherRepo.AdjustSeckillStock(ctx, stockLog); err != nil {
+		s.logger.Error("failed to adjust seckill stock in database, rolling back cache",
+			"err", err, "voucher_id", req.VoucherID, "delta", req.Delta)
+		if _, rbErr := s.adjustStockCache(ctx, keys, -req.Delta); rbErr != nil {
+			s.logger.Error("failed to roll back stock cache, reconcile required", "err", rbErr, "voucher_id", req.VoucherID, "delta", -req.Delta)
+			return nil, fmt.Errorf("%w: %w", ErrStockCacheInconsistent, err)
+		}
+		if errors.Is(err, repository.ErrStockInsufficient) {
+			return nil, ErrStockBelowIssued
+		}
+		return nil, fmt.Errorf("failed to adjust seckill stock: %w", err)
+	}
+
+	if req.Delta > 0 {
+		s.soldOut.Delete(req.VoucherID)
+	}
+	s.logger.Info("seckill stock adjusted",
+		"voucher_id", req.VoucherID,
+		"operator_id", operatorID,
+		"delta", req.Delta,
+		"stock_before", stockLog.StockBefore,
+		"stock_after", stockLog.StockAfter,
+		"cache_stock_after", res,
+		"reason", req.Reason,
+	)
+	return stockLog, nil
+}
+
+// adjustStockCache 执行 adjustStock 脚本, 返回调整后的 Redis 库存
+func (s *voucherService) adjustStockCache(ctx context.Context, keys []string, delta int64) (int64, error) {
+	res, err := s.voucherRepo.ExecScript(ctx, adjustStock, keys, delta)
+	if err != nil {
+		return 0, fmt.Errorf("failed to adjust stock cache: %w", err)
+	}
+	switch res {
+	case -1:
+		return 0, ErrStockCacheMissing
+	case -2:
+		return 0, ErrStockBelowIssued
+	}
+	return res, nil
+}
+
+func (s *voucherService) ListSeckillStockLogs(ctx context.Context, voucherID uint64) ([]*model.TbSeckillStockLog, error) {
+	return s.voucherRepo.ListSeckillStockLogs(ctx, voucherID)
 }
 
 //func (s *voucherService) SeckillVoucher(ctx context.Context, voucherID, userID uint64) (*model.TbVoucherOrder, error) {
@@ -124,7 +394,14 @@ func (s *voucherService) CreateVoucher(ctx context.Context, req *VoucherDTO) err
 //	return order, nil
 //}
 
-func (s *voucherService) SeckillVoucher(ctx context.Context, voucherID, userID uint64) (int64, error) {
+func (s *voucherService) SeckillVoucher(ctx context.Context, voucherID, userID uint64, clientIP string) (int64, error) {
+	if s.isSoldOut(voucherID) {
+		return 0, ErrSeckillSoldOut
+	}
+	if err := s.checkSeckillRateLimit(ctx, voucherID, userID, clientIP); err != nil {
+		return 0, err
+	}
+
 	//script := redis.NewScript(adjustSeckill)
 	orderID, err := s.sf.NextID()
 	if err != nil {
@@ -137,7 +414,8 @@ func (s *voucherService) SeckillVoucher(ctx context.Context, voucherID, userID u
 		strconv.FormatUint(orderID, 10),
 	}
 
-	res, err := s.voucherRepo.ExecScript(ctx, adjustSeckill, keys)
+	res, err := s.voucherRepo.ExecScript(ctx, adjustSeckill, keys,
+		time.Now().Unix(), int64(repository.SeckillOrderStatusTTL.Seconds()), event.OrderVersion)
 	if err != nil {
 		slog.Error("failed to execute seckill script", "err", err)
 		return 0, err
@@ -147,30 +425,176 @@ func (s *voucherService) SeckillVoucher(ctx context.Context, voucherID, userID u
 	case 0:
 		return int64(orderID), nil
 	case 1:
-		return 0, fmt.Errorf("seckill voucher not found or out of stock")
+		s.soldOut.Store(voucherID, time.Now())
+		return 0, ErrSeckillSoldOut
 	case 2:
 		return 0, fmt.Errorf("user has already purchased this voucher")
+	case 3:
+		return 0, fmt.Errorf("seckill not available at this time")
 	default:
 		return 0, fmt.Errorf("unexpected result from seckill script: %d", res)
 	}
 }
 
+func (s *voucherService) isSoldOut(voucherID uint64) bool {
+	v, ok := s.soldOut.Load(voucherID)
+	if !ok {
+		return false
+	}
+	if time.Since(v.(time.Time)) > soldOutFlagTTL {
+		s.soldOut.Delete(voucherID)
+		return false
+	}
+	return true
+}
+
+// checkSeckillRateLimit 按用户、IP 和优惠券三个维度的令牌桶限流, 配置支持热更新
+func (s *voucherService) checkSeckillRateLimit(ctx context.Context, voucherID, userID uint64, clientIP string) error {
+	opts := config.RateLimitOptions
+	if opts == nil {
+		return nil
+	}
+
+	allowed, retryAfter, err := s.limiter.Allow(ctx,
+		repository.Bucket{Key: seckillUserLimitPrefix + strconv.FormatUint(userID, 10), Rate: opts.UserRate, Burst: opts.UserBurst},
+		repository.Bucket{Key: seckillIPLimitPrefix + clientIP, Rate: opts.IPRate, Burst: opts.IPBurst},
+		repository.Bucket{Key: seckillVoucherLimitPrefix + strconv.FormatUint(voucherID, 10), Rate: opts.VoucherRate, Burst: opts.VoucherBurst},
+	)
+	if err != nil {
+		// 限流器故障时放行, 由 Lua 脚本保证库存正确
+		s.logger.Error("failed to check seckill rate limit", "err", err)
+		return nil
+	}
+	if !allowed {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// CreateVoucherOrderDB 创建订单并扣减库存, 同一订单重复消费时视为成功
 func (s *voucherService) CreateVoucherOrderDB(ctx context.Context, order *model.TbVoucherOrder) error {
-	// 创建订单并扣减库存
+	if err := fillOrderSnapshots(ctx, s.voucherRepo, order); err != nil {
+		return err
+	}
 	err := s.voucherRepo.CreateVoucherOrderAndReduceStock(ctx, order)
+	if errors.Is(err, repository.ErrOrderExists) {
+		return s.afterOrderReplayed(ctx, order.ID)
+	}
 	if err != nil {
 		slog.Error("failed to create voucher order and reduce stock", "err", err)
 		return err
 	}
+	s.afterOrderCreated(ctx, order)
 	return nil
 }
 
-func NewVoucherService(voucherRepo repository.VoucherRepo, voucherOrderRepo repository.VoucherOrderRepo, logger *slog.Logger) VoucherService {
-	nsf, _ := sf.NewSonyflake()
+// CreateVoucherOrdersDB 批量创建订单, 任一订单失败时全部回滚
+func (s *voucherService) CreateVoucherOrdersDB(ctx context.Context, orders []*model.TbVoucherOrder) error {
+	if err := fillOrderSnapshots(ctx, s.voucherRepo, orders...); err != nil {
+		return err
+	}
+	if err := s.voucherRepo.CreateVoucherOrdersAndReduceStock(ctx, orders); err != nil {
+		return err
+	}
+	for _, order := range orders {
+		s.afterOrderCreated(ctx, order)
+	}
+	return nil
+}
+
+// afterOrderCreated 订单落库后更新轮询状态, 并加入未支付订单的延时队列
+func (s *voucherService) afterOrderCreated(ctx context.Context, order *model.TbVoucherOrder) {
+	// 订单已落库, 状态更新失败时轮询会回落到 MySQL
+	if err := s.voucherOrderRepo.SetSeckillOrderStatus(ctx, order.ID, repository.SeckillOrderCreated); err != nil {
+		s.logger.Error("failed to update seckill order status", "err", err, "order_id", order.ID)
+	}
+	if opts := config.OrderOptions; opts != nil && opts.UnpaidTimeout > 0 {
+		// 重复消费时按下单时间计算超时, 不延长原有的支付期限
+		createdAt := order.CreateTime
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if err := s.voucherOrderRepo.AddUnpaidOrder(ctx, order.ID, createdAt.Add(opts.UnpaidTimeout)); err != nil {
+			// 超时任务会定期扫描 MySQL 中超时未支付的订单, 不在延时队列中的订单也会被取消
+			s.logger.Error("failed to add order to unpaid delay queue", "err", err, "order_id", order.ID)
+		}
+	}
+}
+
+// afterOrderReplayed 订单已落库但消息被重复消费, 上次处理可能在落库后中断, 重新执行落库后的步骤
+func (s *voucherService) afterOrderReplayed(ctx context.Context, orderID int64) error {
+	order, err := s.voucherOrderRepo.GetVoucherOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("voucher order already created, skip", "order_id", orderID)
+	if order.Status == repository.OrderStatusUnpaid {
+		s.afterOrderCreated(ctx, order)
+	}
+	return nil
+}
+
+// MarkSeckillOrderFailed 订单消息进入死信队列后, 将订单状态标记为失败
+func (s *voucherService) MarkSeckillOrderFailed(ctx context.Context, orderID int64) error {
+	return s.voucherOrderRepo.SetSeckillOrderStatus(ctx, orderID, repository.SeckillOrderFailed)
+}
+
+// GetSeckillOrder 查询秒杀订单状态, 先查 Redis 中的创建状态, 订单已落库或状态不存在时查 MySQL 中订单的实际状态
+// 只能查询自己的订单, 其他用户的订单视为不存在
+func (s *voucherService) GetSeckillOrder(ctx context.Context, orderID int64, userID uint64) (*SeckillOrderDTO, error) {
+	status, err := s.voucherOrderRepo.GetSeckillOrderStatus(ctx, orderID)
+	if err == nil {
+		if status.UserID != userID {
+			return nil, gorm.ErrRecordNotFound
+		}
+		// 已落库的订单可能已支付、取消或退款, 以 MySQL 为准
+		if status.Status != repository.SeckillOrderCreated {
+			return &SeckillOrderDTO{
+				OrderID:     orderID,
+				VoucherID:   status.VoucherID,
+				Status:      status.Status,
+				ShopID:      status.ShopID,
+				Title:       status.Title,
+				PayValue:    status.PayValue,
+				ActualValue: status.ActualValue,
+			}, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		// Redis 故障时回落到 MySQL
+		s.logger.Error("failed to get seckill order status", "err", err, "order_id", orderID)
+	}
+
+	order, err := s.voucherOrderRepo.GetVoucherOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err = fillOrderSnapshots(ctx, s.voucherRepo, order); err != nil {
+		return nil, err
+	}
+	name, ok := orderStatusNames[order.Status]
+	if !ok {
+		name = repository.SeckillOrderCreated
+	}
+	return &SeckillOrderDTO{
+		OrderID:     orderID,
+		VoucherID:   order.VoucherID,
+		Status:      name,
+		ShopID:      order.ShopID,
+		Title:       order.Title,
+		PayValue:    order.PayValue,
+		ActualValue: order.ActualValue,
+	}, nil
+}
+
+func NewVoucherService(voucherRepo repository.VoucherRepo, voucherOrderRepo repository.VoucherOrderRepo, limiter repository.RateLimiter, idGen *sonyflake.Sonyflake, logger *slog.Logger) VoucherService {
 	return &voucherService{
 		voucherRepo:      voucherRepo,
 		voucherOrderRepo: voucherOrderRepo,
-		sf:               nsf,
+		sf:               idGen,
+		limiter:          limiter,
 		logger:           logger,
 	}
 }
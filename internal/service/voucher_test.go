@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hmmm42/city-picks/dal/model"
+	"github.com/hmmm42/city-picks/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeStockAdjustRepo 在 miniredis 上执行库存脚本, MySQL 调整按 dbErr 返回; beforeDB 模拟调整期间的并发抢购
+type fakeStockAdjustRepo struct {
+	redisScriptRepo
+	dbErr    error
+	beforeDB func()
+}
+
+func (r *fakeStockAdjustRepo) GetVoucherByID(ctx context.Context, voucherID uint64) (*model.TbVoucher, error) {
+	return &model.TbVoucher{ID: voucherID, Type: repository.VoucherTypeSeckill}, nil
+}
+
+func (r *fakeStockAdjustRepo) AdjustSeckillStock(ctx context.Context, stockLog *model.TbSeckillStockLog) error {
+	if r.beforeDB != nil {
+		r.beforeDB()
+	}
+	return r.dbErr
+}
+
+func newStockAdjustFixture(t *testing.T, dbErr error) (*voucherService, *fakeStockAdjustRepo, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	require.NoError(t, mr.Set("seckill:stock:7", "5"))
+	repo := &fakeStockAdjustRepo{redisScriptRepo: redisScriptRepo{rdb: rdb}, dbErr: dbErr}
+	return &voucherService{voucherRepo: repo, logger: slog.Default()}, repo, mr
+}
+
+func TestAdjustSeckillStock(t *testing.T) {
+	svc, _, mr := newStockAdjustFixture(t, nil)
+
+	_, err := svc.AdjustSeckillStock(context.Background(), &StockAdjustDTO{VoucherID: 7, Delta: 3}, 1)
+	require.NoError(t, err)
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "8", stock)
+
+	_, err = svc.AdjustSeckillStock(context.Background(), &StockAdjustDTO{VoucherID: 7, Delta: -9}, 1)
+	assert.ErrorIs(t, err, ErrStockBelowIssued)
+	_, err = svc.AdjustSeckillStock(context.Background(), &StockAdjustDTO{VoucherID: 8, Delta: 1}, 1)
+	assert.ErrorIs(t, err, ErrStockCacheMissing)
+}
+
+// MySQL 失败时回滚 Redis 库存
+func TestAdjustSeckillStockRollsBackCache(t *testing.T) {
+	svc, _, mr := newStockAdjustFixture(t, errors.New("db down"))
+
+	_, err := svc.AdjustSeckillStock(context.Background(), &StockAdjustDTO{VoucherID: 7, Delta: 3}, 1)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrStockCacheInconsistent)
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "5", stock)
+}
+
+// 增加的库存在 MySQL 更新前已被抢购, 回滚会使库存小于 0, 返回需要核对的错误
+func TestAdjustSeckillStockRollbackFails(t *testing.T) {
+	svc, repo, mr := newStockAdjustFixture(t, errors.New("db down"))
+	repo.beforeDB = func() { _ = mr.Set("seckill:stock:7", "1") }
+
+	_, err := svc.AdjustSeckillStock(context.Background(), &StockAdjustDTO{VoucherID: 7, Delta: 3}, 1)
+	assert.ErrorIs(t, err, ErrStockCacheInconsistent)
+	stock, _ := mr.Get("seckill:stock:7")
+	assert.Equal(t, "1", stock)
+}
+
+// fakeSeckillStatusRepo 在 fakeOrderRepo 的基础上保存 Redis 中的订单创建状态
+type fakeSeckillStatusRepo struct {
+	*fakeOrderRepo
+	statuses map[int64]*repository.SeckillOrderStatus
+}
+
+func (r *fakeSeckillStatusRepo) GetSeckillOrderStatus(ctx context.Context, orderID int64) (*repository.SeckillOrderStatus, error) {
+	status, ok := r.statuses[orderID]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return status, nil
+}
+
+// 落库前从 Redis 返回状态与快照; 落库后按 MySQL 中订单的实际状态返回, 不再统一报告为 created
+func TestGetSeckillOrder(t *testing.T) {
+	ctx := context.Background()
+	paid := &model.TbVoucherOrder{ID: 2, UserID: 1, VoucherID: 7, Status: repository.OrderStatusPaid,
+		ShopID: 3, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}
+	refunded := &model.TbVoucherOrder{ID: 3, UserID: 1, VoucherID: 7, Status: repository.OrderStatusRefunded,
+		ShopID: 3, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}
+	repo := &fakeSeckillStatusRepo{
+		fakeOrderRepo: newFakeOrderRepo(paid, refunded),
+		statuses: map[int64]*repository.SeckillOrderStatus{
+			1: {Status: repository.SeckillOrderPending, UserID: 1, VoucherID: 7, ShopID: 3, Title: "50元代金券", PayValue: 4750, ActualValue: 5000},
+			2: {Status: repository.SeckillOrderCreated, UserID: 1, VoucherID: 7},
+		},
+	}
+	svc := &voucherService{voucherOrderRepo: repo, logger: slog.Default()}
+
+	order, err := svc.GetSeckillOrder(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &SeckillOrderDTO{OrderID: 1, VoucherID: 7, Status: repository.SeckillOrderPending,
+		ShopID: 3, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}, order)
+
+	order, err = svc.GetSeckillOrder(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &SeckillOrderDTO{OrderID: 2, VoucherID: 7, Status: "paid",
+		ShopID: 3, Title: "50元代金券", PayValue: 4750, ActualValue: 5000}, order)
+
+	order, err = svc.GetSeckillOrder(ctx, 3, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "refunded", order.Status)
+
+	_, err = svc.GetSeckillOrder(ctx, 1, 2)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = svc.GetSeckillOrder(ctx, 3, 2)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -24,5 +24,10 @@ func init() {
 	register(ErrEncodingYaml, 500, "Yaml data could not be encoded")
 	register(ErrDecodingYaml, 500, "Yaml data could not be decoded")
 	register(ErrTokenGenerationFailed, 500, "Token generation failed")
+	register(ErrRequestInProgress, 409, "A request with the same Idempotency-Key is still being processed")
+	register(ErrTooManyRequests, 429, "Too many requests, please retry later")
+	register(ErrIdempotencyKeyReused, 422, "Idempotency-Key was already used with a different request body")
+	register(ErrOrderStatusConflict, 409, "Order status does not allow this operation")
+	register(ErrPaymentFailed, 500, "Payment gateway failed")
 
 }
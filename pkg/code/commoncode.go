@@ -1,6 +1,6 @@
 package code
 
-var OnlyUseHTTPStatus = map[int]bool{200: true, 400: true, 401: true, 403: true, 404: true, 500: true}
+var OnlyUseHTTPStatus = map[int]bool{200: true, 400: true, 401: true, 403: true, 404: true, 409: true, 422: true, 429: true, 500: true}
 
 // http状态码 5开头表示服务器端错误。4开头表示客户端错误
 // 400 Bad Request（错误请求）,401 Unauthorized（未授权）,403 Forbidden（禁止访问）
@@ -46,3 +46,21 @@ const (
 	ErrEncodingYaml
 	ErrDecodingYaml
 )
+
+// 请求控制类错误
+const (
+	// ErrRequestInProgress - 409: A request with the same Idempotency-Key is still being processed.
+	ErrRequestInProgress int = iota + 100401
+	// ErrTooManyRequests - 429: Rate limited, the Retry-After header tells when to retry.
+	ErrTooManyRequests
+	// ErrIdempotencyKeyReused - 422: The Idempotency-Key was already used with a different request body.
+	ErrIdempotencyKeyReused
+)
+
+// 订单类错误
+const (
+	// ErrOrderStatusConflict - 409: The order status does not allow this operation.
+	ErrOrderStatusConflict int = iota + 100501
+	// ErrPaymentFailed - 500: The payment gateway failed to pay or refund.
+	ErrPaymentFailed
+)